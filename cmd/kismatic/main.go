@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/apprenda/kismatic/pkg/cli"
+	"github.com/apprenda/kismatic/pkg/ssh"
 	"github.com/apprenda/kismatic/pkg/util"
 	"time"
 	"math/rand"
@@ -20,7 +21,9 @@ func main() {
 		util.PrintColor(os.Stderr, util.Red, "Error initializing command: %v\n", err)
 		os.Exit(1)
 	}
-	if err := cmd.Execute(); err != nil {
+	err = cmd.Execute()
+	ssh.CloseConnections()
+	if err != nil {
 		util.PrintColor(os.Stderr, util.Red, "%v\n", err)
 		os.Exit(1)
 	}
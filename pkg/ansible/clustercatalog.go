@@ -27,6 +27,13 @@ type ClusterCatalog struct {
 	DockerRegistryAddress        string `yaml:"docker_registry_address"`
 	DockerRegistryPort           string `yaml:"docker_registry_port"`
 
+	EtcdK8sClientPort        int `yaml:"etcd_k8s_client_port"`
+	EtcdK8sPeerPort          int `yaml:"etcd_k8s_peer_port"`
+	EtcdNetworkingClientPort int `yaml:"etcd_networking_client_port"`
+	EtcdNetworkingPeerPort   int `yaml:"etcd_networking_peer_port"`
+	APIServerPort            int `yaml:"kubernetes_master_secure_port"`
+	CalicoFelixMetricsPort   int `yaml:"calico_felix_metrics_port"`
+
 	ForceEtcdRestart              bool `yaml:"force_etcd_restart"`
 	ForceAPIServerRestart         bool `yaml:"force_apiserver_restart"`
 	ForceControllerManagerRestart bool `yaml:"force_controller_manager_restart"`
@@ -36,17 +43,37 @@ type ClusterCatalog struct {
 	ForceCalicoNodeRestart        bool `yaml:"force_calico_node_restart"`
 	ForceDockerRestart            bool `yaml:"force_docker_restart"`
 
-	EnableConfigureIngress bool `yaml:"configure_ingress"`
-
 	KismaticPreflightCheckerLinux string `yaml:"kismatic_preflight_checker"`
 	KismaticPreflightCheckerLocal string `yaml:"kismatic_preflight_checker_local"`
+	KismaticConnectivityMatrix    string `yaml:"kismatic_connectivity_matrix"`
 
 	WorkerNode string `yaml:"worker_node"`
+	EtcdNode   string `yaml:"etcd_node"`
 
 	NFSVolumes []NFSVolume `yaml:"nfs_volumes"`
 
+	// the names of the add-ons that are installed on the cluster
+	EnabledAddOns []string `yaml:"enabled_add_ons"`
+
+	AuthorizationPolicies []AuthorizationPolicy `yaml:"kubernetes_authorization_policies"`
+
 	EnableGluster bool `yaml:"configure_storage"`
 
+	// logging add-on vars
+	LoggingBackend       string `yaml:"pv_logging_backend,omitempty"`
+	LoggingNFSServerIP   string `yaml:"pv_logging_nfs_server_ip,omitempty"`
+	LoggingNFSDir        string `yaml:"pv_logging_nfs_dir,omitempty"`
+	LoggingStorageSize   string `yaml:"pv_logging_storage_size,omitempty"`
+	LoggingStorageSizeGB int    `yaml:"pv_logging_storage_size_gb,omitempty"`
+	LoggingRetentionDays int    `yaml:"logging_retention_days,omitempty"`
+
+	// monitoring add-on vars
+	MonitoringBackend       string `yaml:"pv_monitoring_backend,omitempty"`
+	MonitoringNFSServerIP   string `yaml:"pv_monitoring_nfs_server_ip,omitempty"`
+	MonitoringNFSDir        string `yaml:"pv_monitoring_nfs_dir,omitempty"`
+	MonitoringStorageSize   string `yaml:"pv_monitoring_storage_size,omitempty"`
+	MonitoringStorageSizeGB int    `yaml:"pv_monitoring_storage_size_gb,omitempty"`
+
 	// volume add vars
 	VolumeName              string `yaml:"volume_name"`
 	VolumeReplicaCount      int    `yaml:"volume_replica_count"`
@@ -56,6 +83,12 @@ type ClusterCatalog struct {
 	VolumeQuotaBytes        int    `yaml:"volume_quota_bytes"`
 	VolumeMount             string `yaml:"volume_mount"`
 	VolumeAllowedIPs        string `yaml:"volume_allow_ips"`
+
+	// etcd restore vars
+	EtcdBackupDir string `yaml:"etcd_backup_dir"`
+
+	// certificate rotation vars
+	RotationSerial string `yaml:"rotation_serial"`
 }
 
 type NFSVolume struct {
@@ -63,6 +96,17 @@ type NFSVolume struct {
 	Path string
 }
 
+// AuthorizationPolicy is the spec of an ABAC policy of the API server
+type AuthorizationPolicy struct {
+	User            string `yaml:"user,omitempty"`
+	Group           string `yaml:"group,omitempty"`
+	Namespace       string `yaml:"namespace,omitempty"`
+	Resource        string `yaml:"resource,omitempty"`
+	APIGroup        string `yaml:"apiGroup,omitempty"`
+	NonResourcePath string `yaml:"nonResourcePath,omitempty"`
+	Readonly        bool   `yaml:"readonly,omitempty"`
+}
+
 func (c *ClusterCatalog) EnableRestart() {
 	c.ForceEtcdRestart = true
 	c.ForceAPIServerRestart = true
@@ -10,8 +10,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
 )
 
 const (
@@ -160,6 +163,13 @@ func (r *runner) startPlaybook(playbookFile string, inv Inventory, cc ClusterCat
 	os.Setenv("ANSIBLE_CALLBACK_WHITELIST", "json_lines")
 	os.Setenv("ANSIBLE_CONFIG", filepath.Join(r.ansibleDir, "playbooks", "ansible.cfg"))
 	os.Setenv("ANSIBLE_JSON_LINES_PIPE", r.namedPipe)
+	if knownHosts := ssh.KnownHostsFile(); knownHosts != "" {
+		// Ansible verifies the nodes against the host keys recorded for the cluster,
+		// so the keys of the nodes that were never contacted are recorded first
+		recordHostKeys(inv, ssh.TestConnection)
+		os.Setenv("ANSIBLE_HOST_KEY_CHECKING", "True")
+		os.Setenv("ANSIBLE_SSH_ARGS", knownHostsSSHArgs(knownHosts))
+	}
 
 	// Print Ansible command
 	fmt.Fprintf(r.out, "export PYTHONPATH=%v\n", os.Getenv("PYTHONPATH"))
@@ -167,6 +177,10 @@ func (r *runner) startPlaybook(playbookFile string, inv Inventory, cc ClusterCat
 	fmt.Fprintf(r.out, "export ANSIBLE_CALLBACK_WHITELIST=%v\n", os.Getenv("ANSIBLE_CALLBACK_WHITELIST"))
 	fmt.Fprintf(r.out, "export ANSIBLE_CONFIG=%v\n", os.Getenv("ANSIBLE_CONFIG"))
 	fmt.Fprintf(r.out, "export ANSIBLE_JSON_LINES_PIPE=%v\n", os.Getenv("ANSIBLE_JSON_LINES_PIPE"))
+	if ssh.KnownHostsFile() != "" {
+		fmt.Fprintf(r.out, "export ANSIBLE_HOST_KEY_CHECKING=%v\n", os.Getenv("ANSIBLE_HOST_KEY_CHECKING"))
+		fmt.Fprintf(r.out, "export ANSIBLE_SSH_ARGS='%v'\n", os.Getenv("ANSIBLE_SSH_ARGS"))
+	}
 	fmt.Fprintln(r.out, strings.Join(cmd.Args, " "))
 
 	// Starts async execution of ansible, which will block until
@@ -186,6 +200,36 @@ func (r *runner) startPlaybook(playbookFile string, inv Inventory, cc ClusterCat
 	return eventStream, nil
 }
 
+// the SSH arguments of ansible.cfg, which are replaced by ANSIBLE_SSH_ARGS
+const sshControlArgs = "-o ControlMaster=auto -o ControlPersist=1800s -o ControlPath=/tmp/ssh-%r-%h-%p"
+
+// returns the SSH arguments that make ansible verify the host keys of the
+// nodes against the known_hosts file
+func knownHostsSSHArgs(knownHostsFile string) string {
+	return fmt.Sprintf("%s -o \"UserKnownHostsFile=%s\" -o StrictHostKeyChecking=yes", sshControlArgs, knownHostsFile)
+}
+
+// connects to the nodes of the inventory, which records the host keys of the
+// nodes that are not in the known_hosts file yet. Connection errors, including
+// keys that do not match the recorded keys, are reported by ansible.
+func recordHostKeys(inv Inventory, connect func(ip string, port int, user, key string) error) {
+	nodes := map[string]Node{}
+	for _, r := range inv.Roles {
+		for _, n := range r.Nodes {
+			nodes[n.PublicIP] = n
+		}
+	}
+	var wg sync.WaitGroup
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n Node) {
+			defer wg.Done()
+			connect(n.PublicIP, n.SSHPort, n.SSHUser, n.SSHPrivateKey)
+		}(n)
+	}
+	wg.Wait()
+}
+
 // create a named pipe for getting json events out of ansible.
 // add random int to file name to avoid collision.
 func createTempNamedPipe() (string, error) {
@@ -1,7 +1,11 @@
 package ansible
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -15,3 +19,31 @@ func TestWaitPlaybook(t *testing.T) {
 		t.Error("Did not get the expected error when calling WaitPlaybook")
 	}
 }
+
+func TestRecordHostKeys(t *testing.T) {
+	inv := Inventory{Roles: []Role{
+		{Name: "etcd", Nodes: []Node{{Host: "node01", PublicIP: "10.0.0.1", SSHPort: 22, SSHUser: "kismaticuser", SSHPrivateKey: "key"}}},
+		{Name: "master", Nodes: []Node{{Host: "node01", PublicIP: "10.0.0.1", SSHPort: 22, SSHUser: "kismaticuser", SSHPrivateKey: "key"}}},
+		{Name: "worker", Nodes: []Node{{Host: "node02", PublicIP: "10.0.0.2", SSHPort: 2222, SSHUser: "kismaticuser", SSHPrivateKey: "key"}}},
+	}}
+	var mu sync.Mutex
+	connected := map[string]int{}
+	recordHostKeys(inv, func(ip string, port int, user, key string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		connected[fmt.Sprintf("%s:%d", ip, port)]++
+		return errors.New("connection refused")
+	})
+	if len(connected) != 2 || connected["10.0.0.1:22"] != 1 || connected["10.0.0.2:2222"] != 1 {
+		t.Errorf("expected a single connection to each node, got %v", connected)
+	}
+}
+
+func TestKnownHostsSSHArgs(t *testing.T) {
+	args := knownHostsSSHArgs("/opt/kismatic/generated/known_hosts")
+	for _, a := range []string{"ControlMaster=auto", `-o "UserKnownHostsFile=/opt/kismatic/generated/known_hosts"`, "-o StrictHostKeyChecking=yes"} {
+		if !strings.Contains(args, a) {
+			t.Errorf("expected %q in the SSH arguments, got %q", a, args)
+		}
+	}
+}
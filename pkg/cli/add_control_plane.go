@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type addControlPlaneOpts struct {
+	GeneratedAssetsDirectory string
+	OutputFormat             string
+	Verbose                  bool
+	SkipPreFlight            bool
+}
+
+// NewCmdAddMaster returns the command for adding masters to the cluster
+func NewCmdAddMaster(out io.Writer, installOpts *installOpts) *cobra.Command {
+	opts := &addControlPlaneOpts{}
+	cmd := &cobra.Command{
+		Use:   "add-master MASTER_NAME MASTER_IP [MASTER_INTERNAL_IP]",
+		Short: "add a Master node to an existing Kubernetes cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 || len(args) > 3 {
+				return cmd.Usage()
+			}
+			return doAddControlPlaneNode(out, installOpts.planFilename, opts, nodeFromArgs(args), "master")
+		},
+	}
+	addControlPlaneFlags(cmd, opts)
+	return cmd
+}
+
+// NewCmdAddEtcd returns the command for adding etcd members to the cluster
+func NewCmdAddEtcd(out io.Writer, installOpts *installOpts) *cobra.Command {
+	opts := &addControlPlaneOpts{}
+	cmd := &cobra.Command{
+		Use:   "add-etcd ETCD_NAME ETCD_IP [ETCD_INTERNAL_IP]",
+		Short: "add an Etcd node to an existing Kubernetes cluster",
+		Long: `Add an Etcd node to an existing Kubernetes cluster.
+
+The node is added as a member of both the Kubernetes and the networking etcd
+clusters. The API servers and the cluster network are reconfigured to use the
+new member.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) < 2 || len(args) > 3 {
+				return cmd.Usage()
+			}
+			return doAddControlPlaneNode(out, installOpts.planFilename, opts, nodeFromArgs(args), "etcd")
+		},
+	}
+	addControlPlaneFlags(cmd, opts)
+	return cmd
+}
+
+func addControlPlaneFlags(cmd *cobra.Command, opts *addControlPlaneOpts) {
+	cmd.Flags().StringVar(&opts.GeneratedAssetsDirectory, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.Verbose, "verbose", false, "enable verbose logging from the installation")
+	cmd.Flags().StringVarP(&opts.OutputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
+	cmd.Flags().BoolVar(&opts.SkipPreFlight, "skip-preflight", false, "skip pre-flight checks, useful when rerunning kismatic")
+}
+
+func nodeFromArgs(args []string) install.Node {
+	n := install.Node{
+		Host: args[0],
+		IP:   args[1],
+	}
+	if len(args) == 3 {
+		n.InternalIP = args[2]
+	}
+	return n
+}
+
+func doAddControlPlaneNode(stdout io.Writer, planFile string, opts *addControlPlaneOpts, newNode install.Node, role string) error {
+	out := messagesOut(stdout, opts.OutputFormat)
+	planner := &install.FilePlanner{File: planFile}
+	if !planner.PlanExists() {
+		return fmt.Errorf("add-%s can only be used with an existing plan file", role)
+	}
+	execOpts := install.ExecutorOptions{
+		GeneratedAssetsDirectory: opts.GeneratedAssetsDirectory,
+		OutputFormat:             opts.OutputFormat,
+		Verbose:                  opts.Verbose,
+		SkipCAGeneration:         true,
+	}
+	executor, err := install.NewExecutor(stdout, os.Stderr, execOpts)
+	if err != nil {
+		return err
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %v", err)
+	}
+	if _, errs := install.ValidateNode(&newNode); errs != nil {
+		util.PrintValidationErrors(out, errs)
+		return fmt.Errorf("information provided about the new %s node is invalid", role)
+	}
+	if _, errs := install.ValidatePlan(plan); errs != nil {
+		util.PrintValidationErrors(out, errs)
+		return errors.New("the plan file failed validation")
+	}
+	sshCon := &install.SSHConnection{
+		SSHConfig: &plan.Cluster.SSH,
+		Node:      &newNode,
+	}
+	if _, errs := install.ValidateSSHConnection(sshCon, fmt.Sprintf("New %s node", role)); errs != nil {
+		util.PrintValidationErrors(out, errs)
+		return errors.New("could not establish SSH connection to the new node")
+	}
+	group := plan.Master.Nodes
+	if role == "etcd" {
+		group = plan.Etcd.Nodes
+	}
+	if err := ensureNodeIsNewToGroup(group, newNode, role); err != nil {
+		return err
+	}
+	if !opts.SkipPreFlight {
+		util.PrintHeader(out, fmt.Sprintf("Running Pre-Flight Checks On New %s Node", strings.Title(role)), '=')
+		if err := runPreFlightOnControlPlaneNode(executor, *plan, newNode, role); err != nil {
+			return err
+		}
+	}
+	var updatedPlan *install.Plan
+	if role == "etcd" {
+		updatedPlan, err = executor.AddEtcd(plan, newNode)
+	} else {
+		updatedPlan, err = executor.AddMaster(plan, newNode)
+	}
+	if err != nil {
+		return err
+	}
+	if err := planner.Write(updatedPlan); err != nil {
+		return fmt.Errorf("error updating plan file to include new %s node: %v", role, err)
+	}
+	return nil
+}
+
+// returns an error if the group contains a node that is "equivalent"
+// to the new node that is being added
+func ensureNodeIsNewToGroup(group []install.Node, newNode install.Node, role string) error {
+	for _, n := range group {
+		if n.Host == newNode.Host {
+			return fmt.Errorf("according to the plan file, the host name of the new node is already being used by another %s node", role)
+		}
+		if n.IP == newNode.IP {
+			return fmt.Errorf("according to the plan file, the IP of the new node is already being used by another %s node", role)
+		}
+		if newNode.InternalIP != "" && n.InternalIP == newNode.InternalIP {
+			return fmt.Errorf("according to the plan file, the internal IP of the new node is already being used by another %s node", role)
+		}
+	}
+	return nil
+}
+
+func runPreFlightOnControlPlaneNode(executor install.Executor, plan install.Plan, node install.Node, role string) error {
+	// use the original plan, but only run against the new node
+	preFlightPlan := plan
+	preFlightPlan.Master.Nodes = []install.Node{}
+	preFlightPlan.Master.ExpectedCount = 0
+	preFlightPlan.Etcd.Nodes = []install.Node{}
+	preFlightPlan.Etcd.ExpectedCount = 0
+	preFlightPlan.Worker.Nodes = []install.Node{}
+	preFlightPlan.Worker.ExpectedCount = 0
+	preFlightPlan.Ingress.Nodes = []install.Node{}
+	preFlightPlan.Ingress.ExpectedCount = 0
+	preFlightPlan.Storage.Nodes = []install.Node{}
+	preFlightPlan.Storage.ExpectedCount = 0
+	if role == "etcd" {
+		preFlightPlan.Etcd.Nodes = []install.Node{node}
+		preFlightPlan.Etcd.ExpectedCount = 1
+	} else {
+		preFlightPlan.Master.Nodes = []install.Node{node}
+		preFlightPlan.Master.ExpectedCount = 1
+	}
+	return executor.RunPreFlightCheck(&preFlightPlan)
+}
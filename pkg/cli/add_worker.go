@@ -42,12 +42,13 @@ func NewCmdAddWorker(out io.Writer, installOpts *installOpts) *cobra.Command {
 	cmd.Flags().StringVar(&opts.GeneratedAssetsDirectory, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
 	cmd.Flags().BoolVar(&opts.RestartServices, "restart-services", false, "force restart clusters services (Use with care)")
 	cmd.Flags().BoolVar(&opts.Verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&opts.OutputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().StringVarP(&opts.OutputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
 	cmd.Flags().BoolVar(&opts.SkipPreFlight, "skip-preflight", false, "skip pre-flight checks, useful when rerunning kismatic")
 	return cmd
 }
 
-func doAddWorker(out io.Writer, planFile string, opts *addWorkerOpts, newWorker install.Node) error {
+func doAddWorker(stdout io.Writer, planFile string, opts *addWorkerOpts, newWorker install.Node) error {
+	out := messagesOut(stdout, opts.OutputFormat)
 	planner := &install.FilePlanner{File: planFile}
 	if !planner.PlanExists() {
 		return errors.New("add-worker can only be used with an existin plan file")
@@ -59,7 +60,7 @@ func doAddWorker(out io.Writer, planFile string, opts *addWorkerOpts, newWorker
 		Verbose:                  opts.Verbose,
 		SkipCAGeneration:         true,
 	}
-	executor, err := install.NewExecutor(out, os.Stderr, execOpts)
+	executor, err := install.NewExecutor(stdout, os.Stderr, execOpts)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdAddOn returns the add-on command
+func NewCmdAddOn(out io.Writer) *cobra.Command {
+	var planFile string
+	cmd := &cobra.Command{
+		Use:   "addon",
+		Short: "manage the add-ons of your Kubernetes cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+	addPlanFileFlag(cmd.PersistentFlags(), &planFile)
+	cmd.AddCommand(NewCmdAddOnList(out, &planFile))
+	cmd.AddCommand(NewCmdAddOnEnable(out, &planFile))
+	cmd.AddCommand(NewCmdAddOnDisable(out, &planFile))
+	return cmd
+}
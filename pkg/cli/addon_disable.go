@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type addOnDisableCmd struct {
+	out      io.Writer
+	planner  install.Planner
+	executor install.Executor
+	addOn    string
+}
+
+// NewCmdAddOnDisable returns the command for removing an add-on from an existing cluster
+func NewCmdAddOnDisable(out io.Writer, planFile *string) *cobra.Command {
+	opts := addOnEnableOpts{}
+	cmd := &cobra.Command{
+		Use:   "disable ADD-ON",
+		Short: "disable an add-on and remove it from the cluster",
+		Long: `Disable an add-on and remove it from the cluster.
+
+The workloads and services of the add-on are deleted. The persistent volumes
+of the add-on are kept, so that its data is available if it is enabled again.
+The plan file is updated to record that the add-on is disabled.
+
+Use "kismatic addon list" to find the add-ons that can be disabled.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			planner := &install.FilePlanner{File: *planFile}
+			executorOpts := install.ExecutorOptions{
+				GeneratedAssetsDirectory: opts.generatedAssetsDir,
+				OutputFormat:             opts.outputFormat,
+				Verbose:                  opts.verbose,
+			}
+			executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
+			if err != nil {
+				return err
+			}
+			c := &addOnDisableCmd{
+				out:      messagesOut(out, opts.outputFormat),
+				planner:  planner,
+				executor: executor,
+				addOn:    args[0],
+			}
+			return c.run()
+		},
+	}
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process are stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
+	return cmd
+}
+
+func (c *addOnDisableCmd) run() error {
+	if !c.planner.PlanExists() {
+		return errors.New("add-ons can only be disabled with an existing plan file")
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %v", err)
+	}
+	addOn, err := install.GetAddOn(c.addOn)
+	if err != nil {
+		return err
+	}
+	if !addOn.Enabled(plan) {
+		return fmt.Errorf("add-on %q is not enabled", c.addOn)
+	}
+	if err := install.DisableAddOn(plan, c.addOn); err != nil {
+		return err
+	}
+	if err := c.executor.RemoveAddOn(plan, c.addOn); err != nil {
+		return err
+	}
+	if err := c.planner.Write(plan); err != nil {
+		return fmt.Errorf("error updating plan file: %v", err)
+	}
+	util.PrintColor(c.out, util.Green, "\nThe add-on %q was removed successfully\n", c.addOn)
+	return nil
+}
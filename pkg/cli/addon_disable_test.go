@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestAddOnDisableCmd(t *testing.T) {
+	fe := &fakeExecutor{}
+	fp := &fakePlanner{exists: true, plan: &install.Plan{Logging: install.Logging{Enabled: true}}}
+	c := &addOnDisableCmd{
+		out:      &bytes.Buffer{},
+		planner:  fp,
+		executor: fe,
+		addOn:    "logging",
+	}
+	if err := c.run(); err != nil {
+		t.Fatalf("unexpected error disabling add-on: %v", err)
+	}
+	if fe.addOnRemoved != "logging" {
+		t.Errorf("expected add-on %q to be removed, got %q", "logging", fe.addOnRemoved)
+	}
+	if fp.plan.Logging.Enabled {
+		t.Errorf("expected the plan to record that the add-on is disabled")
+	}
+}
+
+func TestAddOnDisableCmdNotEnabled(t *testing.T) {
+	fe := &fakeExecutor{}
+	c := &addOnDisableCmd{
+		out:      &bytes.Buffer{},
+		planner:  &fakePlanner{exists: true, plan: &install.Plan{}},
+		executor: fe,
+		addOn:    "monitoring",
+	}
+	if err := c.run(); err == nil {
+		t.Error("expected error due to add-on not being enabled, but did not get one")
+	}
+	if fe.addOnRemoved != "" {
+		t.Errorf("add-on %q was removed", fe.addOnRemoved)
+	}
+}
+
+func TestAddOnDisableCmdCannotDisable(t *testing.T) {
+	fe := &fakeExecutor{}
+	c := &addOnDisableCmd{
+		out:      &bytes.Buffer{},
+		planner:  &fakePlanner{exists: true, plan: &install.Plan{}},
+		executor: fe,
+		addOn:    "dns",
+	}
+	if err := c.run(); err == nil {
+		t.Error("expected error due to add-on that cannot be disabled, but did not get one")
+	}
+	if fe.addOnRemoved != "" {
+		t.Errorf("add-on %q was removed", fe.addOnRemoved)
+	}
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type addOnEnableOpts struct {
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+}
+
+type addOnEnableCmd struct {
+	out      io.Writer
+	planner  install.Planner
+	executor install.Executor
+	addOn    string
+}
+
+// NewCmdAddOnEnable returns the command for installing an add-on on an existing cluster
+func NewCmdAddOnEnable(out io.Writer, planFile *string) *cobra.Command {
+	opts := addOnEnableOpts{}
+	cmd := &cobra.Command{
+		Use:   "enable ADD-ON",
+		Short: "enable an add-on and install it on the cluster",
+		Long: `Enable an add-on and install it on the cluster.
+
+The add-on is configured with the settings of its section in the plan file.
+The plan file is updated to record that the add-on is enabled.
+
+Use "kismatic addon list" to find the add-ons that can be enabled.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			planner := &install.FilePlanner{File: *planFile}
+			executorOpts := install.ExecutorOptions{
+				GeneratedAssetsDirectory: opts.generatedAssetsDir,
+				OutputFormat:             opts.outputFormat,
+				Verbose:                  opts.verbose,
+			}
+			executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
+			if err != nil {
+				return err
+			}
+			c := &addOnEnableCmd{
+				out:      messagesOut(out, opts.outputFormat),
+				planner:  planner,
+				executor: executor,
+				addOn:    args[0],
+			}
+			return c.run()
+		},
+	}
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process are stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
+	return cmd
+}
+
+func (c *addOnEnableCmd) run() error {
+	if !c.planner.PlanExists() {
+		return errors.New("add-ons can only be enabled with an existing plan file")
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %v", err)
+	}
+	if err := install.EnableAddOn(plan, c.addOn); err != nil {
+		return err
+	}
+	if _, errs := install.ValidatePlan(plan); errs != nil {
+		util.PrintValidationErrors(c.out, errs)
+		return errors.New("the plan file failed validation")
+	}
+	if err := c.executor.InstallAddOn(plan, c.addOn); err != nil {
+		return err
+	}
+	if err := c.planner.Write(plan); err != nil {
+		return fmt.Errorf("error updating plan file: %v", err)
+	}
+	util.PrintColor(c.out, util.Green, "\nThe add-on %q was installed successfully\n", c.addOn)
+	return nil
+}
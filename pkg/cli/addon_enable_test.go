@@ -0,0 +1,41 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestAddOnEnableCmdUnsupportedAddOn(t *testing.T) {
+	fe := &fakeExecutor{}
+	c := &addOnEnableCmd{
+		out:      &bytes.Buffer{},
+		planner:  &fakePlanner{exists: true, plan: &install.Plan{}},
+		executor: fe,
+		addOn:    "foo",
+	}
+	if err := c.run(); err == nil {
+		t.Error("expected error due to unsupported add-on, but did not get one")
+	}
+	if fe.addOnInstalled != "" {
+		t.Errorf("add-on %q was installed", fe.addOnInstalled)
+	}
+}
+
+func TestAddOnEnableCmdInvalidPlan(t *testing.T) {
+	fe := &fakeExecutor{}
+	fp := &fakePlanner{exists: true, plan: &install.Plan{}}
+	c := &addOnEnableCmd{
+		out:      &bytes.Buffer{},
+		planner:  fp,
+		executor: fe,
+		addOn:    "logging",
+	}
+	if err := c.run(); err == nil {
+		t.Error("expected error due to invalid plan, but did not get one")
+	}
+	if fe.addOnInstalled != "" {
+		t.Errorf("add-on %q was installed with an invalid plan", fe.addOnInstalled)
+	}
+}
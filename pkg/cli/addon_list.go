@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+type addOnListCmd struct {
+	out          io.Writer
+	planner      install.Planner
+	outputFormat string
+}
+
+// addOnStatus is the JSON representation of an add-on
+type addOnStatus struct {
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Enabled       bool     `json:"enabled"`
+	PlanSection   string   `json:"planSection,omitempty"`
+	RequiredRoles []string `json:"requiredRoles,omitempty"`
+	CanEnable     bool     `json:"canEnable"`
+	CanDisable    bool     `json:"canDisable"`
+}
+
+// NewCmdAddOnList returns the command for listing the add-ons of the cluster
+func NewCmdAddOnList(out io.Writer, planFile *string) *cobra.Command {
+	c := &addOnListCmd{out: out}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the add-ons and whether they are enabled in the plan file",
+		Long: `List the add-ons and whether they are enabled in the plan file.
+
+Add-ons that are configured by other sections of the plan file, such as the
+ingress controller that is deployed when there are ingress nodes, are listed
+with the section that configures them. The COMMANDS column shows whether the
+add-on can be enabled or disabled with the add-on command.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			c.planner = &install.FilePlanner{File: *planFile}
+			return c.run()
+		},
+	}
+	cmd.Flags().StringVarP(&c.outputFormat, "output", "o", "simple", `output format (options "simple"|"json")`)
+	return cmd
+}
+
+func (c *addOnListCmd) run() error {
+	if c.outputFormat != "simple" && c.outputFormat != "json" {
+		return fmt.Errorf("output format %q is not supported", c.outputFormat)
+	}
+	if !c.planner.PlanExists() {
+		return errors.New("add-ons can only be listed with an existing plan file")
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %v", err)
+	}
+	statuses := []addOnStatus{}
+	for _, a := range install.AddOns() {
+		statuses = append(statuses, addOnStatus{
+			Name:          a.Name,
+			Description:   a.Description,
+			Enabled:       a.Enabled(plan),
+			PlanSection:   a.PlanSection,
+			RequiredRoles: a.RequiredRoles,
+			CanEnable:     a.CanEnable(),
+			CanDisable:    a.CanDisable(),
+		})
+	}
+	if c.outputFormat == "json" {
+		b, err := json.MarshalIndent(statuses, "", "    ")
+		if err != nil {
+			return fmt.Errorf("marshal error: %v", err)
+		}
+		fmt.Fprintln(c.out, string(b))
+		return nil
+	}
+	w := tabwriter.NewWriter(c.out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "NAME\tENABLED\tCOMMANDS\tPLAN SECTION\tDESCRIPTION\n")
+	for _, s := range statuses {
+		commands := []string{}
+		if s.CanEnable {
+			commands = append(commands, "enable")
+		}
+		if s.CanDisable {
+			commands = append(commands, "disable")
+		}
+		fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\n", s.Name, s.Enabled, dashIfEmpty(strings.Join(commands, ",")), dashIfEmpty(s.PlanSection), s.Description)
+	}
+	return w.Flush()
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
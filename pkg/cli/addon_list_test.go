@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestAddOnListCmd(t *testing.T) {
+	out := &bytes.Buffer{}
+	c := &addOnListCmd{
+		out:          out,
+		planner:      &fakePlanner{exists: true, plan: &install.Plan{Linkerd: install.Linkerd{Enabled: true}}},
+		outputFormat: "simple",
+	}
+	if err := c.run(); err != nil {
+		t.Fatalf("unexpected error listing add-ons: %v", err)
+	}
+	var linkerd string
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "linkerd ") {
+			linkerd = line
+		}
+	}
+	if fields := strings.Fields(linkerd); len(fields) < 3 || fields[1] != "true" || fields[2] != "enable,disable" {
+		t.Errorf("unexpected linkerd row %q in output:\n%s", linkerd, out.String())
+	}
+}
+
+func TestAddOnListCmdJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	c := &addOnListCmd{
+		out:          out,
+		planner:      &fakePlanner{exists: true, plan: &install.Plan{}},
+		outputFormat: "json",
+	}
+	if err := c.run(); err != nil {
+		t.Fatalf("unexpected error listing add-ons: %v", err)
+	}
+	statuses := []addOnStatus{}
+	if err := json.Unmarshal(out.Bytes(), &statuses); err != nil {
+		t.Fatalf("error unmarshaling output: %v", err)
+	}
+	if len(statuses) != len(install.AddOns()) {
+		t.Fatalf("expected %d add-ons, got %d", len(install.AddOns()), len(statuses))
+	}
+	for _, s := range statuses {
+		switch s.Name {
+		case "dns":
+			if !s.Enabled || s.CanDisable {
+				t.Errorf("expected dns to always be enabled, got %+v", s)
+			}
+		case "logging":
+			if s.Enabled || !s.CanEnable || !s.CanDisable {
+				t.Errorf("expected logging to be disabled and toggleable, got %+v", s)
+			}
+		}
+	}
+}
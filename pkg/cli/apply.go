@@ -1,9 +1,11 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"text/tabwriter"
 
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
@@ -19,6 +21,10 @@ type applyCmd struct {
 	verbose            bool
 	outputFormat       string
 	skipPreFlight      bool
+	diff               bool
+	resume             bool
+	runsDir            string
+	sshValidation      install.SSHValidationOptions
 }
 
 type applyOpts struct {
@@ -27,6 +33,10 @@ type applyOpts struct {
 	verbose            bool
 	outputFormat       string
 	skipPreFlight      bool
+	diff               bool
+	resume             bool
+	runsDir            string
+	sshValidation      install.SSHValidationOptions
 }
 
 // NewCmdApply creates a cluter using the plan file
@@ -35,6 +45,19 @@ func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "apply",
 		Short: "apply your plan file to create a Kubernetes cluster",
+		Long: `Apply your plan file to create a Kubernetes cluster.
+
+When --diff is set, the plan file is compared to the plan of the last successful
+run found in the runs directory. The changes are printed, and only the playbooks
+required to apply them to the running cluster are executed. Changes that cannot
+be made to a running cluster, such as a new pod CIDR block, are rejected. When
+no run is marked as successful, the plan of the last installation is used. The
+pre-flight checks are run on the nodes that are added, unless --skip-preflight
+is set.
+
+When --resume is set, the last installation, which failed, is continued from the
+first play that did not complete. The pre-flight checks are skipped. The plan file
+must not have changed since the failed installation.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 0 {
 				return fmt.Errorf("Unexpected args: %v", args)
@@ -45,6 +68,7 @@ func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
 				RestartServices:          applyOpts.restartServices,
 				OutputFormat:             applyOpts.outputFormat,
 				Verbose:                  applyOpts.verbose,
+				RunsDirectory:            applyOpts.runsDir,
 			}
 			executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
 			if err != nil {
@@ -60,6 +84,10 @@ func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
 				verbose:            applyOpts.verbose,
 				outputFormat:       applyOpts.outputFormat,
 				skipPreFlight:      applyOpts.skipPreFlight,
+				diff:               applyOpts.diff,
+				resume:             applyOpts.resume,
+				runsDir:            applyOpts.runsDir,
+				sshValidation:      applyOpts.sshValidation,
 			}
 			return applyCmd.run()
 		},
@@ -69,20 +97,33 @@ func NewCmdApply(out io.Writer, installOpts *installOpts) *cobra.Command {
 	cmd.Flags().StringVar(&applyOpts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
 	cmd.Flags().BoolVar(&applyOpts.restartServices, "restart-services", false, "force restart cluster services (Use with care)")
 	cmd.Flags().BoolVar(&applyOpts.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&applyOpts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().StringVarP(&applyOpts.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
 	cmd.Flags().BoolVar(&applyOpts.skipPreFlight, "skip-preflight", false, "skip pre-flight checks, useful when rerunning kismatic")
+	cmd.Flags().BoolVar(&applyOpts.diff, "diff", false, "only apply the changes made to the plan file since the last successful run")
+	cmd.Flags().BoolVar(&applyOpts.resume, "resume", false, "continue the last installation, which failed, from the first play that did not complete")
+	cmd.Flags().StringVar(&applyOpts.runsDir, "runs-dir", "runs", "path to the directory where the runs are stored")
+	addSSHValidationFlags(cmd.Flags(), &applyOpts.sshValidation)
 
 	return cmd
 }
 
 func (c *applyCmd) run() error {
-	// Validate and run pre-flight
+	if c.diff && c.resume {
+		return errors.New("--diff and --resume cannot be used together")
+	}
+	if c.diff {
+		return c.runDiff()
+	}
+	out := messagesOut(c.out, c.outputFormat)
+	// Validate and run pre-flight. The nodes of a failed installation
+	// do not pass the pre-flight checks, so they are skipped when resuming.
 	opts := &validateOpts{
 		planFile:           c.planFile,
 		verbose:            c.verbose,
 		outputFormat:       c.outputFormat,
-		skipPreFlight:      c.skipPreFlight,
+		skipPreFlight:      c.skipPreFlight || c.resume,
 		generatedAssetsDir: c.generatedAssetsDir,
+		sshValidation:      c.sshValidation,
 	}
 	err := doValidate(c.out, c.planner, opts)
 	if err != nil {
@@ -91,7 +132,11 @@ func (c *applyCmd) run() error {
 	plan, err := c.planner.Read()
 
 	// Perform the installation
-	err = c.executor.Install(plan)
+	if c.resume {
+		err = c.executor.ResumeInstall(plan)
+	} else {
+		err = c.executor.Install(plan)
+	}
 	if err != nil {
 		return fmt.Errorf("error installing: %v", err)
 	}
@@ -99,23 +144,148 @@ func (c *applyCmd) run() error {
 	if err := c.executor.RunSmokeTest(plan); err != nil {
 		return fmt.Errorf("error during smoke test: %v", err)
 	}
-	util.PrintColor(c.out, util.Green, "\nThe cluster was installed successfully\n")
+	util.PrintColor(out, util.Green, "\nThe cluster was installed successfully\n")
 
 	// Generate kubeconfig
-	util.PrintHeader(c.out, "Generating Kubeconfig File", '=')
+	util.PrintHeader(out, "Generating Kubeconfig File", '=')
 	err = install.GenerateKubeconfig(plan, c.generatedAssetsDir)
 	if err != nil {
-		util.PrettyPrintWarn(c.out, "Error generating kubeconfig file: %v\n", err)
+		util.PrettyPrintWarn(out, "Error generating kubeconfig file: %v\n", err)
 	} else {
-		util.PrettyPrintOk(c.out, "Generated kubeconfig file in the %q directory", c.generatedAssetsDir)
-		fmt.Fprintf(c.out, "\n")
+		util.PrettyPrintOk(out, "Generated kubeconfig file in the %q directory", c.generatedAssetsDir)
+		fmt.Fprintf(out, "\n")
 		msg := "To use the generated kubeconfig file with kubectl:" +
 			"\n  * use \"kubectl --kubeconfig %s/kubeconfig\"" +
 			"\n  * or copy the config file \"cp %[1]s/kubeconfig ~/.kube/config\"\n"
-		fmt.Fprintf(c.out, msg, c.generatedAssetsDir)
-		fmt.Fprintf(c.out, "Use \"kismatic dashboard\" command to view the Kubernetes dashboard")
+		fmt.Fprintf(out, msg, c.generatedAssetsDir)
+		if len(plan.Users) > 0 {
+			fmt.Fprintf(out, "The kubeconfig files of the users in the plan are named \"%s/kubeconfig-<user>\"\n", c.generatedAssetsDir)
+		}
+		fmt.Fprintf(out, "Use \"kismatic dashboard\" command to view the Kubernetes dashboard")
+	}
+
+	fmt.Fprintf(out, "\n")
+	return nil
+}
+
+func (c *applyCmd) runDiff() error {
+	out := messagesOut(c.out, c.outputFormat)
+	previous, runDir, err := install.LastSuccessfulPlan(c.runsDir)
+	if err != nil {
+		return fmt.Errorf("error reading plan of the running cluster: %v", err)
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	if _, errs := install.ValidatePlan(plan); errs != nil {
+		util.PrintValidationErrors(out, errs)
+		return errors.New("the plan file failed validation")
+	}
+
+	util.PrintHeader(out, "Comparing Plan To Running Cluster", '=')
+	fmt.Fprintf(out, "Comparing against the plan of run %q\n", runDir)
+	diff := install.DiffPlans(previous, plan)
+	if diff.Empty() {
+		util.PrettyPrintOk(out, "No changes to apply")
+		return nil
+	}
+	printPlanDiff(out, diff)
+	if _, errs := install.ValidatePlanDiff(&diff); errs != nil {
+		util.PrintValidationErrors(out, errs)
+		return errors.New("the changes cannot be applied to a running cluster")
+	}
+
+	// New nodes must be reachable before making changes
+	for _, n := range diff.NodesAdded {
+		node := n.Node
+		sshCon := &install.SSHConnection{
+			SSHConfig: &plan.Cluster.SSH,
+			Node:      &node,
+		}
+		if _, errs := install.ValidateSSHConnection(sshCon, fmt.Sprintf("New %s node", n.Role)); errs != nil {
+			util.PrintValidationErrors(out, errs)
+			return fmt.Errorf("could not establish SSH connection to node %q", node.Host)
+		}
+	}
+	if !c.skipPreFlight && len(diff.NodesAdded) > 0 {
+		util.PrintHeader(out, "Running Pre-Flight Checks On New Nodes", '=')
+		preFlightPlan := newNodesPreFlightPlan(*plan, diff)
+		if err := c.executor.RunPreFlightCheck(&preFlightPlan); err != nil {
+			return err
+		}
 	}
 
-	fmt.Fprintf(c.out, "\n")
+	if err := c.executor.ApplyDiff(previous, plan); err != nil {
+		return fmt.Errorf("error applying changes: %v", err)
+	}
+	if err := c.executor.RunSmokeTest(plan); err != nil {
+		return fmt.Errorf("error during smoke test: %v", err)
+	}
+	util.PrintColor(out, util.Green, "\nThe changes were applied successfully\n")
+	if len(diff.Authorization) > 0 {
+		if err := install.GenerateKubeconfig(plan, c.generatedAssetsDir); err != nil {
+			util.PrettyPrintWarn(out, "Error generating kubeconfig files: %v\n", err)
+		} else {
+			util.PrettyPrintOk(out, "Generated kubeconfig files for the users in the %q directory", c.generatedAssetsDir)
+		}
+	}
 	return nil
 }
+
+// returns the plan with only the nodes that were added to the cluster, such
+// that the pre-flight checks do not run against the nodes that are running
+func newNodesPreFlightPlan(plan install.Plan, d install.PlanDiff) install.Plan {
+	p := plan
+	p.Etcd.Nodes = d.NodesAddedTo("etcd")
+	p.Etcd.ExpectedCount = len(p.Etcd.Nodes)
+	p.Master.Nodes = d.NodesAddedTo("master")
+	p.Master.ExpectedCount = len(p.Master.Nodes)
+	p.Worker.Nodes = d.NodesAddedTo("worker")
+	p.Worker.ExpectedCount = len(p.Worker.Nodes)
+	p.Ingress.Nodes = d.NodesAddedTo("ingress")
+	p.Ingress.ExpectedCount = len(p.Ingress.Nodes)
+	p.Storage.Nodes = d.NodesAddedTo("storage")
+	p.Storage.ExpectedCount = len(p.Storage.Nodes)
+	return p
+}
+
+func printPlanDiff(out io.Writer, d install.PlanDiff) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, n := range d.NodesAdded {
+		fmt.Fprintf(w, "+\t%s node\t%s (%s)\n", n.Role, n.Node.Host, n.Node.IP)
+	}
+	for _, n := range d.NodesRemoved {
+		fmt.Fprintf(w, "-\t%s node\t%s (%s)\n", n.Role, n.Node.Host, n.Node.IP)
+	}
+	for _, n := range d.NodesModified {
+		fmt.Fprintf(w, "~\t%s node\t%s (%s)\n", n.Role, n.Node.Host, n.Node.IP)
+	}
+	for _, n := range d.RolesChanged {
+		fmt.Fprintf(w, "~\t%s role\t%s\n", n.Role, n.Node.Host)
+	}
+	sections := []struct {
+		name    string
+		changes []install.FieldChange
+	}{
+		{"cluster", d.Cluster},
+		{"cluster.networking", d.Networking},
+		{"master", d.Master},
+		{"docker_registry", d.DockerRegistry},
+	}
+	for _, s := range sections {
+		for _, f := range s.changes {
+			fmt.Fprintf(w, "~\t%s.%s\t%q -> %q\n", s.name, f.Field, f.Old, f.New)
+		}
+	}
+	for _, v := range d.NFSVolumesAdded {
+		fmt.Fprintf(w, "+\tnfs volume\t%s:%s\n", v.Host, v.Path)
+	}
+	for _, v := range d.NFSVolumesRemoved {
+		fmt.Fprintf(w, "-\tnfs volume\t%s:%s\n", v.Host, v.Path)
+	}
+	for _, f := range d.Authorization {
+		fmt.Fprintf(w, "~\t%s\t%q -> %q\n", f.Field, f.Old, f.New)
+	}
+	w.Flush()
+}
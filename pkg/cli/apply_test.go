@@ -2,6 +2,8 @@ package cli
 
 import (
 	"bytes"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/apprenda/kismatic/pkg/install"
@@ -64,3 +66,67 @@ func TestApplyCmdInvalidPlanFound(t *testing.T) {
 // 		t.Errorf("did not read CA cert when skip CA generation was set to true")
 // 	}
 // }
+
+func TestApplyCmdDiffNoSuccessfulRuns(t *testing.T) {
+	out := &bytes.Buffer{}
+	fp := &fakePlanner{
+		exists: true,
+		plan:   &install.Plan{},
+	}
+	fe := &fakeExecutor{}
+	runsDir, err := ioutil.TempDir("", "apply-diff-test")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(runsDir)
+
+	applyCmd := &applyCmd{
+		out:      out,
+		planner:  fp,
+		executor: fe,
+		diff:     true,
+		runsDir:  runsDir,
+	}
+	if err := applyCmd.run(); err == nil {
+		t.Error("expected error when there are no successful runs")
+	}
+	if fe.applyDiffCalled || fe.installCalled {
+		t.Error("the cluster was modified without a previous successful run")
+	}
+}
+
+func TestApplyCmdDiffAndResume(t *testing.T) {
+	fe := &fakeExecutor{}
+	applyCmd := &applyCmd{
+		out:      &bytes.Buffer{},
+		planner:  &fakePlanner{exists: true, plan: &install.Plan{}},
+		executor: fe,
+		diff:     true,
+		resume:   true,
+	}
+	if err := applyCmd.run(); err == nil {
+		t.Errorf("expected an error when both --diff and --resume are set")
+	}
+	if fe.resumeCalled || fe.applyDiffCalled {
+		t.Errorf("expected nothing to run when both --diff and --resume are set")
+	}
+}
+
+func TestNewNodesPreFlightPlan(t *testing.T) {
+	existing := install.Node{Host: "worker01", IP: "10.0.0.1"}
+	added := install.Node{Host: "worker02", IP: "10.0.0.2"}
+	previous := install.Plan{
+		Etcd:   install.NodeGroup{ExpectedCount: 1, Nodes: []install.Node{{Host: "etcd01", IP: "10.0.0.3"}}},
+		Worker: install.NodeGroup{ExpectedCount: 1, Nodes: []install.Node{existing}},
+	}
+	plan := previous
+	plan.Worker = install.NodeGroup{ExpectedCount: 2, Nodes: []install.Node{existing, added}}
+
+	p := newNodesPreFlightPlan(plan, install.DiffPlans(&previous, &plan))
+	if len(p.Etcd.Nodes) != 0 || p.Etcd.ExpectedCount != 0 {
+		t.Errorf("expected the running etcd nodes to be left out, got %+v", p.Etcd)
+	}
+	if len(p.Worker.Nodes) != 1 || p.Worker.Nodes[0].Host != "worker02" || p.Worker.ExpectedCount != 1 {
+		t.Errorf("expected only the new worker to be checked, got %+v", p.Worker)
+	}
+}
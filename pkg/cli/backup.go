@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type backupOpts struct {
+	generatedAssetsDir string
+	runsDir            string
+}
+
+type restoreOpts struct {
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+}
+
+type backupRestoreCmd struct {
+	out      io.Writer
+	planner  install.Planner
+	executor install.Executor
+	archive  string
+}
+
+// NewCmdBackup returns the command for backing up and restoring the etcd clusters
+func NewCmdBackup(out io.Writer) *cobra.Command {
+	var planFile string
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "backup and restore the etcd clusters of your Kubernetes cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+	addPlanFileFlag(cmd.PersistentFlags(), &planFile)
+	cmd.AddCommand(NewCmdBackupCreate(out, &planFile))
+	cmd.AddCommand(NewCmdBackupRestore(out, &planFile))
+	return cmd
+}
+
+// NewCmdBackupCreate returns the command for creating a backup of the etcd clusters
+func NewCmdBackupCreate(out io.Writer, planFile *string) *cobra.Command {
+	opts := backupOpts{}
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "create a backup of the etcd clusters",
+		Long: `Create a backup of the etcd clusters.
+
+A backup of the Kubernetes and the networking etcd clusters is taken on the first
+etcd node. The backup is stored in a timestamped archive under the runs directory,
+together with the plan file and the generated keys of the cluster.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doBackupCreate(out, *planFile, opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process are stored")
+	cmd.Flags().StringVar(&opts.runsDir, "runs-dir", "runs", "path to the directory where the backup will be stored")
+	return cmd
+}
+
+// NewCmdBackupRestore returns the command for restoring the etcd clusters from a backup
+func NewCmdBackupRestore(out io.Writer, planFile *string) *cobra.Command {
+	opts := restoreOpts{}
+	cmd := &cobra.Command{
+		Use:   "restore ARCHIVE",
+		Short: "restore the etcd clusters from a backup archive",
+		Long: `Restore the etcd clusters from a backup archive.
+
+All etcd members are stopped and their data is removed. The first etcd node is
+restored from the backup, and the remaining nodes are added back to the cluster
+one at a time. The API servers are stopped during the restore.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			planner := &install.FilePlanner{File: *planFile}
+			executorOpts := install.ExecutorOptions{
+				GeneratedAssetsDirectory: opts.generatedAssetsDir,
+				OutputFormat:             opts.outputFormat,
+				Verbose:                  opts.verbose,
+			}
+			executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
+			if err != nil {
+				return err
+			}
+			c := &backupRestoreCmd{
+				out:      messagesOut(out, opts.outputFormat),
+				planner:  planner,
+				executor: executor,
+				archive:  args[0],
+			}
+			return c.run()
+		},
+	}
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process are stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the restore")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "restore output format (options \"simple\"|\"raw\"|\"json\")")
+	return cmd
+}
+
+func doBackupCreate(out io.Writer, planFile string, opts backupOpts) error {
+	planner := &install.FilePlanner{File: planFile}
+	if !planner.PlanExists() {
+		return fmt.Errorf("plan file not found at %q", planFile)
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	util.PrintHeader(out, "Creating Etcd Backup", '=')
+	backupOpts := install.BackupOptions{
+		PlanFile:                 planFile,
+		GeneratedAssetsDirectory: opts.generatedAssetsDir,
+		RunsDirectory:            opts.runsDir,
+	}
+	archive, err := install.CreateBackup(plan, backupOpts)
+	if err != nil {
+		return fmt.Errorf("error creating backup: %v", err)
+	}
+	util.PrettyPrintOk(out, "Backup stored in %q", archive)
+	return nil
+}
+
+func (c *backupRestoreCmd) run() error {
+	if !c.planner.PlanExists() {
+		return errors.New("restore can only be used with an existing plan file")
+	}
+	if _, err := os.Stat(c.archive); err != nil {
+		return fmt.Errorf("error reading backup archive: %v", err)
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %v", err)
+	}
+	if _, errs := install.ValidatePlan(plan); errs != nil {
+		util.PrintValidationErrors(c.out, errs)
+		return errors.New("the plan file failed validation")
+	}
+	if err := c.executor.RestoreEtcd(plan, c.archive); err != nil {
+		return fmt.Errorf("error restoring etcd: %v", err)
+	}
+	util.PrintColor(c.out, util.Green, "\nThe etcd clusters were restored successfully\n")
+	return nil
+}
@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestBackupRestoreCmdMissingArchive(t *testing.T) {
+	out := &bytes.Buffer{}
+	fp := &fakePlanner{
+		exists: true,
+		plan:   &install.Plan{},
+	}
+	fe := &fakeExecutor{}
+	c := &backupRestoreCmd{
+		out:      out,
+		planner:  fp,
+		executor: fe,
+		archive:  "/non/existent/kismatic-backup.tar.gz",
+	}
+	if err := c.run(); err == nil {
+		t.Error("expected an error when the archive does not exist")
+	}
+	if fe.restoreEtcdCalled {
+		t.Error("restore was called without a backup archive")
+	}
+}
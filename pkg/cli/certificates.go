@@ -0,0 +1,23 @@
+package cli
+
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdCertificates returns the certificates command
+func NewCmdCertificates(out io.Writer) *cobra.Command {
+	var planFile string
+	cmd := &cobra.Command{
+		Use:   "certificates",
+		Short: "manage the certificates of your Kubernetes cluster",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+	addPlanFileFlag(cmd.PersistentFlags(), &planFile)
+	cmd.AddCommand(NewCmdCertificatesList(out, &planFile))
+	cmd.AddCommand(NewCmdCertificatesRotate(out, &planFile))
+	return cmd
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/spf13/cobra"
+)
+
+type certificatesListOpts struct {
+	generatedAssetsDir string
+	deployed           bool
+	outputFormat       string
+}
+
+// NewCmdCertificatesList returns the command for listing the certificates of the cluster
+func NewCmdCertificatesList(out io.Writer, planFile *string) *cobra.Command {
+	opts := certificatesListOpts{}
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the certificates of the cluster and their expiry",
+		Long: `List the certificates of the cluster and their expiry.
+
+The certificates in the generated keys directory are listed with their common name,
+subject alternative names, issuer and the number of days until they expire. Each
+certificate is compared against the names that would be generated for the current
+plan file.
+
+When --deployed is set, the certificates that are deployed on each node are read
+over SSH and listed as well.
+
+The prometheus output format can be written to a node exporter textfile collector
+directory to alert before the certificates expire.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			return doCertificatesList(out, *planFile, opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process are stored")
+	cmd.Flags().BoolVar(&opts.deployed, "deployed", false, "connect to the nodes and list the certificates deployed on them")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options "simple"|"json"|"prometheus")`)
+	return cmd
+}
+
+func doCertificatesList(out io.Writer, planFile string, opts certificatesListOpts) error {
+	if opts.outputFormat != "simple" && opts.outputFormat != "json" && opts.outputFormat != "prometheus" {
+		return fmt.Errorf("output format %q is not supported", opts.outputFormat)
+	}
+	planner := &install.FilePlanner{File: planFile}
+	if !planner.PlanExists() {
+		return fmt.Errorf("plan file not found at %q", planFile)
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	certs, err := install.ListCertificates(plan, filepath.Join(opts.generatedAssetsDir, "keys"))
+	if err != nil {
+		return err
+	}
+	if opts.deployed {
+		deployed, err := install.ListDeployedCertificates(plan)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, deployed...)
+	}
+	return printCertificates(out, certs, opts.outputFormat)
+}
+
+func printCertificates(out io.Writer, certs []install.CertificateInfo, format string) error {
+	switch format {
+	case "simple":
+		w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+		fmt.Fprintf(w, "NAME\tNODE\tCN\tSANS\tISSUER\tEXPIRES IN\tMATCHES PLAN\n")
+		for _, c := range certs {
+			node := c.Node
+			if node == "" {
+				node = "-"
+			}
+			matches := "yes"
+			if !c.MatchesPlan {
+				matches = "no"
+				if len(c.MissingSANs) > 0 {
+					matches = fmt.Sprintf("no (missing %s)", strings.Join(c.MissingSANs, ","))
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d days\t%s\n", c.Name, node, c.CommonName, strings.Join(c.SANs, ","), c.Issuer, c.DaysToExpiry, matches)
+		}
+		return w.Flush()
+	case "json":
+		b, err := json.MarshalIndent(certs, "", "    ")
+		if err != nil {
+			return fmt.Errorf("marshal error: %v", err)
+		}
+		fmt.Fprintln(out, string(b))
+	case "prometheus":
+		fmt.Fprintln(out, "# HELP kismatic_certificate_expiration_timestamp_seconds Time when the certificate expires, in seconds since the Unix epoch.")
+		fmt.Fprintln(out, "# TYPE kismatic_certificate_expiration_timestamp_seconds gauge")
+		for _, c := range certs {
+			fmt.Fprintf(out, "kismatic_certificate_expiration_timestamp_seconds{%s} %d\n", prometheusCertLabels(c), c.NotAfter.Unix())
+		}
+		fmt.Fprintln(out, "# HELP kismatic_certificate_matches_plan Whether the certificate contains the names required by the plan file.")
+		fmt.Fprintln(out, "# TYPE kismatic_certificate_matches_plan gauge")
+		for _, c := range certs {
+			matches := 0
+			if c.MatchesPlan {
+				matches = 1
+			}
+			fmt.Fprintf(out, "kismatic_certificate_matches_plan{%s} %d\n", prometheusCertLabels(c), matches)
+		}
+	}
+	return nil
+}
+
+func prometheusCertLabels(c install.CertificateInfo) string {
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return fmt.Sprintf(`name="%s",node="%s",path="%s",cn="%s"`, escape.Replace(c.Name), escape.Replace(c.Node), escape.Replace(c.Path), escape.Replace(c.CommonName))
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestPrintCertificatesPrometheus(t *testing.T) {
+	out := &bytes.Buffer{}
+	certs := []install.CertificateInfo{
+		{
+			Name:        "master",
+			Node:        "master",
+			Path:        "/etc/kubernetes/kubenode.pem",
+			CommonName:  "master",
+			NotAfter:    time.Unix(1500000000, 0),
+			MatchesPlan: false,
+		},
+	}
+	if err := printCertificates(out, certs, "prometheus"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		`kismatic_certificate_expiration_timestamp_seconds{name="master",node="master",path="/etc/kubernetes/kubenode.pem",cn="master"} 1500000000`,
+		`kismatic_certificate_matches_plan{name="master",node="master",path="/etc/kubernetes/kubenode.pem",cn="master"} 0`,
+	}
+	for _, e := range expected {
+		if !strings.Contains(out.String(), e) {
+			t.Errorf("expected output to contain %q, got:\n%s", e, out.String())
+		}
+	}
+}
+
+func TestCertificatesListUnsupportedFormat(t *testing.T) {
+	if err := doCertificatesList(&bytes.Buffer{}, "kismatic-cluster.yaml", certificatesListOpts{outputFormat: "yaml"}); err == nil {
+		t.Error("expected an error with an unsupported output format")
+	}
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type certificatesRotateOpts struct {
+	generatedAssetsDir string
+	rotateCA           bool
+	verbose            bool
+	outputFormat       string
+}
+
+type certificatesRotateCmd struct {
+	out                io.Writer
+	planner            install.Planner
+	executor           install.Executor
+	generatedAssetsDir string
+	rotateCA           bool
+}
+
+// NewCmdCertificatesRotate returns the command for rotating the certificates of the cluster
+func NewCmdCertificatesRotate(out io.Writer, planFile *string) *cobra.Command {
+	opts := certificatesRotateOpts{}
+	cmd := &cobra.Command{
+		Use:   "rotate",
+		Short: "regenerate the certificates of the cluster and deploy them to the nodes",
+		Long: `Regenerate the certificates of the cluster and deploy them to the nodes.
+
+The node, user, service account and docker registry certificates are regenerated
+with the expiry set in the plan file. The existing certificates are moved to a
+backup directory next to the generated keys. The components using the certificates
+are restarted one node at a time, starting with the etcd nodes.
+
+When --rotate-ca is set, a new Certificate Authority is also generated. All nodes
+are restarted at the same time, as they cannot trust each other until all of them
+have certificates signed by the new Certificate Authority. If the plan file provides
+the Certificate Authority with ca_cert and ca_key, they must be set to the new
+Certificate Authority, which is used instead of generating one.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			planner := &install.FilePlanner{File: *planFile}
+			executorOpts := install.ExecutorOptions{
+				GeneratedAssetsDirectory: opts.generatedAssetsDir,
+				OutputFormat:             opts.outputFormat,
+				Verbose:                  opts.verbose,
+			}
+			executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
+			if err != nil {
+				return err
+			}
+			c := &certificatesRotateCmd{
+				out:                messagesOut(out, opts.outputFormat),
+				planner:            planner,
+				executor:           executor,
+				generatedAssetsDir: opts.generatedAssetsDir,
+				rotateCA:           opts.rotateCA,
+			}
+			return c.run()
+		},
+	}
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process are stored")
+	cmd.Flags().BoolVar(&opts.rotateCA, "rotate-ca", false, "generate a new Certificate Authority (Use with care)")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the rotation")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "rotation output format (options \"simple\"|\"raw\"|\"json\")")
+	return cmd
+}
+
+func (c *certificatesRotateCmd) run() error {
+	if !c.planner.PlanExists() {
+		return errors.New("rotate can only be used with an existing plan file")
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %v", err)
+	}
+	if _, errs := install.ValidatePlan(plan); errs != nil {
+		util.PrintValidationErrors(c.out, errs)
+		return errors.New("the plan file failed validation")
+	}
+	if err := c.executor.RotateCertificates(plan, c.rotateCA); err != nil {
+		return fmt.Errorf("error rotating certificates: %v", err)
+	}
+	util.PrintColor(c.out, util.Green, "\nThe certificates were rotated successfully\n")
+
+	// The kubeconfig file contains the admin certificate
+	util.PrintHeader(c.out, "Generating Kubeconfig File", '=')
+	if err := install.GenerateKubeconfig(plan, c.generatedAssetsDir); err != nil {
+		util.PrettyPrintWarn(c.out, "Error generating kubeconfig file: %v\n", err)
+		return nil
+	}
+	util.PrettyPrintOk(c.out, "Generated kubeconfig file in the %q directory", c.generatedAssetsDir)
+	return nil
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestCertificatesRotateCmdInvalidPlan(t *testing.T) {
+	out := &bytes.Buffer{}
+	fp := &fakePlanner{
+		exists: true,
+		plan:   &install.Plan{},
+	}
+	fe := &fakeExecutor{}
+	c := &certificatesRotateCmd{
+		out:      out,
+		planner:  fp,
+		executor: fe,
+	}
+	if err := c.run(); err == nil {
+		t.Error("expected error due to invalid plan, but did not get one")
+	}
+	if fe.rotateCertsCalled {
+		t.Error("certificates were rotated with an invalid plan")
+	}
+}
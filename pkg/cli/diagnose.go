@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type diagnoseOpts struct {
+	runsDir    string
+	runDir     string
+	outputFile string
+}
+
+type diagnoseCmd struct {
+	out        io.Writer
+	planner    install.Planner
+	planFile   string
+	runsDir    string
+	runDir     string
+	outputFile string
+	// collect writes the diagnostics of the cluster to w
+	collect func(p *install.Plan, opts install.DiagnosticsOptions, w io.Writer) error
+}
+
+// NewCmdDiagnose returns the command for collecting the diagnostics of the cluster
+func NewCmdDiagnose(out io.Writer) *cobra.Command {
+	var planFile string
+	opts := diagnoseOpts{}
+	cmd := &cobra.Command{
+		Use:   "diagnose",
+		Short: "collect logs and diagnostics from the nodes of your cluster",
+		Long: `Collect logs and diagnostics from every node in the plan file into a tarball.
+
+The journal of the services installed by kismatic, the docker containers, the
+iptables rules, the calico status, the metadata of the certificates, the disk
+and memory usage, and the inspector results are collected from each node. They
+are archived together with the plan file and the known files of the run
+directory, such as the ansible log. The admin password, the values of fields
+that look like secrets, the client keys of kubeconfig files, and private keys
+are redacted from the archive.
+
+A node that cannot be reached does not stop the collection, the error is
+recorded in the archive instead.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			outputFile := opts.outputFile
+			if outputFile == "" {
+				outputFile = fmt.Sprintf("diagnostics-%s.tar.gz", time.Now().Format("2006-01-02-15-04-05"))
+			}
+			c := &diagnoseCmd{
+				out:        out,
+				planner:    &install.FilePlanner{File: planFile},
+				planFile:   planFile,
+				runsDir:    opts.runsDir,
+				runDir:     opts.runDir,
+				outputFile: outputFile,
+				collect: func(p *install.Plan, diagOpts install.DiagnosticsOptions, w io.Writer) error {
+					return install.CollectDiagnostics(out, p, diagOpts, w)
+				},
+			}
+			return c.run()
+		},
+	}
+	addPlanFileFlag(cmd.Flags(), &planFile)
+	cmd.Flags().StringVar(&opts.runsDir, "runs-dir", "runs", "path to the directory where the runs are stored")
+	cmd.Flags().StringVar(&opts.runDir, "run-dir", "", "path to the run directory to add to the diagnostics (default the most recent run in --runs-dir)")
+	cmd.Flags().StringVarP(&opts.outputFile, "output-file", "o", "", "path to the diagnostics tarball to write (default \"diagnostics-<timestamp>.tar.gz\")")
+	return cmd
+}
+
+func (c *diagnoseCmd) run() error {
+	if !c.planner.PlanExists() {
+		return errors.New("diagnose can only be used with an existing plan file")
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	runDir := c.runDir
+	if runDir == "" {
+		runDir, err = install.LastRun(c.runsDir)
+		if err != nil {
+			util.PrettyPrintWarn(c.out, "The diagnostics will not include a run directory: %v", err)
+		}
+	}
+
+	util.PrintHeader(c.out, "Collecting Diagnostics", '=')
+	f, err := os.OpenFile(c.outputFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating diagnostics file: %v", err)
+	}
+	opts := install.DiagnosticsOptions{
+		PlanFile:     c.planFile,
+		RunDirectory: runDir,
+	}
+	err = c.collect(plan, opts, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(c.outputFile)
+		return fmt.Errorf("error collecting diagnostics: %v", err)
+	}
+	util.PrettyPrintOk(c.out, "Diagnostics stored in %q", c.outputFile)
+	return nil
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func diagnoseTestCmd(t *testing.T) (*diagnoseCmd, string) {
+	dir, err := ioutil.TempDir("", "diagnose-cmd-tests")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	c := &diagnoseCmd{
+		out:        &bytes.Buffer{},
+		planner:    &fakePlanner{exists: true, plan: &install.Plan{}},
+		planFile:   "kismatic-cluster.yaml",
+		runsDir:    filepath.Join(dir, "runs"),
+		outputFile: filepath.Join(dir, "diagnostics.tar.gz"),
+	}
+	return c, dir
+}
+
+func TestDiagnoseCmdUsesLastRun(t *testing.T) {
+	c, dir := diagnoseTestCmd(t)
+	defer os.RemoveAll(dir)
+	for _, r := range []string{"apply/2017-01-27-10-00-00", "apply/2017-01-28-10-00-00"} {
+		if err := os.MkdirAll(filepath.Join(c.runsDir, r), 0777); err != nil {
+			t.Fatalf("error creating run directory: %v", err)
+		}
+	}
+	var gotOpts install.DiagnosticsOptions
+	c.collect = func(p *install.Plan, opts install.DiagnosticsOptions, w io.Writer) error {
+		gotOpts = opts
+		_, err := w.Write([]byte("diagnostics"))
+		return err
+	}
+	if err := c.run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotOpts.RunDirectory != filepath.Join(c.runsDir, "apply", "2017-01-28-10-00-00") {
+		t.Errorf("expected the most recent run to be collected, got %q", gotOpts.RunDirectory)
+	}
+	if gotOpts.PlanFile != "kismatic-cluster.yaml" {
+		t.Errorf("unexpected plan file %q", gotOpts.PlanFile)
+	}
+	b, err := ioutil.ReadFile(c.outputFile)
+	if err != nil {
+		t.Fatalf("error reading diagnostics file: %v", err)
+	}
+	if string(b) != "diagnostics" {
+		t.Errorf("unexpected diagnostics file content %q", b)
+	}
+}
+
+func TestDiagnoseCmdNoRuns(t *testing.T) {
+	c, dir := diagnoseTestCmd(t)
+	defer os.RemoveAll(dir)
+	c.collect = func(p *install.Plan, opts install.DiagnosticsOptions, w io.Writer) error {
+		if opts.RunDirectory != "" {
+			t.Errorf("did not expect a run directory, got %q", opts.RunDirectory)
+		}
+		return nil
+	}
+	if err := c.run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDiagnoseCmdCollectError(t *testing.T) {
+	c, dir := diagnoseTestCmd(t)
+	defer os.RemoveAll(dir)
+	c.collect = func(p *install.Plan, opts install.DiagnosticsOptions, w io.Writer) error {
+		return errors.New("disk full")
+	}
+	if err := c.run(); err == nil {
+		t.Fatalf("expected an error when the diagnostics could not be collected")
+	}
+	if _, err := os.Stat(c.outputFile); !os.IsNotExist(err) {
+		t.Errorf("expected the incomplete diagnostics file to be removed")
+	}
+}
+
+func TestDiagnoseCmdNoPlan(t *testing.T) {
+	c, dir := diagnoseTestCmd(t)
+	defer os.RemoveAll(dir)
+	c.planner = &fakePlanner{exists: false}
+	if err := c.run(); err == nil {
+		t.Errorf("expected an error when the plan file does not exist")
+	}
+}
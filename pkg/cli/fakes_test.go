@@ -33,19 +33,39 @@ func (fp *fakePlanner) GetDashboardURL(p *install.Plan) (string, error) {
 }
 
 type fakeExecutor struct {
-	installCalled bool
-	err           error
+	installCalled     bool
+	resumeCalled      bool
+	upgradeCalled     bool
+	applyDiffCalled   bool
+	restoreEtcdCalled bool
+	rotateCertsCalled bool
+	addOnInstalled    string
+	addOnRemoved      string
+	err               error
 }
 
 func (fe *fakeExecutor) AddWorker(p *install.Plan, newWorker install.Node) (*install.Plan, error) {
 	return nil, nil
 }
 
+func (fe *fakeExecutor) AddMaster(p *install.Plan, newMaster install.Node) (*install.Plan, error) {
+	return nil, nil
+}
+
+func (fe *fakeExecutor) AddEtcd(p *install.Plan, newEtcd install.Node) (*install.Plan, error) {
+	return nil, nil
+}
+
 func (fe *fakeExecutor) Install(p *install.Plan) error {
 	fe.installCalled = true
 	return fe.err
 }
 
+func (fe *fakeExecutor) ResumeInstall(p *install.Plan) error {
+	fe.resumeCalled = true
+	return fe.err
+}
+
 func (fe *fakeExecutor) RunPreFlightCheck(p *install.Plan) error {
 	return nil
 }
@@ -62,6 +82,40 @@ func (fe *fakeExecutor) AddVolume(*install.Plan, install.StorageVolume) error {
 	return nil
 }
 
+func (fe *fakeExecutor) RemoveNode(p *install.Plan, host string) (*install.Plan, error) {
+	return nil, nil
+}
+
+func (fe *fakeExecutor) Upgrade(*install.Plan, []install.NodeVersion) error {
+	fe.upgradeCalled = true
+	return fe.err
+}
+
+func (fe *fakeExecutor) ApplyDiff(previous, plan *install.Plan) error {
+	fe.applyDiffCalled = true
+	return fe.err
+}
+
+func (fe *fakeExecutor) RestoreEtcd(p *install.Plan, archive string) error {
+	fe.restoreEtcdCalled = true
+	return fe.err
+}
+
+func (fe *fakeExecutor) RotateCertificates(p *install.Plan, rotateCA bool) error {
+	fe.rotateCertsCalled = true
+	return fe.err
+}
+
+func (fe *fakeExecutor) InstallAddOn(p *install.Plan, name string) error {
+	fe.addOnInstalled = name
+	return fe.err
+}
+
+func (fe *fakeExecutor) RemoveAddOn(p *install.Plan, name string) error {
+	fe.addOnRemoved = name
+	return fe.err
+}
+
 type fakePKI struct {
 	called              bool
 	generateCACalled    bool
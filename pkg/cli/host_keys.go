@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/ssh"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type hostKeysRotateCmd struct {
+	out        io.Writer
+	planner    install.Planner
+	knownHosts *ssh.KnownHosts
+	hosts      []string
+	// connect establishes an SSH connection to the node, recording its key
+	connect func(ip string, sshConfig install.SSHConfig) error
+}
+
+// returns the known_hosts file of the cluster, stored in the generated assets
+// directory used by the command
+func knownHostsFile(cmd *cobra.Command) string {
+	dir := "generated"
+	if f := cmd.Flags().Lookup("generated-assets-dir"); f != nil {
+		dir = f.Value.String()
+	}
+	return filepath.Join(dir, "known_hosts")
+}
+
+// NewCmdHostKeys returns the command for managing the recorded SSH host keys of the nodes
+func NewCmdHostKeys(out io.Writer) *cobra.Command {
+	var planFile string
+	cmd := &cobra.Command{
+		Use:   "host-keys",
+		Short: "manage the SSH host keys recorded for the nodes of the cluster",
+		Long: `Manage the SSH host keys recorded for the nodes of the cluster.
+
+The host key of each node is recorded in the known_hosts file of the generated
+assets directory the first time kismatic connects to it. Connections to a node
+that presents a different key fail, including the connections made by ansible,
+which only trusts the keys recorded in this file.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+	addPlanFileFlag(cmd.PersistentFlags(), &planFile)
+	cmd.AddCommand(NewCmdHostKeysRotate(out, &planFile))
+	return cmd
+}
+
+// NewCmdHostKeysRotate returns the command for replacing the recorded host keys of nodes
+func NewCmdHostKeysRotate(out io.Writer, planFile *string) *cobra.Command {
+	var generatedAssetsDir string
+	cmd := &cobra.Command{
+		Use:   "rotate [HOST...]",
+		Short: "replace the recorded SSH host keys of nodes that were rebuilt",
+		Long: `Replace the recorded SSH host keys of nodes that were rebuilt.
+
+The recorded keys of the given nodes are removed, and the new keys are recorded
+by connecting to the nodes. The keys of all the nodes in the plan are replaced
+when no HOST is given. Verify the fingerprints of the new keys before using
+the nodes.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c := &hostKeysRotateCmd{
+				out:        out,
+				planner:    &install.FilePlanner{File: *planFile},
+				knownHosts: &ssh.KnownHosts{File: knownHostsFile(cmd)},
+				hosts:      args,
+				connect: func(ip string, sshConfig install.SSHConfig) error {
+					return ssh.TestConnection(ip, sshConfig.Port, sshConfig.User, sshConfig.Key)
+				},
+			}
+			return c.run()
+		},
+	}
+	cmd.Flags().StringVar(&generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process are stored")
+	return cmd
+}
+
+func (c *hostKeysRotateCmd) run() error {
+	if !c.planner.PlanExists() {
+		return errors.New("host-keys rotate can only be used with an existing plan file")
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	ips := plan.GetUniqueNodeIPs()
+	if len(c.hosts) > 0 {
+		ips = []string{}
+		for _, h := range c.hosts {
+			con, err := plan.GetSSHConnection(h)
+			if err != nil {
+				return err
+			}
+			ips = append(ips, con.Node.IP)
+		}
+	}
+	util.PrintHeader(c.out, "Rotating SSH Host Keys", '=')
+	var failed []string
+	for _, ip := range ips {
+		addr := net.JoinHostPort(ip, strconv.Itoa(plan.Cluster.SSH.Port))
+		if err := c.knownHosts.Remove(addr); err != nil {
+			return err
+		}
+		if err := c.connect(ip, plan.Cluster.SSH); err != nil {
+			util.PrettyPrintErr(c.out, "Connecting to %s: %v", ip, err)
+			failed = append(failed, ip)
+			continue
+		}
+		fingerprints, err := c.knownHosts.Fingerprints(addr)
+		if err != nil {
+			return err
+		}
+		util.PrettyPrintOk(c.out, "Recorded host key of %s %s", ip, strings.Join(fingerprints, ", "))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("the host keys of %s could not be recorded", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/ssh"
+)
+
+func hostKeysTestPlan() *install.Plan {
+	return &install.Plan{
+		Cluster: install.Cluster{SSH: install.SSHConfig{Port: 22, User: "kismaticuser", Key: "key"}},
+		Etcd:    install.NodeGroup{Nodes: []install.Node{{Host: "etcd", IP: "10.0.0.1"}}},
+		Master:  install.MasterNodeGroup{Nodes: []install.Node{{Host: "master", IP: "10.0.0.2"}}},
+		Worker:  install.NodeGroup{Nodes: []install.Node{{Host: "worker", IP: "10.0.0.3"}}},
+	}
+}
+
+func mustWriteKnownHosts(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "host-keys-tests")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	file := filepath.Join(dir, "known_hosts")
+	data := "10.0.0.1 ssh-rsa etcdkey\n10.0.0.2 ssh-rsa masterkey\n10.0.0.3 ssh-rsa workerkey\n"
+	if err := ioutil.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatalf("error writing known hosts file: %v", err)
+	}
+	return file
+}
+
+func TestHostKeysRotateGivenHosts(t *testing.T) {
+	file := mustWriteKnownHosts(t)
+	defer os.RemoveAll(filepath.Dir(file))
+	var connected []string
+	c := &hostKeysRotateCmd{
+		out:        &bytes.Buffer{},
+		planner:    &fakePlanner{exists: true, plan: hostKeysTestPlan()},
+		knownHosts: &ssh.KnownHosts{File: file},
+		hosts:      []string{"master"},
+		connect: func(ip string, sshConfig install.SSHConfig) error {
+			connected = append(connected, ip)
+			return nil
+		},
+	}
+	if err := c.run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(connected) != 1 || connected[0] != "10.0.0.2" {
+		t.Errorf("expected to connect to the master node, connected to %v", connected)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("error reading known hosts file: %v", err)
+	}
+	if strings.Contains(string(data), "masterkey") {
+		t.Errorf("the key of the master node was not removed")
+	}
+	if !strings.Contains(string(data), "etcdkey") || !strings.Contains(string(data), "workerkey") {
+		t.Errorf("the keys of other nodes were removed")
+	}
+}
+
+func TestHostKeysRotateAllNodes(t *testing.T) {
+	file := mustWriteKnownHosts(t)
+	defer os.RemoveAll(filepath.Dir(file))
+	var connected []string
+	c := &hostKeysRotateCmd{
+		out:        &bytes.Buffer{},
+		planner:    &fakePlanner{exists: true, plan: hostKeysTestPlan()},
+		knownHosts: &ssh.KnownHosts{File: file},
+		connect: func(ip string, sshConfig install.SSHConfig) error {
+			connected = append(connected, ip)
+			return nil
+		},
+	}
+	if err := c.run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(connected) != 3 {
+		t.Errorf("expected to connect to 3 nodes, connected to %v", connected)
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("error reading known hosts file: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "" {
+		t.Errorf("expected all keys to be removed, got %q", string(data))
+	}
+}
+
+func TestHostKeysRotateUnknownHost(t *testing.T) {
+	c := &hostKeysRotateCmd{
+		out:        &bytes.Buffer{},
+		planner:    &fakePlanner{exists: true, plan: hostKeysTestPlan()},
+		knownHosts: &ssh.KnownHosts{File: "known_hosts"},
+		hosts:      []string{"foo"},
+		connect: func(ip string, sshConfig install.SSHConfig) error {
+			t.Errorf("unexpected connection to %s", ip)
+			return nil
+		},
+	}
+	if err := c.run(); err == nil {
+		t.Errorf("expected an error for a host that is not in the plan")
+	}
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"io"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -28,6 +29,9 @@ func NewCmdInstall(in io.Reader, out io.Writer) *cobra.Command {
 	cmd.AddCommand(NewCmdValidate(out, opts))
 	cmd.AddCommand(NewCmdApply(out, opts))
 	cmd.AddCommand(NewCmdAddWorker(out, opts))
+	cmd.AddCommand(NewCmdAddMaster(out, opts))
+	cmd.AddCommand(NewCmdAddEtcd(out, opts))
+	cmd.AddCommand(NewCmdRemoveNode(out, opts))
 	cmd.AddCommand(NewCmdStep(out, opts))
 
 	// PersistentFlags
@@ -39,3 +43,13 @@ func NewCmdInstall(in io.Reader, out io.Writer) *cobra.Command {
 func addPlanFileFlag(flagSet *pflag.FlagSet, p *string) {
 	flagSet.StringVarP(p, "plan-file", "f", "kismatic-cluster.yaml", "path to the installation plan file")
 }
+
+// messagesOut returns where the messages of a command that runs playbooks
+// are written. In json mode, stdout only contains the events of the
+// playbooks, so the messages are written to stderr.
+func messagesOut(out io.Writer, outputFormat string) io.Writer {
+	if outputFormat == "json" {
+		return os.Stderr
+	}
+	return out
+}
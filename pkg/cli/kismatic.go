@@ -3,6 +3,7 @@ package cli
 import (
 	"io"
 
+	"github.com/apprenda/kismatic/pkg/ssh"
 	"github.com/spf13/cobra"
 )
 
@@ -16,6 +17,10 @@ more documentation is availble at https://github.com/apprenda/kismatic`,
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
 		},
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// Host keys of the nodes are recorded next to the generated assets
+			ssh.UseKnownHostsFile(knownHostsFile(cmd))
+		},
 		SilenceUsage:  true,
 		SilenceErrors: true,
 	}
@@ -26,6 +31,14 @@ more documentation is availble at https://github.com/apprenda/kismatic`,
 	cmd.AddCommand(NewCmdIP(out))
 	cmd.AddCommand(NewCmdDashboard(out))
 	cmd.AddCommand(NewCmdSSH(out))
+	cmd.AddCommand(NewCmdUpgrade(out))
+	cmd.AddCommand(NewCmdBackup(out))
+	cmd.AddCommand(NewCmdCertificates(out))
+	cmd.AddCommand(NewCmdHostKeys(out))
+	cmd.AddCommand(NewCmdAddOn(out))
+	cmd.AddCommand(NewCmdKubeconfig(out))
+	cmd.AddCommand(NewCmdStatus(out))
+	cmd.AddCommand(NewCmdDiagnose(out))
 
 	return cmd, nil
 }
@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type kubeconfigOpts struct {
+	generatedAssetsDir string
+	expiry             time.Duration
+	outputFile         string
+	merge              bool
+	kubeconfig         string
+}
+
+type kubeconfigCmd struct {
+	out        io.Writer
+	planner    install.Planner
+	user       string
+	outputFile string
+	merge      bool
+	kubeconfig string
+	// generate returns the kubeconfig of the user
+	generate func(p *install.Plan, user string) ([]byte, error)
+}
+
+// NewCmdKubeconfig returns the command for generating the kubeconfig of a user
+func NewCmdKubeconfig(out io.Writer) *cobra.Command {
+	var planFile string
+	opts := kubeconfigOpts{}
+	cmd := &cobra.Command{
+		Use:   "kubeconfig USER",
+		Short: "generate a kubeconfig file for a user of the cluster",
+		Long: `Generate a kubeconfig file for the admin user or a user declared in the plan file.
+
+The kubeconfig points at the load balanced FQDN of the master nodes and embeds
+the cluster CA and the certificate of the user. The certificate is generated
+with the cluster CA when it does not exist or has expired.
+
+When --expiry is set, a new certificate that is valid for that long is issued
+for the kubeconfig, and the certificate of the user in the generated assets
+directory is left as is.
+
+When --merge is set, the cluster, user and context are added to the kubeconfig
+file given by --kubeconfig, replacing the entries with the same name, and the
+context is made the current context.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			user := args[0]
+			if opts.expiry < 0 {
+				return fmt.Errorf("expiry cannot be negative")
+			}
+			outputFile := opts.outputFile
+			if outputFile == "" {
+				outputFile = filepath.Join(opts.generatedAssetsDir, "kubeconfig-"+user)
+				if user == "admin" {
+					outputFile = filepath.Join(opts.generatedAssetsDir, "kubeconfig")
+				}
+			}
+			kubeconfig := opts.kubeconfig
+			if kubeconfig == "" {
+				kubeconfig = filepath.Join(os.Getenv("HOME"), ".kube", "config")
+			}
+			pki := &install.LocalPKI{
+				CACsr:                   filepath.Join("ansible", "playbooks", "tls", "ca-csr.json"),
+				CAConfigFile:            filepath.Join("ansible", "playbooks", "tls", "ca-config.json"),
+				CASigningProfile:        "kubernetes",
+				GeneratedCertsDirectory: filepath.Join(opts.generatedAssetsDir, "keys"),
+				Log:                     out,
+			}
+			c := &kubeconfigCmd{
+				out:        out,
+				planner:    &install.FilePlanner{File: planFile},
+				user:       user,
+				outputFile: outputFile,
+				merge:      opts.merge,
+				kubeconfig: kubeconfig,
+				generate: func(p *install.Plan, user string) ([]byte, error) {
+					return install.GenerateUserKubeconfig(p, pki, user, opts.expiry)
+				},
+			}
+			return c.run()
+		},
+	}
+	addPlanFileFlag(cmd.Flags(), &planFile)
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process are stored")
+	cmd.Flags().DurationVar(&opts.expiry, "expiry", 0, "issue a new certificate for the kubeconfig that is valid for this long (e.g. 8h). The certificate of the user is used when not set")
+	cmd.Flags().StringVarP(&opts.outputFile, "output-file", "o", "", "path to the kubeconfig file to write (default \"<generated-assets-dir>/kubeconfig-<user>\")")
+	cmd.Flags().BoolVar(&opts.merge, "merge", false, "merge the cluster, user and context into the kubeconfig file given by --kubeconfig instead of writing a new file")
+	cmd.Flags().StringVar(&opts.kubeconfig, "kubeconfig", "", "path to the kubeconfig file to merge into (default \"$HOME/.kube/config\")")
+	return cmd
+}
+
+func (c *kubeconfigCmd) run() error {
+	if !c.planner.PlanExists() {
+		return errors.New("kubeconfig can only be used with an existing plan file")
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	kubeconfig, err := c.generate(plan, c.user)
+	if err != nil {
+		return err
+	}
+	if !c.merge {
+		if err = ioutil.WriteFile(c.outputFile, kubeconfig, 0600); err != nil {
+			return fmt.Errorf("error writing kubeconfig file: %v", err)
+		}
+		util.PrettyPrintOk(c.out, "Generated kubeconfig for user %q at %q", c.user, c.outputFile)
+		return nil
+	}
+
+	existing, err := ioutil.ReadFile(c.kubeconfig)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error reading kubeconfig file %q: %v", c.kubeconfig, err)
+	}
+	merged, err := install.MergeKubeconfig(existing, kubeconfig)
+	if err != nil {
+		return fmt.Errorf("error merging into kubeconfig file %q: %v", c.kubeconfig, err)
+	}
+	if err = os.MkdirAll(filepath.Dir(c.kubeconfig), 0700); err != nil {
+		return fmt.Errorf("error creating directory for kubeconfig file: %v", err)
+	}
+	if err = ioutil.WriteFile(c.kubeconfig, merged, 0600); err != nil {
+		return fmt.Errorf("error writing kubeconfig file: %v", err)
+	}
+	util.PrettyPrintOk(c.out, "Merged kubeconfig for user %q into %q, the current context is %q", c.user, c.kubeconfig, plan.Cluster.Name+"-"+c.user)
+	return nil
+}
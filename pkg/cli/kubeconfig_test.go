@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+const testKubeconfig = `apiVersion: v1
+clusters:
+- cluster:
+    server: https://someFQDN:6443
+  name: someName
+contexts:
+- context:
+    cluster: someName
+    user: someName-jane
+  name: someName-jane
+current-context: someName-jane
+kind: Config
+preferences: {}
+users:
+- name: someName-jane
+  user:
+    client-certificate-data: somecert
+`
+
+func kubeconfigTestCmd(t *testing.T) (*kubeconfigCmd, string) {
+	dir, err := ioutil.TempDir("", "kubeconfig-cmd-tests")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	c := &kubeconfigCmd{
+		out:        &bytes.Buffer{},
+		planner:    &fakePlanner{exists: true, plan: &install.Plan{Cluster: install.Cluster{Name: "someName"}}},
+		user:       "jane",
+		outputFile: filepath.Join(dir, "kubeconfig-jane"),
+		kubeconfig: filepath.Join(dir, ".kube", "config"),
+		generate: func(p *install.Plan, user string) ([]byte, error) {
+			return []byte(testKubeconfig), nil
+		},
+	}
+	return c, dir
+}
+
+func TestKubeconfigCmdWritesFile(t *testing.T) {
+	c, dir := kubeconfigTestCmd(t)
+	defer os.RemoveAll(dir)
+	if err := c.run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadFile(c.outputFile)
+	if err != nil {
+		t.Fatalf("error reading kubeconfig file: %v", err)
+	}
+	if string(b) != testKubeconfig {
+		t.Errorf("unexpected kubeconfig file:\n%s", string(b))
+	}
+	// the kubeconfig has the key of the user, so only the owner can read it
+	info, err := os.Stat(c.outputFile)
+	if err != nil {
+		t.Fatalf("error reading kubeconfig file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the kubeconfig file to have mode 0600, got %v", info.Mode().Perm())
+	}
+	if _, err = os.Stat(c.kubeconfig); !os.IsNotExist(err) {
+		t.Errorf("expected the kubeconfig to not be merged")
+	}
+}
+
+func TestKubeconfigCmdMerge(t *testing.T) {
+	c, dir := kubeconfigTestCmd(t)
+	defer os.RemoveAll(dir)
+	c.merge = true
+	// the directory of the kubeconfig file is created
+	if err := c.run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	existing := strings.Replace(testKubeconfig, "jane", "bob", -1)
+	if err := ioutil.WriteFile(c.kubeconfig, []byte(existing), 0600); err != nil {
+		t.Fatalf("error writing kubeconfig file: %v", err)
+	}
+	if err := c.run(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadFile(c.kubeconfig)
+	if err != nil {
+		t.Fatalf("error reading kubeconfig file: %v", err)
+	}
+	merged := string(b)
+	if !strings.Contains(merged, "name: someName-bob") || !strings.Contains(merged, "name: someName-jane") {
+		t.Errorf("expected the contexts of both users in the merged kubeconfig:\n%s", merged)
+	}
+	if !strings.Contains(merged, "current-context: someName-jane") {
+		t.Errorf("expected the current context to be the merged context:\n%s", merged)
+	}
+	if _, err = os.Stat(c.outputFile); !os.IsNotExist(err) {
+		t.Errorf("expected the kubeconfig file to not be written when merging")
+	}
+}
+
+func TestKubeconfigCmdNoPlan(t *testing.T) {
+	c, dir := kubeconfigTestCmd(t)
+	defer os.RemoveAll(dir)
+	c.planner = &fakePlanner{exists: false}
+	if err := c.run(); err == nil {
+		t.Errorf("expected an error when the plan file does not exist")
+	}
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type removeNodeOpts struct {
+	GeneratedAssetsDirectory string
+	OutputFormat             string
+	Verbose                  bool
+}
+
+// NewCmdRemoveNode returns the command for removing nodes from the cluster
+func NewCmdRemoveNode(out io.Writer, installOpts *installOpts) *cobra.Command {
+	opts := &removeNodeOpts{}
+	cmd := &cobra.Command{
+		Use:   "remove-node NODE_NAME",
+		Short: "remove a Worker, Ingress or Storage node from an existing Kubernetes cluster",
+		Long: `Remove a Worker, Ingress or Storage node from an existing Kubernetes cluster.
+
+The node is cordoned and drained, deleted from Kubernetes and the cluster
+services running on it are stopped. The plan file is updated to reflect
+the removal.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return cmd.Usage()
+			}
+			return doRemoveNode(out, installOpts.planFilename, opts, args[0])
+		},
+	}
+	cmd.Flags().StringVar(&opts.GeneratedAssetsDirectory, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
+	cmd.Flags().BoolVar(&opts.Verbose, "verbose", false, "enable verbose logging from the installation")
+	cmd.Flags().StringVarP(&opts.OutputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
+	return cmd
+}
+
+func doRemoveNode(stdout io.Writer, planFile string, opts *removeNodeOpts, host string) error {
+	out := messagesOut(stdout, opts.OutputFormat)
+	planner := &install.FilePlanner{File: planFile}
+	if !planner.PlanExists() {
+		return errors.New("remove-node can only be used with an existing plan file")
+	}
+	execOpts := install.ExecutorOptions{
+		GeneratedAssetsDirectory: opts.GeneratedAssetsDirectory,
+		OutputFormat:             opts.OutputFormat,
+		Verbose:                  opts.Verbose,
+		SkipCAGeneration:         true,
+	}
+	executor, err := install.NewExecutor(stdout, os.Stderr, execOpts)
+	if err != nil {
+		return err
+	}
+	plan, err := planner.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %v", err)
+	}
+	if _, errs := install.ValidatePlan(plan); errs != nil {
+		util.PrintValidationErrors(out, errs)
+		return errors.New("the plan file failed validation")
+	}
+	updatedPlan, err := executor.RemoveNode(plan, host)
+	if err != nil {
+		return err
+	}
+	if err := planner.Write(updatedPlan); err != nil {
+		return fmt.Errorf("error updating plan file to remove node: %v", err)
+	}
+	util.PrettyPrintOk(out, "Removed node %q from the cluster", host)
+	return nil
+}
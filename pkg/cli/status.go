@@ -0,0 +1,433 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/apprenda/kismatic/pkg/data"
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type statusCmd struct {
+	out          io.Writer
+	planner      install.Planner
+	outputFormat string
+	// kubernetesClient returns the client used to query the API server
+	kubernetesClient func(p *install.Plan) (data.KubernetesClient, error)
+	// nodeClient returns the client used to check the services of a node
+	nodeClient func(p *install.Plan, node install.Node) (data.NodeClient, error)
+}
+
+// the roles of the nodes that run the kubelet and calico
+var kubeletRoles = []string{"master", "worker", "ingress", "storage"}
+
+// NewCmdStatus returns the command for reporting the health of the cluster
+func NewCmdStatus(out io.Writer) *cobra.Command {
+	var planFile string
+	c := &statusCmd{
+		out:              out,
+		kubernetesClient: remoteKubectl,
+		nodeClient:       remoteNode,
+	}
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "show the health of the cluster",
+		Long: `Show the health of the cluster.
+
+The nodes of the plan file are checked over SSH, and the API server is queried
+from the first master node. The following is reported:
+- the health of the etcd members, on each etcd node
+- the component statuses reported by the API server
+- the readiness of the nodes, and the role of each node in the plan file
+- the status of calico on each node
+- the docker registry, when one is configured
+- the pods of the add-ons that are enabled in the plan file
+
+The command fails when any of the checks fails.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			c.planner = &install.FilePlanner{File: planFile}
+			return c.run()
+		},
+	}
+	addPlanFileFlag(cmd.Flags(), &planFile)
+	cmd.Flags().StringVarP(&c.outputFormat, "output", "o", "simple", `output format (options "simple"|"json")`)
+	return cmd
+}
+
+func remoteKubectl(p *install.Plan) (data.KubernetesClient, error) {
+	client, err := p.GetSSHClient("master")
+	if err != nil {
+		return nil, err
+	}
+	return data.RemoteKubectl{SSHClient: client}, nil
+}
+
+func remoteNode(p *install.Plan, node install.Node) (data.NodeClient, error) {
+	client, err := p.GetSSHClient(node.Host)
+	if err != nil {
+		return nil, err
+	}
+	return data.RemoteNode{SSHClient: client}, nil
+}
+
+func (c *statusCmd) run() error {
+	if c.outputFormat != "simple" && c.outputFormat != "json" {
+		return fmt.Errorf("output format %q is not supported", c.outputFormat)
+	}
+	if !c.planner.PlanExists() {
+		return errors.New("status can only be used with an existing plan file")
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+	resp := c.buildStatus(plan)
+	if err = printStatus(c.out, resp, c.outputFormat); err != nil {
+		return err
+	}
+	if !resp.Healthy {
+		return errors.New("the cluster is not healthy")
+	}
+	return nil
+}
+
+func (c *statusCmd) buildStatus(p *install.Plan) *StatusResponse {
+	resp := &StatusResponse{}
+	nodes := p.GetUniqueNodes()
+	resp.Nodes = make([]NodeStatus, len(nodes))
+	etcd := make([][]EtcdMemberStatus, len(nodes))
+	// the nodes are checked concurrently, each goroutine only writes to its own index
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		resp.Nodes[i] = NodeStatus{Host: n.Host, IP: n.IP, Roles: p.GetNodeRoles(n.Host)}
+		wg.Add(1)
+		go func(i int, n install.Node) {
+			defer wg.Done()
+			etcd[i] = c.checkNode(p, n, &resp.Nodes[i])
+		}(i, n)
+	}
+	wg.Wait()
+	for _, members := range etcd {
+		resp.Etcd = append(resp.Etcd, members...)
+	}
+	c.checkKubernetes(p, resp)
+	if p.DockerRegistryProvided() {
+		resp.Registry = c.checkRegistry(p)
+	}
+
+	// a node is healthy when all the services that run on it are healthy
+	resp.Healthy = len(resp.Errors) == 0
+	for i := range resp.Nodes {
+		n := &resp.Nodes[i]
+		n.Healthy = true
+		if runsKubelet(n.Roles) {
+			n.Healthy = n.Ready == string(data.ConditionTrue) && n.CalicoRunning
+		}
+		for _, m := range resp.Etcd {
+			if m.Host == n.Host && !m.Healthy {
+				n.Healthy = false
+			}
+		}
+		resp.Healthy = resp.Healthy && n.Healthy
+	}
+	for _, cs := range resp.Components {
+		resp.Healthy = resp.Healthy && cs.Healthy
+	}
+	if resp.Registry != nil {
+		resp.Healthy = resp.Healthy && resp.Registry.Healthy
+	}
+	for _, a := range resp.AddOns {
+		resp.Healthy = resp.Healthy && a.Healthy
+	}
+	return resp
+}
+
+// checks the etcd members and calico on the node, and returns the status of the etcd members
+func (c *statusCmd) checkNode(p *install.Plan, n install.Node, status *NodeStatus) []EtcdMemberStatus {
+	members := []EtcdMemberStatus{}
+	client, err := c.nodeClient(p, n)
+	if err != nil {
+		err = fmt.Errorf("error creating SSH client: %v", err)
+	}
+	if hasAnyRole(status.Roles, "etcd") {
+		for _, c := range p.EtcdClusters() {
+			cluster := data.EtcdCluster{Name: c.Name, Port: c.ClientPort, CertsDir: c.CertsDirectory}
+			m := EtcdMemberStatus{Cluster: cluster.Name, Host: n.Host}
+			if err != nil {
+				m.Message = err.Error()
+			} else {
+				m.Healthy, m.Message = etcdMemberHealth(client, cluster, n)
+			}
+			members = append(members, m)
+		}
+	}
+	if runsKubelet(status.Roles) {
+		if err != nil {
+			status.Calico = err.Error()
+		} else {
+			status.CalicoRunning, status.Calico = calicoStatus(client)
+		}
+	}
+	return members
+}
+
+// returns the health of the member of the etcd cluster that runs on the node
+func etcdMemberHealth(client data.NodeClient, cluster data.EtcdCluster, n install.Node) (bool, string) {
+	health, err := client.EtcdClusterHealth(cluster)
+	if err != nil {
+		return false, err.Error()
+	}
+	for _, m := range health.Members {
+		for _, ip := range []string{n.IP, n.InternalIP} {
+			if ip != "" && strings.Contains(m.ClientURL, fmt.Sprintf("//%s:%d", ip, cluster.Port)) {
+				return m.Healthy, m.Message
+			}
+		}
+	}
+	return false, "member of the node not found in the cluster"
+}
+
+func calicoStatus(client data.NodeClient) (bool, string) {
+	status, err := client.CalicoNodeStatus()
+	if err != nil {
+		return false, err.Error()
+	}
+	if !status.Running {
+		return false, "not running"
+	}
+	established := 0
+	for _, peer := range status.Peers {
+		if peer.Info == "Established" {
+			established++
+		}
+	}
+	return established == len(status.Peers), fmt.Sprintf("running, %d/%d peers established", established, len(status.Peers))
+}
+
+// checks the component statuses, the readiness of the nodes and the pods of the add-ons
+func (c *statusCmd) checkKubernetes(p *install.Plan, resp *StatusResponse) {
+	for i := range resp.Nodes {
+		if runsKubelet(resp.Nodes[i].Roles) {
+			resp.Nodes[i].Ready = string(data.ConditionUnknown)
+		}
+	}
+	client, err := c.kubernetesClient(p)
+	if err != nil {
+		resp.Errors = append(resp.Errors, fmt.Sprintf("error creating SSH client for master node: %v", err))
+		return
+	}
+
+	cs, err := client.ListComponentStatuses()
+	if err != nil {
+		resp.Errors = append(resp.Errors, err.Error())
+	} else if cs != nil {
+		for _, item := range cs.Items {
+			s := ComponentStatus{Name: item.Name}
+			for _, cond := range item.Conditions {
+				if cond.Type == "Healthy" {
+					s.Healthy = cond.Status == data.ConditionTrue
+					s.Message = cond.Message
+					if cond.Error != "" {
+						s.Message = cond.Error
+					}
+				}
+			}
+			resp.Components = append(resp.Components, s)
+		}
+	}
+
+	nodes, err := client.ListNodes()
+	if err != nil {
+		resp.Errors = append(resp.Errors, err.Error())
+	} else {
+		ready := map[string]string{}
+		if nodes != nil {
+			for _, n := range nodes.Items {
+				ready[n.Name] = string(data.ConditionUnknown)
+				for _, cond := range n.Status.Conditions {
+					if cond.Type == data.NodeReady {
+						ready[n.Name] = string(cond.Status)
+					}
+				}
+			}
+		}
+		for i := range resp.Nodes {
+			if !runsKubelet(resp.Nodes[i].Roles) {
+				continue
+			}
+			r, ok := ready[resp.Nodes[i].Host]
+			if !ok {
+				r = "NotRegistered"
+			}
+			resp.Nodes[i].Ready = r
+		}
+	}
+
+	pods, err := client.ListPods()
+	if err != nil {
+		resp.Errors = append(resp.Errors, err.Error())
+		return
+	}
+	for _, a := range install.AddOns() {
+		if !a.Enabled(p) || len(a.PodLabels) == 0 {
+			continue
+		}
+		s := AddOnPodsStatus{Name: a.Name, Pods: []PodStatus{}}
+		if pods != nil {
+			for _, pod := range pods.Items {
+				if hasAnyLabel(pod, a.PodLabels) {
+					s.Pods = append(s.Pods, podStatus(pod))
+				}
+			}
+		}
+		s.Healthy = len(s.Pods) > 0
+		for _, pod := range s.Pods {
+			s.Healthy = s.Healthy && pod.Healthy
+		}
+		resp.AddOns = append(resp.AddOns, s)
+	}
+}
+
+// returns true if the pod has any of the labels, given as "key=value"
+func hasAnyLabel(pod data.Pod, labels []string) bool {
+	for _, l := range labels {
+		kv := strings.SplitN(l, "=", 2)
+		if len(kv) == 2 && pod.Labels[kv[0]] == kv[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func podStatus(pod data.Pod) PodStatus {
+	s := PodStatus{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		Node:      pod.Spec.NodeName,
+		Phase:     string(pod.Status.Phase),
+	}
+	ready := 0
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Ready {
+			ready++
+		}
+		s.Restarts += cs.RestartCount
+	}
+	s.Ready = fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers))
+	s.Healthy = s.Phase == "Running" && ready == len(pod.Spec.Containers)
+	return s
+}
+
+// checks that the docker registry responds, from the first master node
+func (c *statusCmd) checkRegistry(p *install.Plan) *RegistryStatus {
+	master := p.Master.Nodes[0]
+	address := p.DockerRegistryAddress()
+	s := &RegistryStatus{Address: address}
+	client, err := c.nodeClient(p, master)
+	if err != nil {
+		s.Message = fmt.Sprintf("error creating SSH client: %v", err)
+		return s
+	}
+	code, err := client.HTTPStatusCode("https://" + address + "/v2/")
+	if err != nil {
+		s.Message = err.Error()
+		return s
+	}
+	// the registry responds with 401 when it requires authentication
+	s.Healthy = code == 200 || code == 401
+	s.Message = fmt.Sprintf("responded with status %d", code)
+	return s
+}
+
+func runsKubelet(roles []string) bool {
+	return hasAnyRole(roles, kubeletRoles...)
+}
+
+func hasAnyRole(roles []string, want ...string) bool {
+	for _, r := range roles {
+		for _, w := range want {
+			if r == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func printStatus(out io.Writer, resp *StatusResponse, format string) error {
+	if format == "json" {
+		b, err := json.MarshalIndent(resp, "", "    ")
+		if err != nil {
+			return fmt.Errorf("marshal error: %v", err)
+		}
+		fmt.Fprintln(out, string(b))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "HOST\tIP\tROLES\tREADY\tCALICO\tHEALTHY\n")
+	for _, n := range resp.Nodes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%t\n", n.Host, n.IP, strings.Join(n.Roles, ","), dashIfEmpty(n.Ready), dashIfEmpty(n.Calico), n.Healthy)
+	}
+	w.Flush()
+
+	if len(resp.Etcd) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintf(w, "ETCD CLUSTER\tHOST\tHEALTHY\tMESSAGE\n")
+		for _, m := range resp.Etcd {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", m.Cluster, m.Host, m.Healthy, m.Message)
+		}
+		w.Flush()
+	}
+
+	if len(resp.Components) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintf(w, "COMPONENT\tHEALTHY\tMESSAGE\n")
+		for _, cs := range resp.Components {
+			fmt.Fprintf(w, "%s\t%t\t%s\n", cs.Name, cs.Healthy, dashIfEmpty(cs.Message))
+		}
+		w.Flush()
+	}
+
+	if resp.Registry != nil {
+		fmt.Fprintln(out)
+		fmt.Fprintf(w, "DOCKER REGISTRY\tHEALTHY\tMESSAGE\n")
+		fmt.Fprintf(w, "%s\t%t\t%s\n", resp.Registry.Address, resp.Registry.Healthy, resp.Registry.Message)
+		w.Flush()
+	}
+
+	if len(resp.AddOns) > 0 {
+		fmt.Fprintln(out)
+		fmt.Fprintf(w, "ADD-ON\tPOD\tNODE\tPHASE\tREADY\tRESTARTS\n")
+		for _, a := range resp.AddOns {
+			if len(a.Pods) == 0 {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", a.Name, "no pods found", "-", "-", "-", "-")
+			}
+			for _, pod := range a.Pods {
+				fmt.Fprintf(w, "%s\t%s/%s\t%s\t%s\t%s\t%d\n", a.Name, pod.Namespace, pod.Name, dashIfEmpty(pod.Node), pod.Phase, pod.Ready, pod.Restarts)
+			}
+		}
+		w.Flush()
+	}
+
+	fmt.Fprintln(out)
+	for _, e := range resp.Errors {
+		util.PrettyPrintErr(out, "%s", e)
+	}
+	if resp.Healthy {
+		util.PrettyPrintOk(out, "The cluster is healthy")
+	} else {
+		util.PrettyPrintErr(out, "The cluster is not healthy")
+	}
+	return nil
+}
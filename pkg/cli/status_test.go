@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/data"
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+type fakeNodeClient struct {
+	host       string
+	etcdHealth map[string]*data.EtcdClusterHealth
+	calico     *data.CalicoNodeStatus
+	calicoErr  error
+	httpCode   int
+}
+
+func (c fakeNodeClient) EtcdClusterHealth(cluster data.EtcdCluster) (*data.EtcdClusterHealth, error) {
+	h, ok := c.etcdHealth[cluster.Name]
+	if !ok {
+		return nil, fmt.Errorf("etcd not running on %s", c.host)
+	}
+	return h, nil
+}
+
+func (c fakeNodeClient) CalicoNodeStatus() (*data.CalicoNodeStatus, error) {
+	return c.calico, c.calicoErr
+}
+
+func (c fakeNodeClient) HTTPStatusCode(url string) (int, error) {
+	return c.httpCode, nil
+}
+
+const statusTestNodes = `{"items": [
+	{"metadata": {"name": "master"}, "status": {"conditions": [{"type": "Ready", "status": "True"}]}},
+	{"metadata": {"name": "worker"}, "status": {"conditions": [{"type": "OutOfDisk", "status": "False"}, {"type": "Ready", "status": "True"}]}}
+]}`
+
+const statusTestComponents = `{"items": [
+	{"metadata": {"name": "scheduler"}, "conditions": [{"type": "Healthy", "status": "True", "message": "ok"}]},
+	{"metadata": {"name": "etcd-0"}, "conditions": [{"type": "Healthy", "status": "True", "message": "{\"health\": \"true\"}"}]}
+]}`
+
+const statusTestPods = `{"items": [
+	{"metadata": {"name": "kube-dns-1", "namespace": "kube-system", "labels": {"k8s-app": "kube-dns"}},
+	 "spec": {"nodeName": "worker", "containers": [{"name": "kubedns"}, {"name": "dnsmasq"}]},
+	 "status": {"phase": "Running", "containerStatuses": [{"name": "kubedns", "ready": true, "restartCount": 1}, {"name": "dnsmasq", "ready": true}]}},
+	{"metadata": {"name": "kubernetes-dashboard-1", "namespace": "kube-system", "labels": {"app": "kubernetes-dashboard"}},
+	 "spec": {"nodeName": "worker", "containers": [{"name": "dashboard"}]},
+	 "status": {"phase": "Running", "containerStatuses": [{"name": "dashboard", "ready": true}]}},
+	{"metadata": {"name": "nginx", "namespace": "default", "labels": {"app": "nginx"}},
+	 "spec": {"nodeName": "worker", "containers": [{"name": "nginx"}]},
+	 "status": {"phase": "Pending"}}
+]}`
+
+func statusTestPlan() *install.Plan {
+	return &install.Plan{
+		Cluster:        install.Cluster{SSH: install.SSHConfig{Port: 22, User: "kismaticuser", Key: "key"}},
+		DockerRegistry: install.DockerRegistry{SetupInternal: true},
+		Etcd:           install.NodeGroup{Nodes: []install.Node{{Host: "etcd", IP: "10.0.0.1"}}},
+		Master:         install.MasterNodeGroup{Nodes: []install.Node{{Host: "master", IP: "10.0.0.2"}}},
+		Worker:         install.NodeGroup{Nodes: []install.Node{{Host: "worker", IP: "10.0.0.3"}}},
+	}
+}
+
+func healthyEtcd(ip string) map[string]*data.EtcdClusterHealth {
+	return map[string]*data.EtcdClusterHealth{
+		"kubernetes": {Healthy: true, Members: []data.EtcdMemberHealth{{ClientURL: "https://" + ip + ":2379", Healthy: true, Message: "got healthy result from https://" + ip + ":2379"}}},
+		"networking": {Healthy: true, Members: []data.EtcdMemberHealth{{ClientURL: "https://" + ip + ":6666", Healthy: true, Message: "got healthy result from https://" + ip + ":6666"}}},
+	}
+}
+
+func statusTestCmd(nodeClients map[string]fakeNodeClient) *statusCmd {
+	return &statusCmd{
+		out:          &bytes.Buffer{},
+		planner:      &fakePlanner{exists: true, plan: statusTestPlan()},
+		outputFormat: "json",
+		kubernetesClient: func(p *install.Plan) (data.KubernetesClient, error) {
+			return fakeKubernetesGetter{
+				podList:  []byte(statusTestPods),
+				nodeList: []byte(statusTestNodes),
+				csList:   []byte(statusTestComponents),
+			}, nil
+		},
+		nodeClient: func(p *install.Plan, node install.Node) (data.NodeClient, error) {
+			return nodeClients[node.Host], nil
+		},
+	}
+}
+
+func healthyNodeClients() map[string]fakeNodeClient {
+	running := &data.CalicoNodeStatus{Running: true, Peers: []data.CalicoPeer{{Address: "10.0.0.3", Info: "Established"}}}
+	return map[string]fakeNodeClient{
+		"etcd":   {host: "etcd", etcdHealth: healthyEtcd("10.0.0.1")},
+		"master": {host: "master", calico: running, httpCode: 200},
+		"worker": {host: "worker", calico: running},
+	}
+}
+
+func TestStatusCmdHealthy(t *testing.T) {
+	c := statusTestCmd(healthyNodeClients())
+	if err := c.run(); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, c.out)
+	}
+	var resp StatusResponse
+	if err := json.Unmarshal(c.out.(*bytes.Buffer).Bytes(), &resp); err != nil {
+		t.Fatalf("error reading JSON output: %v", err)
+	}
+	if !resp.Healthy {
+		t.Errorf("expected the cluster to be healthy: %+v", resp)
+	}
+	if len(resp.Nodes) != 3 || resp.Nodes[0].Roles[0] != "etcd" || resp.Nodes[2].Ready != "True" {
+		t.Errorf("unexpected nodes: %+v", resp.Nodes)
+	}
+	// etcd only nodes do not run the kubelet
+	if resp.Nodes[0].Ready != "" || resp.Nodes[0].Calico != "" {
+		t.Errorf("expected the kubelet and calico to not be checked on etcd nodes: %+v", resp.Nodes[0])
+	}
+	if len(resp.Etcd) != 2 || len(resp.Components) != 2 {
+		t.Errorf("expected 2 etcd members and 2 components, got %+v and %+v", resp.Etcd, resp.Components)
+	}
+	if resp.Registry == nil || resp.Registry.Address != "10.0.0.2:8443" {
+		t.Errorf("expected the internal registry on the first master to be checked, got %+v", resp.Registry)
+	}
+	// the pods that do not belong to the add-ons are not reported
+	if len(resp.AddOns) != 2 || resp.AddOns[0].Name != "dns" || len(resp.AddOns[0].Pods) != 1 {
+		t.Fatalf("unexpected add-ons: %+v", resp.AddOns)
+	}
+	if pod := resp.AddOns[0].Pods[0]; pod.Ready != "2/2" || pod.Restarts != 1 || pod.Node != "worker" {
+		t.Errorf("unexpected add-on pod: %+v", pod)
+	}
+}
+
+func TestStatusCmdUnhealthy(t *testing.T) {
+	nodeClients := healthyNodeClients()
+	worker := nodeClients["worker"]
+	worker.calico = &data.CalicoNodeStatus{Running: false}
+	nodeClients["worker"] = worker
+	etcd := nodeClients["etcd"]
+	etcd.etcdHealth = map[string]*data.EtcdClusterHealth{
+		"kubernetes": {Members: []data.EtcdMemberHealth{{ClientURL: "https://10.0.0.1:2379", Message: "got unhealthy result from https://10.0.0.1:2379"}}},
+	}
+	nodeClients["etcd"] = etcd
+
+	c := statusTestCmd(nodeClients)
+	c.outputFormat = "simple"
+	if err := c.run(); err == nil {
+		t.Fatalf("expected an error when the cluster is not healthy")
+	}
+	out := c.out.(*bytes.Buffer).String()
+	for _, s := range []string{"not running", "got unhealthy result", "etcd not running on etcd", "The cluster is not healthy"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected output to contain %q:\n%s", s, out)
+		}
+	}
+}
+
+func TestStatusCmdUnsupportedOutput(t *testing.T) {
+	c := statusTestCmd(healthyNodeClients())
+	c.outputFormat = "yaml"
+	if err := c.run(); err == nil {
+		t.Errorf("expected an error with an unsupported output format")
+	}
+}
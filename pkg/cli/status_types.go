@@ -0,0 +1,68 @@
+package cli
+
+// StatusResponse is the health of the cluster
+type StatusResponse struct {
+	Healthy    bool               `json:"healthy"`
+	Nodes      []NodeStatus       `json:"nodes"`
+	Etcd       []EtcdMemberStatus `json:"etcd"`
+	Components []ComponentStatus  `json:"components"`
+	Registry   *RegistryStatus    `json:"registry,omitempty"`
+	AddOns     []AddOnPodsStatus  `json:"addOns"`
+	// Errors are the checks that could not be run
+	Errors []string `json:"errors,omitempty"`
+}
+
+// NodeStatus is the health of a node of the plan
+type NodeStatus struct {
+	Host  string   `json:"host"`
+	IP    string   `json:"ip"`
+	Roles []string `json:"roles"`
+	// Ready is the Ready condition of the node in Kubernetes. It is empty
+	// for nodes that do not run the kubelet.
+	Ready string `json:"ready,omitempty"`
+	// Calico is the status of calico on the node. It is empty for nodes
+	// that do not run calico.
+	Calico        string `json:"calico,omitempty"`
+	CalicoRunning bool   `json:"calicoRunning"`
+	Healthy       bool   `json:"healthy"`
+}
+
+// EtcdMemberStatus is the health of the etcd member that runs on an etcd node
+type EtcdMemberStatus struct {
+	Cluster string `json:"cluster"`
+	Host    string `json:"host"`
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message"`
+}
+
+// ComponentStatus is the health of a component of the control plane, as seen by the API server
+type ComponentStatus struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message"`
+}
+
+// RegistryStatus is the health of the docker registry
+type RegistryStatus struct {
+	Address string `json:"address"`
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message"`
+}
+
+// AddOnPodsStatus is the health of the pods of an add-on
+type AddOnPodsStatus struct {
+	Name    string      `json:"name"`
+	Healthy bool        `json:"healthy"`
+	Pods    []PodStatus `json:"pods"`
+}
+
+// PodStatus is the status of a pod of an add-on
+type PodStatus struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Node      string `json:"node"`
+	Phase     string `json:"phase"`
+	Ready     string `json:"ready"`
+	Restarts  int32  `json:"restarts"`
+	Healthy   bool   `json:"healthy"`
+}
@@ -50,13 +50,14 @@ func NewCmdStep(out io.Writer, opts *installOpts) *cobra.Command {
 			stepCmd.planFile = opts.planFilename
 			stepCmd.planner = &install.FilePlanner{File: stepCmd.planFile}
 			stepCmd.executor = executor
+			stepCmd.out = messagesOut(out, stepCmd.outputFormat)
 			return stepCmd.run()
 		},
 	}
 	cmd.Flags().StringVar(&stepCmd.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
 	cmd.Flags().BoolVar(&stepCmd.restartServices, "restart-services", false, "force restart cluster services (Use with care)")
 	cmd.Flags().BoolVar(&stepCmd.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&stepCmd.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\")")
+	cmd.Flags().StringVarP(&stepCmd.outputFormat, "output", "o", "simple", "installation output format (options \"simple\"|\"raw\"|\"json\")")
 	return cmd
 }
 
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/apprenda/kismatic/pkg/install"
+	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/spf13/cobra"
+)
+
+type upgradeOpts struct {
+	planFile           string
+	generatedAssetsDir string
+	verbose            bool
+	outputFormat       string
+}
+
+type upgradeCmd struct {
+	out          io.Writer
+	planner      install.Planner
+	executor     install.Executor
+	listVersions func(*install.Plan) ([]install.NodeVersion, error)
+}
+
+// NewCmdUpgrade returns the command for upgrading the cluster in place
+func NewCmdUpgrade(out io.Writer) *cobra.Command {
+	opts := upgradeOpts{}
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "upgrade your Kubernetes cluster",
+		Long: `Upgrade your Kubernetes cluster to the version bundled with this installer.
+
+The etcd nodes are upgraded first, followed by the master nodes. Worker, ingress
+and storage nodes are then cordoned, drained and upgraded one at a time.
+
+Nodes that are already at the version of this installer are skipped. The
+upgrade fails if any node is at a newer version than this installer.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("Unexpected args: %v", args)
+			}
+			planner := &install.FilePlanner{File: opts.planFile}
+			executorOpts := install.ExecutorOptions{
+				GeneratedAssetsDirectory: opts.generatedAssetsDir,
+				OutputFormat:             opts.outputFormat,
+				Verbose:                  opts.verbose,
+			}
+			executor, err := install.NewExecutor(out, os.Stderr, executorOpts)
+			if err != nil {
+				return err
+			}
+			c := &upgradeCmd{
+				out:          messagesOut(out, opts.outputFormat),
+				planner:      planner,
+				executor:     executor,
+				listVersions: install.ListVersions,
+			}
+			return c.run()
+		},
+	}
+
+	addPlanFileFlag(cmd.Flags(), &opts.planFile)
+	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process are stored")
+	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the upgrade")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "upgrade output format (options \"simple\"|\"raw\"|\"json\")")
+
+	return cmd
+}
+
+func (c *upgradeCmd) run() error {
+	if !c.planner.PlanExists() {
+		return errors.New("upgrade can only be used with an existing plan file")
+	}
+	plan, err := c.planner.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read plan file: %v", err)
+	}
+	if _, errs := install.ValidatePlan(plan); errs != nil {
+		util.PrintValidationErrors(c.out, errs)
+		return errors.New("the plan file failed validation")
+	}
+
+	util.PrintHeader(c.out, "Reading Installed Versions", '=')
+	versions, err := c.listVersions(plan)
+	if err != nil {
+		return fmt.Errorf("error reading installed versions: %v", err)
+	}
+	printVersions(c.out, versions)
+
+	if err := c.executor.Upgrade(plan, versions); err != nil {
+		return fmt.Errorf("error upgrading cluster: %v", err)
+	}
+	util.PrintColor(c.out, util.Green, "\nThe cluster was upgraded successfully\n")
+	return nil
+}
+
+func printVersions(out io.Writer, versions []install.NodeVersion) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "NODE\tROLES\tVERSION\n")
+	for _, v := range versions {
+		version := v.Version
+		if version == "" {
+			version = "Unknown"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Node.Host, strings.Join(v.Roles, ","), version)
+	}
+	w.Flush()
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/install"
+)
+
+func TestUpgradeCmdInvalidPlan(t *testing.T) {
+	out := &bytes.Buffer{}
+	fp := &fakePlanner{
+		exists: true,
+		plan:   &install.Plan{},
+	}
+	fe := &fakeExecutor{}
+	listVersionsCalled := false
+	c := &upgradeCmd{
+		out:      out,
+		planner:  fp,
+		executor: fe,
+		listVersions: func(*install.Plan) ([]install.NodeVersion, error) {
+			listVersionsCalled = true
+			return nil, nil
+		},
+	}
+	if err := c.run(); err == nil {
+		t.Error("expected error due to invalid plan, but did not get one")
+	}
+	if listVersionsCalled {
+		t.Error("versions were read with an invalid plan")
+	}
+	if fe.upgradeCalled {
+		t.Error("upgrade was called with an invalid plan")
+	}
+}
+
+func TestUpgradeCmdPlanNotFound(t *testing.T) {
+	fe := &fakeExecutor{}
+	c := &upgradeCmd{
+		out:      &bytes.Buffer{},
+		planner:  &fakePlanner{exists: false},
+		executor: fe,
+	}
+	if err := c.run(); err == nil {
+		t.Error("expected error due to missing plan, but did not get one")
+	}
+	if fe.upgradeCalled {
+		t.Error("upgrade was called without a plan")
+	}
+}
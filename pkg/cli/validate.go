@@ -4,12 +4,15 @@ import (
 	"fmt"
 	"io"
 	"path/filepath"
+	"text/tabwriter"
+	"time"
 
 	"os"
 
 	"github.com/apprenda/kismatic/pkg/install"
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 type validateOpts struct {
@@ -18,6 +21,7 @@ type validateOpts struct {
 	verbose            bool
 	outputFormat       string
 	skipPreFlight      bool
+	sshValidation      install.SSHValidationOptions
 }
 
 // NewCmdValidate creates a new install validate command
@@ -37,12 +41,19 @@ func NewCmdValidate(out io.Writer, installOpts *installOpts) *cobra.Command {
 	}
 	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging from the installation")
-	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options simple|raw)")
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", "installation output format (options simple|raw|json)")
 	cmd.Flags().BoolVar(&opts.skipPreFlight, "skip-preflight", false, "skip pre-flight checks")
+	addSSHValidationFlags(cmd.Flags(), &opts.sshValidation)
 	return cmd
 }
 
-func doValidate(out io.Writer, planner install.Planner, opts *validateOpts) error {
+func addSSHValidationFlags(flagSet *pflag.FlagSet, opts *install.SSHValidationOptions) {
+	flagSet.IntVar(&opts.Concurrency, "ssh-concurrency", install.DefaultSSHValidationConcurrency, "maximum number of nodes whose SSH connectivity is validated at the same time")
+	flagSet.DurationVar(&opts.Timeout, "ssh-timeout", install.DefaultSSHValidationTimeout, "time allowed for validating the SSH connectivity to all nodes")
+}
+
+func doValidate(stdout io.Writer, planner install.Planner, opts *validateOpts) error {
+	out := messagesOut(stdout, opts.outputFormat)
 	util.PrintHeader(out, "Validating", '=')
 	// Check if plan file exists
 	if !planner.PlanExists() {
@@ -67,13 +78,23 @@ func doValidate(out io.Writer, planner install.Planner, opts *validateOpts) erro
 	util.PrettyPrintOk(out, "Validating installation plan file")
 
 	// Validate SSH connections
-	ok, errs = install.ValidatePlanSSHConnections(plan)
-	if !ok {
+	reports := install.CheckPlanSSHConnections(plan, opts.sshValidation)
+	errs = nil
+	for _, r := range reports {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("SSH connectivity validation failed for %q: %v", r.IP, r.Err))
+		}
+	}
+	if len(errs) > 0 {
 		util.PrettyPrintErr(out, "Validating SSH connectivity to nodes")
+		printSSHConnectionReports(out, reports)
 		util.PrintValidationErrors(out, errs)
 		return fmt.Errorf("SSH connectivity validation error prevents installation from proceeding")
 	}
 	util.PrettyPrintOk(out, "Validating SSH connectivity to nodes")
+	if opts.verbose {
+		printSSHConnectionReports(out, reports)
+	}
 
 	// get a new pki
 	pki, err := newPKI(out, opts)
@@ -96,7 +117,7 @@ func doValidate(out io.Writer, planner install.Planner, opts *validateOpts) erro
 		OutputFormat: opts.outputFormat,
 		Verbose:      opts.verbose,
 	}
-	e, err := install.NewPreFlightExecutor(out, os.Stderr, options)
+	e, err := install.NewPreFlightExecutor(stdout, os.Stderr, options)
 	if err != nil {
 		return err
 	}
@@ -123,3 +144,16 @@ func newPKI(stdout io.Writer, options *validateOpts) (*install.LocalPKI, error)
 
 	return pki, nil
 }
+
+func printSSHConnectionReports(out io.Writer, reports []install.SSHConnectionReport) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "HOST\tIP\tREACHABLE\tAUTHENTICATED\tSUDO\tLATENCY\n")
+	for _, r := range reports {
+		latency := "-"
+		if r.Reachable {
+			latency = fmt.Sprintf("%dms", r.Latency/time.Millisecond)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%t\t%t\t%t\t%s\n", r.Host, r.IP, r.Reachable, r.Authenticated, r.Sudo, latency)
+	}
+	w.Flush()
+}
@@ -2,7 +2,11 @@ package cli
 
 import (
 	"bytes"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/apprenda/kismatic/pkg/install"
 )
@@ -47,3 +51,27 @@ func TestValidateCmdPlanInvalid(t *testing.T) {
 		t.Errorf("did not read the plan file")
 	}
 }
+
+func TestPrintSSHConnectionReports(t *testing.T) {
+	out := &bytes.Buffer{}
+	reports := []install.SSHConnectionReport{
+		{Host: "etcd01", IP: "10.0.0.1"},
+		{Host: "master01", IP: "10.0.0.2"},
+	}
+	reports[0].Reachable = true
+	reports[0].Authenticated = true
+	reports[0].Sudo = true
+	reports[0].Latency = 12 * time.Millisecond
+	reports[1].Err = errors.New("connection refused")
+	printSSHConnectionReports(out, reports)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and a line per node, got:\n%s", out.String())
+	}
+	if fields := strings.Fields(lines[1]); !reflect.DeepEqual(fields, []string{"etcd01", "10.0.0.1", "true", "true", "true", "12ms"}) {
+		t.Errorf("unexpected report line %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); !reflect.DeepEqual(fields, []string{"master01", "10.0.0.2", "false", "false", "false", "-"}) {
+		t.Errorf("unexpected report line %q", lines[2])
+	}
+}
@@ -44,12 +44,13 @@ This function requires a target cluster that has storage nodes.`,
 	cmd.Flags().StringVarP(&opts.storageClass, "storage-class", "c", "kismatic", "The StorageClass to present for claims in Kubernetes. Classes should identify properties of volumes in business terms, such as 'durable' or 'fast-reads'")
 	cmd.Flags().StringSliceVarP(&opts.allowAddress, "allow-address", "a", nil, "Comma delimited list of address wildcards permitted access to the volume in addition to Kubernetes nodes.")
 	cmd.Flags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging")
-	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options "simple"|"raw")`)
+	cmd.Flags().StringVarP(&opts.outputFormat, "output", "o", "simple", `output format (options "simple"|"raw"|"json")`)
 	cmd.Flags().StringVar(&opts.generatedAssetsDir, "generated-assets-dir", "generated", "path to the directory where assets generated during the installation process will be stored")
 	return cmd
 }
 
-func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []string) error {
+func doVolumeAdd(stdout io.Writer, opts volumeAddOptions, planFile string, args []string) error {
+	out := messagesOut(stdout, opts.outputFormat)
 	// Setup ansible
 	planner := &install.FilePlanner{File: planFile}
 	if !planner.PlanExists() {
@@ -81,7 +82,7 @@ func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []s
 		// Need to refactor executor code... this will do for now as we don't need the generated assets dir in this command
 		GeneratedAssetsDirectory: opts.generatedAssetsDir,
 	}
-	exec, err := install.NewExecutor(out, out, execOpts)
+	exec, err := install.NewExecutor(stdout, out, execOpts)
 	if err != nil {
 		return err
 	}
@@ -98,7 +99,7 @@ func doVolumeAdd(out io.Writer, opts volumeAddOptions, planFile string, args []s
 		skipPreFlight:      true,
 		generatedAssetsDir: opts.generatedAssetsDir,
 	}
-	if err := doValidate(out, planner, vopts); err != nil {
+	if err := doValidate(stdout, planner, vopts); err != nil {
 		return err
 	}
 
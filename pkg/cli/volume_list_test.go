@@ -15,6 +15,8 @@ type fakeKubernetesGetter struct {
 	pvList          []byte
 	pvsInNil        bool
 	pvsShouldError  bool
+	nodeList        []byte
+	csList          []byte
 }
 
 type fakeGlusterGetter struct {
@@ -46,6 +48,14 @@ func (g fakeKubernetesGetter) ListPersistentVolumes() (*data.PersistentVolumeLis
 	return data.UnmarshalPVs(string(g.pvList))
 }
 
+func (g fakeKubernetesGetter) ListNodes() (*data.NodeList, error) {
+	return data.UnmarshalNodes(string(g.nodeList))
+}
+
+func (g fakeKubernetesGetter) ListComponentStatuses() (*data.ComponentStatusList, error) {
+	return data.UnmarshalComponentStatuses(string(g.csList))
+}
+
 func (g fakeGlusterGetter) ListVolumes() (*data.GlusterVolumeInfoCliOutput, error) {
 	if g.isNil {
 		return nil, nil
@@ -16,9 +16,19 @@ type PVLister interface {
 	ListPersistentVolumes() (*PersistentVolumeList, error)
 }
 
+type NodeLister interface {
+	ListNodes() (*NodeList, error)
+}
+
+type ComponentStatusLister interface {
+	ListComponentStatuses() (*ComponentStatusList, error)
+}
+
 type KubernetesClient interface {
 	PodLister
 	PVLister
+	NodeLister
+	ComponentStatusLister
 }
 
 // RemoteKubectl
@@ -73,3 +83,52 @@ func UnmarshalPods(raw string) (*PodList, error) {
 
 	return &pods, nil
 }
+
+// ListNodes returns the nodes registered with the cluster
+func (k RemoteKubectl) ListNodes() (*NodeList, error) {
+	nodesRaw, err := k.SSHClient.Output(true, "sudo kubectl get nodes -o json")
+	if err != nil {
+		return nil, fmt.Errorf("error getting node data: %v", err)
+	}
+
+	return UnmarshalNodes(nodesRaw)
+}
+
+func UnmarshalNodes(raw string) (*NodeList, error) {
+	// an empty JSON response from kubectl contains this string
+	if strings.Contains(raw, "No resources found") {
+		return nil, nil
+	}
+	var nodes NodeList
+	err := json.Unmarshal([]byte(raw), &nodes)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling node data: %v", err)
+	}
+
+	return &nodes, nil
+}
+
+// ListComponentStatuses returns the status of the scheduler, controller manager and etcd
+// as seen by the API server
+func (k RemoteKubectl) ListComponentStatuses() (*ComponentStatusList, error) {
+	csRaw, err := k.SSHClient.Output(true, "sudo kubectl get componentstatuses -o json")
+	if err != nil {
+		return nil, fmt.Errorf("error getting component status data: %v", err)
+	}
+
+	return UnmarshalComponentStatuses(csRaw)
+}
+
+func UnmarshalComponentStatuses(raw string) (*ComponentStatusList, error) {
+	// an empty JSON response from kubectl contains this string
+	if strings.Contains(raw, "No resources found") {
+		return nil, nil
+	}
+	var cs ComponentStatusList
+	err := json.Unmarshal([]byte(raw), &cs)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling component status data: %v", err)
+	}
+
+	return &cs, nil
+}
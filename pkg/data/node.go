@@ -0,0 +1,151 @@
+package data
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
+)
+
+// NodeClient reports the health of the services that run on a node
+type NodeClient interface {
+	EtcdClusterHealth(cluster EtcdCluster) (*EtcdClusterHealth, error)
+	CalicoNodeStatus() (*CalicoNodeStatus, error)
+	HTTPStatusCode(url string) (int, error)
+}
+
+// EtcdCluster is an etcd cluster that runs on the etcd nodes
+type EtcdCluster struct {
+	Name string
+	// Port is the client port of the cluster
+	Port int
+	// CertsDir is the directory that contains the client certificates of the cluster
+	CertsDir string
+}
+
+// EtcdClusterHealth is the health of an etcd cluster, as reported by etcdctl
+type EtcdClusterHealth struct {
+	Healthy bool
+	Members []EtcdMemberHealth
+}
+
+// EtcdMemberHealth is the health of a member of an etcd cluster
+type EtcdMemberHealth struct {
+	ID        string
+	ClientURL string
+	Healthy   bool
+	Message   string
+}
+
+// CalicoNodeStatus is the status of calico on a node, as reported by calicoctl
+type CalicoNodeStatus struct {
+	Running bool
+	Peers   []CalicoPeer
+}
+
+// CalicoPeer is a BGP peer of a calico node
+type CalicoPeer struct {
+	Address string
+	Type    string
+	State   string
+	Info    string
+}
+
+// RemoteNode runs the health checks on a node over SSH
+type RemoteNode struct {
+	SSHClient ssh.Client
+}
+
+// EtcdClusterHealth returns the health of the etcd cluster, using the member on the node
+func (n RemoteNode) EtcdClusterHealth(cluster EtcdCluster) (*EtcdClusterHealth, error) {
+	cmd := fmt.Sprintf("sudo etcdctl --endpoint='https://127.0.0.1:%d/' --cert-file=%s/etcd.pem --key-file=%s/etcd-key.pem --ca-file=%s/ca.pem cluster-health",
+		cluster.Port, cluster.CertsDir, cluster.CertsDir, cluster.CertsDir)
+	// etcdctl exits with an error when the cluster is not healthy
+	raw, err := n.SSHClient.Output(true, cmd)
+	health := UnmarshalEtcdClusterHealth(raw)
+	if err != nil && len(health.Members) == 0 {
+		return nil, fmt.Errorf("error getting %s etcd cluster health: %v", cluster.Name, err)
+	}
+
+	return health, nil
+}
+
+var (
+	etcdMemberHealthRE = regexp.MustCompile(`^member (\S+) is (\w+): (.*)$`)
+	etcdClientURLRE    = regexp.MustCompile(`https?://[^\s\]]+`)
+)
+
+func UnmarshalEtcdClusterHealth(raw string) *EtcdClusterHealth {
+	health := &EtcdClusterHealth{}
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "cluster is healthy" {
+			health.Healthy = true
+			continue
+		}
+		m := etcdMemberHealthRE.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		member := EtcdMemberHealth{ID: m[1], Healthy: m[2] == "healthy", Message: m[3]}
+		member.ClientURL = etcdClientURLRE.FindString(m[3])
+		health.Members = append(health.Members, member)
+	}
+
+	return health
+}
+
+// CalicoNodeStatus returns the status of calico on the node
+func (n RemoteNode) CalicoNodeStatus() (*CalicoNodeStatus, error) {
+	raw, err := n.SSHClient.Output(true, "sudo calicoctl node status")
+	if err != nil {
+		return nil, fmt.Errorf("error getting calico node status: %v", err)
+	}
+
+	return UnmarshalCalicoNodeStatus(raw), nil
+}
+
+func UnmarshalCalicoNodeStatus(raw string) *CalicoNodeStatus {
+	status := &CalicoNodeStatus{}
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "Calico process is running." {
+			status.Running = true
+			continue
+		}
+		// the peers are in a table, one per row
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+		cols := strings.Split(strings.Trim(line, "|"), "|")
+		if len(cols) < 5 {
+			continue
+		}
+		for i := range cols {
+			cols[i] = strings.TrimSpace(cols[i])
+		}
+		if cols[0] == "PEER ADDRESS" {
+			continue
+		}
+		status.Peers = append(status.Peers, CalicoPeer{Address: cols[0], Type: cols[1], State: cols[2], Info: cols[4]})
+	}
+
+	return status
+}
+
+// HTTPStatusCode returns the status code of a GET request made from the node.
+// The certificate of the server is not verified.
+func (n RemoteNode) HTTPStatusCode(url string) (int, error) {
+	raw, err := n.SSHClient.Output(false, fmt.Sprintf("curl -sk -o /dev/null -m 5 -w '%%{http_code}' %s", url))
+	if err != nil {
+		return 0, fmt.Errorf("error reaching %s: %v", url, err)
+	}
+	code, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected response from %s: %q", url, raw)
+	}
+
+	return code, nil
+}
@@ -0,0 +1,61 @@
+package data
+
+import "testing"
+
+func TestUnmarshalEtcdClusterHealth(t *testing.T) {
+	raw := "member 8e9e05c52164694d is healthy: got healthy result from https://10.0.0.1:2379\r\n" +
+		"member 91bc3c398fb3c146 is unreachable: [https://10.0.0.2:2379] are all unreachable\r\n" +
+		"cluster is degraded\r\n"
+	health := UnmarshalEtcdClusterHealth(raw)
+	if health.Healthy {
+		t.Errorf("expected degraded cluster to not be healthy")
+	}
+	if len(health.Members) != 2 {
+		t.Fatalf("expected 2 members, got %d", len(health.Members))
+	}
+	if !health.Members[0].Healthy || health.Members[0].ClientURL != "https://10.0.0.1:2379" {
+		t.Errorf("unexpected first member: %+v", health.Members[0])
+	}
+	if health.Members[1].Healthy || health.Members[1].ClientURL != "https://10.0.0.2:2379" {
+		t.Errorf("unexpected second member: %+v", health.Members[1])
+	}
+
+	health = UnmarshalEtcdClusterHealth("member 8e9e05c52164694d is healthy: got healthy result from https://10.0.0.1:2379\ncluster is healthy\n")
+	if !health.Healthy {
+		t.Errorf("expected cluster to be healthy")
+	}
+}
+
+func TestUnmarshalCalicoNodeStatus(t *testing.T) {
+	raw := `Calico process is running.
+
+IPv4 BGP status
++--------------+-------------------+-------+----------+--------------------------------+
+| PEER ADDRESS |     PEER TYPE     | STATE |  SINCE   |              INFO              |
++--------------+-------------------+-------+----------+--------------------------------+
+| 10.0.0.2     | node-to-node mesh | up    | 23:30:04 | Established                    |
+| 10.0.0.3     | node-to-node mesh | start | 23:30:04 | Connect Socket: Connection     |
++--------------+-------------------+-------+----------+--------------------------------+
+
+IPv6 BGP status
+No IPv6 peers found.
+`
+	status := UnmarshalCalicoNodeStatus(raw)
+	if !status.Running {
+		t.Errorf("expected calico to be running")
+	}
+	if len(status.Peers) != 2 {
+		t.Fatalf("expected 2 peers, got %d", len(status.Peers))
+	}
+	if status.Peers[0].Address != "10.0.0.2" || status.Peers[0].Info != "Established" {
+		t.Errorf("unexpected first peer: %+v", status.Peers[0])
+	}
+	if status.Peers[1].State != "start" || status.Peers[1].Info != "Connect Socket: Connection" {
+		t.Errorf("unexpected second peer: %+v", status.Peers[1])
+	}
+
+	status = UnmarshalCalicoNodeStatus("Calico process is not running.\n")
+	if status.Running {
+		t.Errorf("expected calico to not be running")
+	}
+}
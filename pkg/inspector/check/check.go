@@ -1,5 +1,7 @@
 package check
 
+import "context"
+
 // A Check implements a workflow that validates a condition. If an error
 // occurs while running the check, it returns false and the error. If the
 // check is able to successfully determine the condition, it returns true
@@ -13,3 +15,24 @@ type ClosableCheck interface {
 	Check
 	Close() error
 }
+
+// A RemediableCheck is a check that can suggest the steps to take when
+// the check fails
+type RemediableCheck interface {
+	Check
+	Remediation() string
+}
+
+// An ExclusiveCheck is a check that must not run at the same time as the
+// other checks that use the same resource, such as the package manager
+type ExclusiveCheck interface {
+	Check
+	ExclusiveResource() string
+}
+
+// A ContextCheck is a check that stops, and kills the commands that it
+// started, when the context is done
+type ContextCheck interface {
+	Check
+	CheckContext(ctx context.Context) (bool, error)
+}
@@ -1,6 +1,7 @@
 package check
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
 	"regexp"
@@ -9,21 +10,33 @@ import (
 // ExecutableInPathCheck checks whether the binary is on the executable path
 type ExecutableInPathCheck struct {
 	Name string
+	// Distro of the node, used for suggesting how to install the executable
+	Distro Distro
 }
 
 // Check returns true if the executable is in the path
 func (c ExecutableInPathCheck) Check() (bool, error) {
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext runs the check, and stops it when the context is done
+func (c ExecutableInPathCheck) CheckContext(ctx context.Context) (bool, error) {
 	// Need to explicitly call bash when running against Ubuntu
 	if err := c.validateExecutableName(); err != nil {
 		return false, err
 	}
-	cmd := exec.Command("bash", "-c", fmt.Sprintf("command -v %s", c.Name))
+	cmd := exec.CommandContext(ctx, "bash", "-c", fmt.Sprintf("command -v %s", c.Name))
 	if err := cmd.Run(); err != nil {
 		return false, nil
 	}
 	return true, nil
 }
 
+// Remediation returns the steps for installing the executable
+func (c ExecutableInPathCheck) Remediation() string {
+	return executableRemediation(c.Distro, c.Name)
+}
+
 func (c ExecutableInPathCheck) validateExecutableName() error {
 	// We need to ensure that we are not executing arbitrary code here...
 	// Only allow single word binary names. Allow dashes.
@@ -0,0 +1,224 @@
+package check
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// KernelModuleCheck returns true if the kernel module is loaded, built into
+// the kernel, or can be loaded
+type KernelModuleCheck struct {
+	Module string
+}
+
+// Check returns true if the kernel module is available
+func (c KernelModuleCheck) Check() (bool, error) {
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext runs the check, and stops modprobe when the context is done
+func (c KernelModuleCheck) CheckContext(ctx context.Context) (bool, error) {
+	// Loaded modules are listed in /sys/module with dashes replaced by underscores
+	name := strings.Replace(c.Module, "-", "_", -1)
+	if _, err := os.Stat(filepath.Join("/sys/module", name)); err == nil {
+		return true, nil
+	}
+	// modprobe resolves the module, including the built-in ones, without
+	// loading it when running in dry-run mode
+	if err := exec.CommandContext(ctx, "modprobe", "--dry-run", c.Module).Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return false, nil
+		}
+		return false, fmt.Errorf("error running modprobe: %v", err)
+	}
+	return true, nil
+}
+
+// SysctlCheck returns true if the kernel parameter has the given value
+type SysctlCheck struct {
+	Parameter string
+	Value     string
+}
+
+// Check returns true if the kernel parameter has the value
+func (c SysctlCheck) Check() (bool, error) {
+	file := filepath.Join("/proc/sys", strings.Replace(c.Parameter, ".", "/", -1))
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return false, fmt.Errorf("kernel parameter %q does not exist", c.Parameter)
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading kernel parameter %q: %v", c.Parameter, err)
+	}
+	return sysctlValueEquals(string(b), c.Value), nil
+}
+
+// Remediation returns the steps for setting the kernel parameter
+func (c SysctlCheck) Remediation() string {
+	return sysctlRemediation(c.Parameter, c.Value)
+}
+
+// values with multiple fields, such as net.ipv4.ip_local_port_range, are
+// separated by tabs, so the fields are compared instead of the strings
+func sysctlValueEquals(actual, expected string) bool {
+	a := strings.Fields(actual)
+	e := strings.Fields(expected)
+	if len(a) != len(e) {
+		return false
+	}
+	for i := range a {
+		if a[i] != e[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// KernelVersionCheck returns true if the version of the running kernel is
+// at least the minimum version
+type KernelVersionCheck struct {
+	MinimumVersion string
+}
+
+// Check returns true if the kernel is recent enough
+func (c KernelVersionCheck) Check() (bool, error) {
+	b, err := ioutil.ReadFile("/proc/sys/kernel/osrelease")
+	if err != nil {
+		return false, fmt.Errorf("error reading kernel version: %v", err)
+	}
+	return CompareKernelVersions(strings.TrimSpace(string(b)), c.MinimumVersion) >= 0, nil
+}
+
+// ParseKernelVersion returns the numeric components of the kernel version.
+// The components are separated by dots or dashes, and the parsing stops at
+// the first component that is not a number, such that "3.10.0-514.el7.x86_64"
+// is parsed as [3 10 0 514].
+func ParseKernelVersion(version string) []int {
+	fields := strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '-' })
+	parts := []int{}
+	for _, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// CompareKernelVersions returns a negative number if a is older than b, zero
+// if they are the same, and a positive number if a is newer than b. Missing
+// components are compared as zero.
+func CompareKernelVersions(a, b string) int {
+	va := ParseKernelVersion(a)
+	vb := ParseKernelVersion(b)
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
+
+// SwapDisabledCheck returns true if no swap space is in use
+type SwapDisabledCheck struct{}
+
+// Check returns true if swap is disabled
+func (c SwapDisabledCheck) Check() (bool, error) {
+	f, err := os.Open("/proc/swaps")
+	if err != nil {
+		return false, fmt.Errorf("error reading /proc/swaps: %v", err)
+	}
+	defer f.Close()
+	enabled, err := swapEnabled(f)
+	if err != nil {
+		return false, err
+	}
+	return !enabled, nil
+}
+
+// Remediation returns the steps for disabling swap
+func (c SwapDisabledCheck) Remediation() string {
+	return swapRemediation()
+}
+
+// the swaps file has a header line, followed by a line for each swap space
+func swapEnabled(r io.Reader) (bool, error) {
+	s := bufio.NewScanner(r)
+	lines := 0
+	for s.Scan() {
+		if strings.TrimSpace(s.Text()) != "" {
+			lines++
+		}
+	}
+	if err := s.Err(); err != nil {
+		return false, fmt.Errorf("error reading swaps: %v", err)
+	}
+	return lines > 1, nil
+}
+
+// The SELinux modes
+const (
+	SELinuxEnforcing  = "enforcing"
+	SELinuxPermissive = "permissive"
+	SELinuxDisabled   = "disabled"
+)
+
+// SELinuxModeCheck returns true if the current SELinux mode is one of the
+// allowed modes. Nodes without SELinux are in disabled mode.
+type SELinuxModeCheck struct {
+	AllowedModes []string
+}
+
+// Check returns true if the SELinux mode is allowed
+func (c SELinuxModeCheck) Check() (bool, error) {
+	mode, err := selinuxMode("/sys/fs/selinux")
+	if err != nil {
+		return false, err
+	}
+	for _, m := range c.AllowedModes {
+		if strings.ToLower(m) == mode {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Remediation returns the steps for switching to an allowed SELinux mode
+func (c SELinuxModeCheck) Remediation() string {
+	return selinuxRemediation(c.AllowedModes)
+}
+
+// returns the SELinux mode from the selinuxfs mounted in the directory
+func selinuxMode(selinuxfs string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(selinuxfs, "enforce"))
+	if os.IsNotExist(err) {
+		return SELinuxDisabled, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading SELinux mode: %v", err)
+	}
+	switch strings.TrimSpace(string(b)) {
+	case "1":
+		return SELinuxEnforcing, nil
+	case "0":
+		return SELinuxPermissive, nil
+	default:
+		return "", fmt.Errorf("unknown SELinux mode %q", strings.TrimSpace(string(b)))
+	}
+}
@@ -0,0 +1,99 @@
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareKernelVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"3.10.0-514.el7.x86_64", "3.10.0-327", 1},
+		{"3.10.0-229.el7.x86_64", "3.10.0-327", -1},
+		{"4.4.0-62-generic", "4.4", 1},
+		{"4.4.0", "4.4", 0},
+		{"3.13.0-107-generic", "4.4", -1},
+		{"4.10.0-rc1", "4.9.8", 1},
+	}
+	for _, test := range tests {
+		c := CompareKernelVersions(test.a, test.b)
+		if (c < 0 && test.expected >= 0) || (c > 0 && test.expected <= 0) || (c == 0 && test.expected != 0) {
+			t.Errorf("comparing %q to %q: expected %d, got %d", test.a, test.b, test.expected, c)
+		}
+	}
+}
+
+func TestSysctlValueEquals(t *testing.T) {
+	if !sysctlValueEquals("1\n", "1") {
+		t.Errorf("expected the values to be equal")
+	}
+	if !sysctlValueEquals("32768\t60999\n", "32768 60999") {
+		t.Errorf("expected the fields to be compared")
+	}
+	if sysctlValueEquals("0\n", "1") {
+		t.Errorf("expected the values to be different")
+	}
+}
+
+func TestSwapEnabled(t *testing.T) {
+	header := "Filename\t\t\t\tType\t\tSize\tUsed\tPriority\n"
+	enabled, err := swapEnabled(strings.NewReader(header))
+	if err != nil || enabled {
+		t.Errorf("expected swap to be disabled, got %v and %v", enabled, err)
+	}
+	enabled, err = swapEnabled(strings.NewReader(header + "/dev/sda2                               partition\t2097148\t0\t-1\n"))
+	if err != nil || !enabled {
+		t.Errorf("expected swap to be enabled, got %v and %v", enabled, err)
+	}
+}
+
+func TestSELinuxMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selinux-check")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if mode, err := selinuxMode(dir); err != nil || mode != SELinuxDisabled {
+		t.Errorf("expected disabled mode without selinuxfs, got %q and %v", mode, err)
+	}
+	for content, expected := range map[string]string{"1": SELinuxEnforcing, "0\n": SELinuxPermissive} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "enforce"), []byte(content), 0644); err != nil {
+			t.Fatalf("error writing enforce file: %v", err)
+		}
+		if mode, err := selinuxMode(dir); err != nil || mode != expected {
+			t.Errorf("expected %q mode, got %q and %v", expected, mode, err)
+		}
+	}
+}
+
+func TestSELinuxModeRemediation(t *testing.T) {
+	tests := []struct {
+		allowed  []string
+		contains string
+	}{
+		{allowed: []string{"disabled", "Permissive"}, contains: "setenforce 0"},
+		{allowed: []string{"disabled"}, contains: "SELINUX=disabled"},
+		{allowed: []string{"enforcing"}, contains: "SELINUX=enforcing"},
+	}
+	for _, test := range tests {
+		r := SELinuxModeCheck{AllowedModes: test.allowed}.Remediation()
+		if !strings.Contains(r, test.contains) {
+			t.Errorf("expected the remediation for modes %v to contain %q, but got %q", test.allowed, test.contains, r)
+		}
+	}
+}
+
+func TestSysctlRemediation(t *testing.T) {
+	r := SysctlCheck{Parameter: "net.ipv4.ip_forward", Value: "1"}.Remediation()
+	if !strings.Contains(r, "sudo sysctl -w 'net.ipv4.ip_forward=1'") {
+		t.Errorf("expected the remediation to set the parameter, but got %q", r)
+	}
+	if !strings.Contains(r, "/etc/sysctl.d") {
+		t.Errorf("expected the remediation to persist the parameter, but got %q", r)
+	}
+}
@@ -1,6 +1,9 @@
 package check
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type PackageQuery struct {
 	Name    string
@@ -16,14 +19,33 @@ func (p PackageQuery) String() string {
 type PackageAvailableCheck struct {
 	PackageQuery   PackageQuery
 	PackageManager PackageManager
+	// Distro of the node, used for suggesting how to install the package
+	Distro Distro
 }
 
 // Check returns true if the package is available. Otherwise returns false, or an error
 // if the check is unable to determine the condition.
 func (c PackageAvailableCheck) Check() (bool, error) {
-	ok, err := IsPackageReadyToContinue(c.PackageManager, c.PackageQuery)
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext runs the check, and stops the package manager when the
+// context is done
+func (c PackageAvailableCheck) CheckContext(ctx context.Context) (bool, error) {
+	ok, err := IsPackageReadyToContinue(ctx, c.PackageManager, c.PackageQuery)
 	if err != nil {
 		return false, err
 	}
 	return ok, nil
 }
+
+// Remediation returns the command that installs the package
+func (c PackageAvailableCheck) Remediation() string {
+	return packageInstallRemediation(c.Distro, c.PackageQuery)
+}
+
+// ExclusiveResource returns the package manager, as yum and apt-get hold
+// a lock while they run
+func (c PackageAvailableCheck) ExclusiveResource() string {
+	return "package-manager"
+}
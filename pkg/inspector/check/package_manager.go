@@ -3,6 +3,7 @@ package check
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"os/exec"
 	"strings"
@@ -11,20 +12,20 @@ import (
 // PackageManager runs queries against the underlying operating system's
 // package manager
 type PackageManager interface {
-	IsAvailable(PackageQuery) (bool, error)
-	IsInstalled(PackageQuery) (bool, error)
+	IsAvailable(context.Context, PackageQuery) (bool, error)
+	IsInstalled(context.Context, PackageQuery) (bool, error)
 	Enforced() bool
 }
 
-func IsPackageReadyToContinue(m PackageManager, q PackageQuery) (bool, error) {
+func IsPackageReadyToContinue(ctx context.Context, m PackageManager, q PackageQuery) (bool, error) {
 	if m.Enforced() {
-		installed, _ := m.IsInstalled(q)
+		installed, _ := m.IsInstalled(ctx, q)
 
 		if installed {
 			return true, nil
 		}
 
-		available, _ := m.IsAvailable(q)
+		available, _ := m.IsAvailable(ctx, q)
 
 		if available {
 			return false, fmt.Errorf("%v is not installed but is available in a package repository", q)
@@ -37,19 +38,15 @@ func IsPackageReadyToContinue(m PackageManager, q PackageQuery) (bool, error) {
 
 // NewPackageManager returns a package manager for the given distribution
 func NewPackageManager(distro Distro, enforcePackages bool) (PackageManager, error) {
-	run := func(name string, arg ...string) ([]byte, error) {
-		r, err := exec.Command(name, arg...).CombinedOutput()
-		return r, err
-	}
 	switch distro {
 	case RHEL, CentOS:
 		return &rpmManager{
-			run:             run,
+			run:             runCommand,
 			enforcePackages: enforcePackages,
 		}, nil
 	case Ubuntu:
 		return &debManager{
-			run:             run,
+			run:             runCommand,
 			enforcePackages: enforcePackages,
 		}, nil
 	case Darwin:
@@ -59,12 +56,17 @@ func NewPackageManager(distro Distro, enforcePackages bool) (PackageManager, err
 	}
 }
 
+// runs the command, and kills it if the context is done before it completes
+func runCommand(ctx context.Context, name string, arg ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, arg...).CombinedOutput()
+}
+
 type noopManager struct{}
 
-func (noopManager) IsAvailable(PackageQuery) (bool, error) {
+func (noopManager) IsAvailable(context.Context, PackageQuery) (bool, error) {
 	return false, fmt.Errorf("unable to determine if package is available using noop pkg manager")
 }
-func (noopManager) IsInstalled(PackageQuery) (bool, error) {
+func (noopManager) IsInstalled(context.Context, PackageQuery) (bool, error) {
 	return false, fmt.Errorf("unable to determine if package is installed using noop pkg manager")
 }
 func (noopManager) Enforced() bool {
@@ -73,7 +75,7 @@ func (noopManager) Enforced() bool {
 
 // package manager for EL-based distributions
 type rpmManager struct {
-	run             func(string, ...string) ([]byte, error)
+	run             func(context.Context, string, ...string) ([]byte, error)
 	enforcePackages bool
 }
 
@@ -81,8 +83,8 @@ func (m rpmManager) Enforced() bool {
 	return m.enforcePackages
 }
 
-func (m rpmManager) IsAvailable(p PackageQuery) (bool, error) {
-	out, err := m.run("yum", "list", "available", "-q", p.Name)
+func (m rpmManager) IsAvailable(ctx context.Context, p PackageQuery) (bool, error) {
+	out, err := m.run(ctx, "yum", "list", "available", "-q", p.Name)
 	if err != nil && strings.Contains(string(out), "No matching Packages to list") {
 		return false, nil
 	}
@@ -92,8 +94,8 @@ func (m rpmManager) IsAvailable(p PackageQuery) (bool, error) {
 	return m.isPackageListed(p, out), nil
 }
 
-func (m rpmManager) IsInstalled(p PackageQuery) (bool, error) {
-	out, err := m.run("yum", "list", "installed", "-q", p.Name)
+func (m rpmManager) IsInstalled(ctx context.Context, p PackageQuery) (bool, error) {
+	out, err := m.run(ctx, "yum", "list", "installed", "-q", p.Name)
 	if err != nil && strings.Contains(string(out), "No matching Packages to list") {
 		return false, nil
 	}
@@ -124,7 +126,7 @@ func (m rpmManager) isPackageListed(p PackageQuery, list []byte) bool {
 
 // package manager for debian-based distributions
 type debManager struct {
-	run             func(string, ...string) ([]byte, error)
+	run             func(context.Context, string, ...string) ([]byte, error)
 	enforcePackages bool
 }
 
@@ -132,20 +134,20 @@ func (m debManager) Enforced() bool {
 	return m.enforcePackages
 }
 
-func (m debManager) IsInstalled(p PackageQuery) (bool, error) {
+func (m debManager) IsInstalled(ctx context.Context, p PackageQuery) (bool, error) {
 	// First check if the package is installed
-	installed, err := m.isPackageListed(p)
+	installed, err := m.isPackageListed(ctx, p)
 	if err != nil {
 		return false, err
 	}
 	return installed, nil
 }
 
-func (m debManager) IsAvailable(p PackageQuery) (bool, error) {
+func (m debManager) IsAvailable(ctx context.Context, p PackageQuery) (bool, error) {
 	// If it's not installed, ensure that it is available via the
 	// package manager. We attempt to install using --dry-run. If exit status is zero, we
 	// know the package is available for download
-	out, err := m.run("apt-get", "install", "-q", "--dry-run", fmt.Sprintf("%s=%s", p.Name, p.Version))
+	out, err := m.run(ctx, "apt-get", "install", "-q", "--dry-run", fmt.Sprintf("%s=%s", p.Name, p.Version))
 	if err != nil && strings.Contains(string(out), "Unable to locate package") {
 		return false, nil
 	}
@@ -155,8 +157,8 @@ func (m debManager) IsAvailable(p PackageQuery) (bool, error) {
 	return true, nil
 }
 
-func (m debManager) isPackageListed(p PackageQuery) (bool, error) {
-	out, err := m.run("dpkg", "-l", p.Name)
+func (m debManager) isPackageListed(ctx context.Context, p PackageQuery) (bool, error) {
+	out, err := m.run(ctx, "dpkg", "-l", p.Name)
 	if err != nil && strings.Contains(string(out), "no packages found matching") {
 		return false, nil
 	}
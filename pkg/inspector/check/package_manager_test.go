@@ -1,9 +1,11 @@
 package check
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 )
 
 type runMock struct {
@@ -15,7 +17,7 @@ type runMock struct {
 	dpkgErr   error
 }
 
-func (m runMock) run(cmd string, args ...string) ([]byte, error) {
+func (m runMock) run(ctx context.Context, cmd string, args ...string) ([]byte, error) {
 	switch cmd {
 	default:
 		panic(fmt.Sprintf("mock does not implement command %s", cmd))
@@ -45,7 +47,7 @@ NetworkManager.x86_64           1:1.0.6-30.el7_2               @koji-override-1`
 		run: mock.run,
 	}
 	p := PackageQuery{"NetworkManager", "1:1.0.6-30.el7_2"}
-	ok, _ := m.IsAvailable(p)
+	ok, _ := m.IsAvailable(context.Background(), p)
 	if !ok {
 		t.Error("expected true, but got false")
 	}
@@ -61,7 +63,7 @@ func TestRPMPackageManagerPackageNotFound(t *testing.T) {
 		run: mock.run,
 	}
 	p := PackageQuery{"NonExistent", "1.0"}
-	ok, err := m.IsAvailable(p)
+	ok, err := m.IsAvailable(context.Background(), p)
 	if ok {
 		t.Error("expected false, but got true")
 	}
@@ -80,7 +82,7 @@ NetworkManager.x86_64           1:1.0.6-30.el7_2               @koji-override-1`
 		run: mock.run,
 	}
 	p := PackageQuery{"NetworkManager", "1.0"}
-	ok, err := m.IsAvailable(p)
+	ok, err := m.IsAvailable(context.Background(), p)
 	if ok {
 		t.Error("expected false, but got true")
 	}
@@ -99,7 +101,7 @@ NetworkManager.x86_64           1:1.0.6-30.el7_2               @koji-override-1`
 		run: mock.run,
 	}
 	p := PackageQuery{"NetworkManagr", "1:1.0.6-30.el7_2"}
-	ok, err := m.IsAvailable(p)
+	ok, err := m.IsAvailable(context.Background(), p)
 	if ok {
 		t.Error("expected false, but got true")
 	}
@@ -116,7 +118,7 @@ func TestRPMPackageManagerExecError(t *testing.T) {
 		run: mock.run,
 	}
 	p := PackageQuery{"SomePkg", "1.0"}
-	ok, err := m.IsAvailable(p)
+	ok, err := m.IsAvailable(context.Background(), p)
 	if ok {
 		t.Error("expected false, but got true")
 	}
@@ -139,7 +141,7 @@ ii  libc6:amd64                                           2.23-0ubuntu3
 		run: mock.run,
 	}
 	p := PackageQuery{"libc6", "2.23"}
-	ok, _ := m.IsAvailable(p)
+	ok, _ := m.IsAvailable(context.Background(), p)
 	if !ok {
 		t.Errorf("expected true, but got false")
 	}
@@ -156,7 +158,7 @@ func TestDebPackageManagerPackageNotInstalledButAvailable(t *testing.T) {
 		run: mock.run,
 	}
 	p := PackageQuery{"libc6a", "1.0"}
-	ok, err := m.IsAvailable(p)
+	ok, err := m.IsAvailable(context.Background(), p)
 	if !ok {
 		t.Errorf("expected true, got false")
 	}
@@ -176,7 +178,7 @@ func TestDebPackageManagerPackageNotInstalledNotAvailable(t *testing.T) {
 		run: mock.run,
 	}
 	p := PackageQuery{"libc6a", "1.0"}
-	ok, err := m.IsAvailable(p)
+	ok, err := m.IsAvailable(context.Background(), p)
 	if ok {
 		t.Errorf("expected false, but got true")
 	}
@@ -193,7 +195,7 @@ func TestDebPackageManagerExecError(t *testing.T) {
 		run: mock.run,
 	}
 	p := PackageQuery{"", ""}
-	ok, err := m.IsInstalled(p)
+	ok, err := m.IsInstalled(context.Background(), p)
 	if ok {
 		t.Error("expected false, but got true")
 	}
@@ -210,7 +212,7 @@ func TestDebPackageManagerExecError2(t *testing.T) {
 		run: mock.run,
 	}
 	p := PackageQuery{"", ""}
-	ok, err := m.IsAvailable(p)
+	ok, err := m.IsAvailable(context.Background(), p)
 	if ok {
 		t.Error("expected false, but got true")
 	}
@@ -218,3 +220,15 @@ func TestDebPackageManagerExecError2(t *testing.T) {
 		t.Error("expected an error, but didn't get one")
 	}
 }
+
+func TestRunCommandKilledWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := runCommand(ctx, "sleep", "10"); err == nil {
+		t.Errorf("expected an error when the command is killed")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected the command to be killed when the context is done, took %v", d)
+	}
+}
@@ -0,0 +1,107 @@
+package check
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// the state of a listening socket in /proc/net/tcp
+const tcpListenState = "0A"
+
+// portOwner returns a description of the process that is listening on the
+// TCP port, such as "nginx (PID 1234)". It returns an empty string if the
+// process cannot be found.
+func portOwner(port int) string {
+	inodes := map[string]bool{}
+	for _, file := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		f, err := os.Open(file)
+		if err != nil {
+			continue
+		}
+		found, err := listeningSocketInodes(f, port)
+		f.Close()
+		if err != nil {
+			continue
+		}
+		for _, i := range found {
+			inodes[i] = true
+		}
+	}
+	if len(inodes) == 0 {
+		return ""
+	}
+	pid, name, ok := findSocketProcess("/proc", inodes)
+	if !ok {
+		return ""
+	}
+	if name == "" {
+		return fmt.Sprintf("PID %d", pid)
+	}
+	return fmt.Sprintf("%s (PID %d)", name, pid)
+}
+
+// listeningSocketInodes returns the inodes of the sockets that are listening
+// on the port. Each line of /proc/net/tcp has the local address in the hex
+// "IP:port" format in the second field, the state of the socket in the fourth
+// field, and the inode of the socket in the tenth field.
+func listeningSocketInodes(r io.Reader, port int) ([]string, error) {
+	inodes := []string{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) < 10 || f[3] != tcpListenState {
+			// Ignore the header, and the sockets that are not listening
+			continue
+		}
+		addr := strings.Split(f[1], ":")
+		if len(addr) != 2 {
+			continue
+		}
+		p, err := strconv.ParseInt(addr[1], 16, 32)
+		if err != nil || int(p) != port {
+			continue
+		}
+		inodes = append(inodes, f[9])
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("error reading sockets: %v", err)
+	}
+	return inodes, nil
+}
+
+// findSocketProcess returns the PID and the name of the process that has
+// a file descriptor for one of the socket inodes
+func findSocketProcess(procDir string, inodes map[string]bool) (int, string, bool) {
+	procs, err := ioutil.ReadDir(procDir)
+	if err != nil {
+		return 0, "", false
+	}
+	for _, p := range procs {
+		pid, err := strconv.Atoi(p.Name())
+		if err != nil {
+			// Not a process directory
+			continue
+		}
+		fds, err := ioutil.ReadDir(filepath.Join(procDir, p.Name(), "fd"))
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(procDir, p.Name(), "fd", fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			if inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+				comm, _ := ioutil.ReadFile(filepath.Join(procDir, p.Name(), "comm"))
+				return pid, strings.TrimSpace(string(comm)), true
+			}
+		}
+	}
+	return 0, "", false
+}
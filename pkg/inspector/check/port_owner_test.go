@@ -0,0 +1,73 @@
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const procNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 18712 1 ffff8800b8f0e000 100 0 0 10 0
+   1: 0100007F:0019 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 14539 1 ffff8800b8f0e800 100 0 0 10 0
+   2: 0A00000F:1F90 0A000010:D3A2 01 00000000:00000000 00:00000000 00000000  1000        0 20001 1 ffff8800b8f0f000 20 4 30 10 -1
+`
+
+func TestListeningSocketInodes(t *testing.T) {
+	inodes, err := listeningSocketInodes(strings.NewReader(procNetTCP), 8080)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The established connection on port 8080 is not a listening socket
+	if !reflect.DeepEqual(inodes, []string{"18712"}) {
+		t.Errorf("expected the inode of the listening socket, got %v", inodes)
+	}
+	inodes, err = listeningSocketInodes(strings.NewReader(procNetTCP), 6443)
+	if err != nil || len(inodes) != 0 {
+		t.Errorf("expected no inodes, got %v and %v", inodes, err)
+	}
+}
+
+func TestFindSocketProcess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	fds := filepath.Join(dir, "1234", "fd")
+	if err := os.MkdirAll(fds, 0755); err != nil {
+		t.Fatalf("error creating fd dir: %v", err)
+	}
+	if err := os.Symlink("/dev/null", filepath.Join(fds, "0")); err != nil {
+		t.Fatalf("error creating fd link: %v", err)
+	}
+	if err := os.Symlink("socket:[18712]", filepath.Join(fds, "3")); err != nil {
+		t.Fatalf("error creating fd link: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "1234", "comm"), []byte("nginx\n"), 0644); err != nil {
+		t.Fatalf("error writing comm file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "sys"), 0755); err != nil {
+		t.Fatalf("error creating dir: %v", err)
+	}
+
+	pid, name, ok := findSocketProcess(dir, map[string]bool{"18712": true})
+	if !ok || pid != 1234 || name != "nginx" {
+		t.Errorf("expected nginx with PID 1234, got %q with PID %d (found: %v)", name, pid, ok)
+	}
+	if _, _, ok := findSocketProcess(dir, map[string]bool{"14539": true}); ok {
+		t.Errorf("expected the socket not to be found")
+	}
+}
+
+func TestPackageInstallRemediation(t *testing.T) {
+	q := PackageQuery{Name: "kubelet", Version: "1.6.1-0"}
+	if r := packageInstallRemediation(CentOS, q); !strings.Contains(r, "sudo yum install -y kubelet-1.6.1-0") {
+		t.Errorf("expected a yum command, got %q", r)
+	}
+	if r := packageInstallRemediation(Ubuntu, q); !strings.Contains(r, "sudo apt-get install -y kubelet=1.6.1-0") {
+		t.Errorf("expected an apt-get command, got %q", r)
+	}
+}
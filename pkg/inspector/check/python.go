@@ -1,6 +1,7 @@
 package check
 
 import (
+	"context"
 	"errors"
 	"os/exec"
 	"strings"
@@ -13,7 +14,12 @@ type Python2Check struct {
 }
 
 func (c Python2Check) Check() (bool, error) {
-	cmd := exec.Command("python", "--version")
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext runs the check, and stops it when the context is done
+func (c Python2Check) CheckContext(ctx context.Context) (bool, error) {
+	cmd := exec.CommandContext(ctx, "python", "--version")
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return false, errors.New("Python 2 doesn't seem to be installed")
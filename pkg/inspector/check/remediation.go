@@ -0,0 +1,74 @@
+package check
+
+import (
+	"fmt"
+	"strings"
+)
+
+// packageInstallRemediation returns the command that installs the package
+// on the distribution
+func packageInstallRemediation(distro Distro, q PackageQuery) string {
+	switch distro {
+	case RHEL, CentOS:
+		return fmt.Sprintf("Install the package by running `sudo yum install -y %s-%s`. If the package is not available, configure the Kismatic package repository as described in docs/PACKAGES.md.", q.Name, q.Version)
+	case Ubuntu:
+		return fmt.Sprintf("Install the package by running `sudo apt-get update && sudo apt-get install -y %s=%s`. If the package is not available, configure the Kismatic package repository as described in docs/PACKAGES.md.", q.Name, q.Version)
+	default:
+		return fmt.Sprintf("Install version %s of the %s package.", q.Version, q.Name)
+	}
+}
+
+// executableRemediation returns the steps for finding and installing the
+// package that provides the executable on the distribution
+func executableRemediation(distro Distro, name string) string {
+	switch distro {
+	case RHEL, CentOS:
+		return fmt.Sprintf("Find the package that provides %q by running `yum provides '*bin/%s'`, and install it with `sudo yum install -y <package>`.", name, name)
+	case Ubuntu:
+		return fmt.Sprintf("Find the package that provides %q by running `apt-file search --regexp 'bin/%s$'`, and install it with `sudo apt-get install -y <package>`.", name, name)
+	default:
+		return fmt.Sprintf("Install %q and make sure that it is in the PATH.", name)
+	}
+}
+
+// firewallRemediation returns the steps for allowing the traffic to the port
+func firewallRemediation(ip string, port int) string {
+	return fmt.Sprintf("Make sure that TCP traffic to port %d on %s is allowed by the firewall of the node (such as firewalld or iptables), and by any security group or network ACL between the nodes.", port, ip)
+}
+
+// portInUseRemediation returns the steps for freeing the port
+func portInUseRemediation(port int, owner string) string {
+	if owner != "" {
+		return fmt.Sprintf("Port %d is in use by %s. Stop the process, or configure it to use a different port.", port, owner)
+	}
+	return fmt.Sprintf("Find the process that is using port %d by running `sudo ss -ltnp 'sport = :%d'`, and stop it or configure it to use a different port.", port, port)
+}
+
+// sysctlRemediation returns the steps for setting the kernel parameter
+func sysctlRemediation(parameter, value string) string {
+	return fmt.Sprintf("Set the kernel parameter by running `sudo sysctl -w '%s=%s'`, and add `%s = %s` to a file in /etc/sysctl.d so that it is set when the node reboots.", parameter, value, parameter, value)
+}
+
+// swapRemediation returns the steps for disabling swap
+func swapRemediation() string {
+	return "Disable swap by running `sudo swapoff -a`, and remove the swap entries from /etc/fstab so that it stays disabled when the node reboots."
+}
+
+// selinuxRemediation returns the steps for switching SELinux to one of the
+// allowed modes, preferring permissive mode as it does not require a reboot
+func selinuxRemediation(allowedModes []string) string {
+	modes := map[string]bool{}
+	for _, m := range allowedModes {
+		modes[strings.ToLower(m)] = true
+	}
+	switch {
+	case modes[SELinuxPermissive]:
+		return "Switch SELinux to permissive mode by running `sudo setenforce 0`, and set `SELINUX=permissive` in /etc/selinux/config so that the mode is kept when the node reboots."
+	case modes[SELinuxDisabled]:
+		return "Disable SELinux by setting `SELINUX=disabled` in /etc/selinux/config, and reboot the node."
+	case modes[SELinuxEnforcing]:
+		return "Enable SELinux by setting `SELINUX=enforcing` in /etc/selinux/config, and reboot the node."
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,134 @@
+package check
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// CPUCoresCheck returns true if the node has at least the minimum number
+// of CPU cores, as listed in /proc/cpuinfo
+type CPUCoresCheck struct {
+	MinimumCores int
+}
+
+// Check returns true if the node has enough CPU cores
+func (c CPUCoresCheck) Check() (bool, error) {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return false, fmt.Errorf("error reading /proc/cpuinfo: %v", err)
+	}
+	defer f.Close()
+	cores, err := countCPUCores(f)
+	if err != nil {
+		return false, err
+	}
+	return cores >= c.MinimumCores, nil
+}
+
+// counts the processor entries of the cpuinfo
+func countCPUCores(r io.Reader) (int, error) {
+	cores := 0
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.SplitN(s.Text(), ":", 2)
+		if len(fields) == 2 && strings.TrimSpace(fields[0]) == "processor" {
+			cores++
+		}
+	}
+	if err := s.Err(); err != nil {
+		return 0, fmt.Errorf("error reading cpuinfo: %v", err)
+	}
+	if cores == 0 {
+		return 0, errors.New("cpuinfo does not contain any processor")
+	}
+	return cores, nil
+}
+
+// MemoryCheck returns true if the total memory of the node, as listed in
+// /proc/meminfo, is at least the minimum. The total does not include the
+// memory reserved by the kernel, so it is lower than the installed memory.
+type MemoryCheck struct {
+	MinimumMemoryMB int
+}
+
+// Check returns true if the node has enough memory
+func (c MemoryCheck) Check() (bool, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return false, fmt.Errorf("error reading /proc/meminfo: %v", err)
+	}
+	defer f.Close()
+	memoryMB, err := readMemTotalMB(f)
+	if err != nil {
+		return false, err
+	}
+	return memoryMB >= c.MinimumMemoryMB, nil
+}
+
+// reads the MemTotal field of the meminfo, which is in kB
+func readMemTotalMB(r io.Reader) (int, error) {
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kb, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid MemTotal in meminfo %q: %v", s.Text(), err)
+		}
+		return kb / 1024, nil
+	}
+	if err := s.Err(); err != nil {
+		return 0, fmt.Errorf("error reading meminfo: %v", err)
+	}
+	return 0, errors.New("meminfo does not contain the MemTotal field")
+}
+
+// FreeSpaceCheck returns true if the filesystem of the path has at least the
+// minimum free space available to unprivileged users. When the path does not
+// exist yet, the filesystem of its closest existing parent is checked, as
+// that is where the path will be created.
+type FreeSpaceCheck struct {
+	Path               string
+	MinimumFreeSpaceMB int
+}
+
+// Check returns true if there is enough free space on the path
+func (c FreeSpaceCheck) Check() (bool, error) {
+	freeMB, err := availableSpaceMB(c.Path)
+	if err != nil {
+		return false, err
+	}
+	return freeMB >= uint64(c.MinimumFreeSpaceMB), nil
+}
+
+func availableSpaceMB(path string) (uint64, error) {
+	path = filepath.Clean(path)
+	for {
+		_, err := os.Stat(path)
+		if err == nil {
+			break
+		}
+		if !os.IsNotExist(err) {
+			return 0, fmt.Errorf("error reading %q: %v", path, err)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return 0, fmt.Errorf("no parent of %q exists", path)
+		}
+		path = parent
+	}
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("error reading the filesystem of %q: %v", path, err)
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize) / (1024 * 1024), nil
+}
@@ -0,0 +1,68 @@
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testCPUInfo = `processor	: 0
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) CPU E5-2676 v3 @ 2.40GHz
+flags		: fpu vme de pse tsc msr
+
+processor	: 1
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) CPU E5-2676 v3 @ 2.40GHz
+flags		: fpu vme de pse tsc msr
+`
+
+func TestCountCPUCores(t *testing.T) {
+	cores, err := countCPUCores(strings.NewReader(testCPUInfo))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cores != 2 {
+		t.Errorf("expected 2 cores, got %d", cores)
+	}
+	if _, err := countCPUCores(strings.NewReader("")); err == nil {
+		t.Errorf("expected an error when there are no processors")
+	}
+}
+
+func TestReadMemTotalMB(t *testing.T) {
+	meminfo := "MemTotal:        2048000 kB\nMemFree:          512000 kB\n"
+	memoryMB, err := readMemTotalMB(strings.NewReader(meminfo))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if memoryMB != 2000 {
+		t.Errorf("expected 2000 MB, got %d", memoryMB)
+	}
+	if _, err := readMemTotalMB(strings.NewReader("MemFree: 512000 kB\n")); err == nil {
+		t.Errorf("expected an error when MemTotal is missing")
+	}
+}
+
+func TestFreeSpaceCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "free-space-check")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	// the filesystem of the parent is checked when the path does not exist
+	c := FreeSpaceCheck{Path: filepath.Join(dir, "not", "created"), MinimumFreeSpaceMB: 1}
+	ok, err := c.Check()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Errorf("expected at least 1 MB of free space in %q", dir)
+	}
+	c.MinimumFreeSpaceMB = 1 << 30
+	if ok, _ := c.Check(); ok {
+		t.Errorf("expected the check to fail when a petabyte of free space is required")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -50,6 +51,44 @@ func (c *TCPPortClientCheck) Check() (bool, error) {
 	return true, nil
 }
 
+// Remediation returns the steps for allowing the traffic to the port
+func (c *TCPPortClientCheck) Remediation() string {
+	return firewallRemediation(c.IPAddress, c.PortNumber)
+}
+
+// TCPConnectCheck verifies that a TCP connection can be established to
+// the port of a peer node. Unlike TCPPortClientCheck, it does not expect
+// an echo server on the port, so the port can be used by any service.
+type TCPConnectCheck struct {
+	// IPAddress is the IP of the peer node
+	IPAddress string
+	// PortNumber is the target service port
+	PortNumber int
+	// Timeout is the maximum amount of time the check will
+	// wait when connecting to the peer before bailing out
+	Timeout time.Duration
+}
+
+// Check returns true if the TCP connection is established. Otherwise,
+// returns false and an error message
+func (c *TCPConnectCheck) Check() (bool, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(c.IPAddress, strconv.Itoa(c.PortNumber)), timeout)
+	if err != nil {
+		return false, fmt.Errorf("Port %d on host %q is unreachable. Error was: %v", c.PortNumber, c.IPAddress, err)
+	}
+	conn.Close()
+	return true, nil
+}
+
+// Remediation returns the steps for allowing the traffic to the port
+func (c *TCPConnectCheck) Remediation() string {
+	return firewallRemediation(c.IPAddress, c.PortNumber)
+}
+
 // TCPPortServerCheck ensures that the given port is free, and stands up a TCP server that can be used to
 // check TCP connectivity to the host using TCPPortClientCheck
 type TCPPortServerCheck struct {
@@ -106,3 +145,9 @@ func (c *TCPPortServerCheck) Close() error {
 	}
 	return errors.New("called close on a TCPPortServerCheck that is not started")
 }
+
+// Remediation returns the steps for freeing the port, including the process
+// that is using it when it can be found
+func (c *TCPPortServerCheck) Remediation() string {
+	return portInUseRemediation(c.PortNumber, portOwner(c.PortNumber))
+}
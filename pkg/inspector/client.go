@@ -40,12 +40,32 @@ func NewClient(targetNode string, targetNodeFacts []string) (*Client, error) {
 
 // ExecuteRules against the target inspector server
 func (c Client) ExecuteRules(rules []rule.Rule) ([]rule.Result, error) {
-	serverSideRules := getServerSideRules(rules)
-	d, err := json.Marshal(serverSideRules)
+	results, err := postRules(c.TargetNode, getServerSideRules(rules))
+	if err != nil {
+		return nil, err
+	}
+
+	// Execute the rules that should run from a remote node
+	clientSideRules := getClientSideRules(rules)
+	remoteResults, err := c.engine.ExecuteRules(clientSideRules, c.TargetNodeFacts)
+	if err != nil {
+		return nil, err
+	}
+	results = append(results, remoteResults...)
+
+	if err := closeChecks(c.TargetNode); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// postRules sends the rules to the inspector server for execution
+func postRules(targetNode string, rules []rule.Rule) ([]rule.Result, error) {
+	d, err := json.Marshal(rules)
 	if err != nil {
 		return nil, fmt.Errorf("error marshaling check request: %v", err)
 	}
-	resp, err := http.Post(fmt.Sprintf("http://%s%s", c.TargetNode, executeEndpoint), "application/json", bytes.NewReader(d))
+	resp, err := http.Post(fmt.Sprintf("http://%s%s", targetNode, executeEndpoint), "application/json", bytes.NewReader(d))
 	if err != nil {
 		return nil, fmt.Errorf("error posting request to server: %v", err)
 	}
@@ -68,22 +88,19 @@ func (c Client) ExecuteRules(rules []rule.Rule) ([]rule.Result, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error decoding server response: %v", err)
 	}
+	return results, nil
+}
 
-	// Execute the rules that should run from a remote node
-	clientSideRules := getClientSideRules(rules)
-	remoteResults, err := c.engine.ExecuteRules(clientSideRules, c.TargetNodeFacts)
-	if err != nil {
-		return nil, err
-	}
-	results = append(results, remoteResults...)
-
-	endpoint := fmt.Sprintf("http://%s%s", c.TargetNode, closeEndpoint)
-	resp, err = http.Get(endpoint)
+// closeChecks tells the inspector server to close the checks that are
+// still open, such as the TCP listeners
+func closeChecks(targetNode string) error {
+	endpoint := fmt.Sprintf("http://%s%s", targetNode, closeEndpoint)
+	resp, err := http.Get(endpoint)
 	if err != nil {
-		return nil, fmt.Errorf("GET request to %q failed. You might have to restart the inspector server. Error was: %v", endpoint, err)
+		return fmt.Errorf("GET request to %q failed. You might have to restart the inspector server. Error was: %v", endpoint, err)
 	}
-
-	return results, nil
+	resp.Body.Close()
+	return nil
 }
 
 func getServerSideRules(rules []rule.Rule) []rule.Rule {
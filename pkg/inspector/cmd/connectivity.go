@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/inspector"
+	"github.com/spf13/cobra"
+)
+
+type connectivityOpts struct {
+	outputType string
+	matrixFile string
+	nodes      string
+}
+
+var connectivityExample = `# Verify the connectivity between the nodes defined in the matrix
+kismatic-inspector connectivity -f connectivity-matrix.json
+
+# Verify the connectivity between the nodes, and ask for JSON output
+kismatic-inspector connectivity -f connectivity-matrix.json -o json
+
+# Only verify the connectivity between some of the nodes of the matrix
+kismatic-inspector connectivity -f connectivity-matrix.json --nodes etcd01,master01`
+
+// NewCmdConnectivity returns the "connectivity" command
+func NewCmdConnectivity(out io.Writer) *cobra.Command {
+	opts := connectivityOpts{}
+	cmd := &cobra.Command{
+		Use:     "connectivity",
+		Short:   "Verify the connectivity between nodes that are running the inspector server.",
+		Example: connectivityExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runConnectivity(out, opts)
+		},
+	}
+	cmd.Flags().StringVarP(&opts.outputType, "output", "o", "table", "set the result output type. Options are 'json', 'table'")
+	cmd.Flags().StringVarP(&opts.matrixFile, "file", "f", "", "the path to the JSON file that defines the connectivity matrix")
+	cmd.Flags().StringVar(&opts.nodes, "nodes", "", "comma-separated list of the hosts of the matrix to check. All nodes are checked when empty")
+	return cmd
+}
+
+func runConnectivity(out io.Writer, opts connectivityOpts) error {
+	if err := validateOutputType(opts.outputType); err != nil {
+		return err
+	}
+	if opts.matrixFile == "" {
+		return fmt.Errorf("--file is required")
+	}
+	m, err := inspector.ReadConnectivityMatrix(opts.matrixFile)
+	if err != nil {
+		return err
+	}
+	if opts.nodes != "" {
+		*m = m.WithNodes(strings.Split(opts.nodes, ","))
+	}
+	results, err := inspector.CheckConnectivity(*m)
+	if err != nil {
+		return fmt.Errorf("error checking the connectivity between the nodes: %v", err)
+	}
+	if err := printResults(out, results, opts.outputType); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if !r.Success {
+			return errors.New("connectivity checks failed")
+		}
+	}
+	return nil
+}
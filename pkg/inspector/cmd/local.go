@@ -69,6 +69,7 @@ func runLocal(out io.Writer, opts localOpts) error {
 	e := rule.Engine{
 		RuleCheckMapper: rule.DefaultCheckMapper{
 			PackageManager: pkgMgr,
+			Distro:         distro,
 		},
 	}
 	labels := append(roles, string(distro))
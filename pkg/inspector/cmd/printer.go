@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"text/tabwriter"
+	"time"
 
 	"github.com/apprenda/kismatic/pkg/inspector/rule"
 )
@@ -30,10 +31,23 @@ func printResultsAsJSON(out io.Writer, results []rule.Result) error {
 
 func printResultsAsTable(out io.Writer, results []rule.Result) error {
 	w := tabwriter.NewWriter(out, 1, 8, 4, '\t', 0)
-	fmt.Fprintf(w, "CHECK\tSUCCESS\tMSG\n")
+	fmt.Fprintf(w, "CHECK\tSUCCESS\tDURATION\tMSG\n")
 	for _, r := range results {
-		fmt.Fprintf(w, "%s\t%t\t%v\n", r.Name, r.Success, r.Error)
+		fmt.Fprintf(w, "%s\t%t\t%v\t%v\n", r.Name, r.Success, r.Duration.Round(time.Millisecond), r.Error)
 	}
 	w.Flush()
+	// The remediation steps are too long for a column, so they are
+	// listed after the table
+	header := false
+	for _, r := range results {
+		if r.Success || r.Remediation == "" {
+			continue
+		}
+		if !header {
+			fmt.Fprintf(out, "\nREMEDIATION\n")
+			header = true
+		}
+		fmt.Fprintf(out, "- %s: %s\n", r.Name, r.Remediation)
+	}
 	return nil
 }
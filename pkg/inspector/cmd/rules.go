@@ -27,10 +27,14 @@ func NewCmdRules(out io.Writer) *cobra.Command {
 
 // NewCmdDumpRules returns the "dump" command
 func NewCmdDumpRules(out io.Writer, file string) *cobra.Command {
-	var overwrite bool
+	var overwrite, optional bool
 	cmd := &cobra.Command{
 		Use:   "dump",
 		Short: "Dump the inspector rules to a file",
+		Long: `Dump the inspector rules to a file.
+
+The optional rules, which check that net.ipv4.ip_forward is enabled and that swap
+is disabled, are not run by default. Use --optional to include them in the file.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if _, err := os.Stat(file); err == nil && !overwrite {
 				return fmt.Errorf("%q already exists. Use --overwrite to overwrite it", file)
@@ -39,13 +43,18 @@ func NewCmdDumpRules(out io.Writer, file string) *cobra.Command {
 			if err != nil {
 				return fmt.Errorf("error creating %q: %v", file, err)
 			}
-			if err := rule.DumpDefaultRules(f); err != nil {
+			dump := rule.DumpDefaultRules
+			if optional {
+				dump = rule.DumpDefaultAndOptionalRules
+			}
+			if err := dump(f); err != nil {
 				return fmt.Errorf("error dumping rules: %v", err)
 			}
 			return nil
 		},
 	}
 	cmd.Flags().BoolVar(&overwrite, "overwrite", false, "overwrite the destination file if it exists")
+	cmd.Flags().BoolVar(&optional, "optional", false, "include the optional rules in the file")
 	return cmd
 }
 
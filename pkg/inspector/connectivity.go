@@ -0,0 +1,242 @@
+package inspector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	"github.com/apprenda/kismatic/pkg/inspector/rule"
+)
+
+// DefaultConnectivityTimeout is the timeout used when probing a peer
+// if the matrix does not define one
+const DefaultConnectivityTimeout = "5s"
+
+// ConnectivityMatrix describes the ports that must be reachable between
+// the nodes of a cluster
+type ConnectivityMatrix struct {
+	// Nodes that take part in the connectivity check
+	Nodes []ConnectivityNode
+	// Probes are the connections that must succeed between the nodes
+	Probes []ConnectivityProbe
+	// Timeout is the maximum amount of time to wait when connecting to a peer
+	Timeout string
+}
+
+// ConnectivityNode is a node that has an inspector server running
+type ConnectivityNode struct {
+	// Host is the hostname of the node
+	Host string
+	// IP is the address that peers use to connect to the node
+	IP string
+	// Inspector is the ip:port of the inspector server running on the node
+	Inspector string
+}
+
+// ConnectivityProbe is a connection from one node to a port on another node
+type ConnectivityProbe struct {
+	// From is the host of the node that opens the connection
+	From string
+	// To is the host of the node that accepts the connection
+	To string
+	// Port that must be accessible
+	Port int
+	// Service is a description of what uses the port
+	Service string
+}
+
+// Name returns the name of the probe, as it is reported in the results
+func (p ConnectivityProbe) Name() string {
+	if p.Service == "" {
+		return fmt.Sprintf("Port %d on %s accessible from %s", p.Port, p.To, p.From)
+	}
+	return fmt.Sprintf("Port %d (%s) on %s accessible from %s", p.Port, p.Service, p.To, p.From)
+}
+
+// ReadConnectivityMatrix reads the JSON encoded matrix from the file
+func ReadConnectivityMatrix(file string) (*ConnectivityMatrix, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading connectivity matrix from %q: %v", file, err)
+	}
+	m := &ConnectivityMatrix{}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("error unmarshaling connectivity matrix: %v", err)
+	}
+	return m, nil
+}
+
+// Validate the matrix
+func (m ConnectivityMatrix) Validate() []error {
+	errs := []error{}
+	nodes := map[string]bool{}
+	for _, n := range m.Nodes {
+		if n.Host == "" {
+			errs = append(errs, fmt.Errorf("Node with IP %q has an empty host", n.IP))
+			continue
+		}
+		if nodes[n.Host] {
+			errs = append(errs, fmt.Errorf("Node %q is defined more than once", n.Host))
+		}
+		nodes[n.Host] = true
+		if n.Inspector == "" {
+			errs = append(errs, fmt.Errorf("Node %q does not have an inspector address", n.Host))
+		}
+	}
+	for _, p := range m.Probes {
+		if !nodes[p.From] {
+			errs = append(errs, fmt.Errorf("Probe %q is from unknown node %q", p.Name(), p.From))
+		}
+		if !nodes[p.To] {
+			errs = append(errs, fmt.Errorf("Probe %q is to unknown node %q", p.Name(), p.To))
+		}
+		// The IP of the target node is validated by the rule
+		r := rule.PeerTCPPortAccessible{PeerIP: "127.0.0.1", Port: p.Port, Timeout: m.timeout()}
+		for _, err := range r.Validate() {
+			errs = append(errs, fmt.Errorf("Probe %q is invalid: %v", p.Name(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// WithNodes returns the matrix restricted to the nodes with the given hosts,
+// and to the probes between them
+func (m ConnectivityMatrix) WithNodes(hosts []string) ConnectivityMatrix {
+	keep := map[string]bool{}
+	for _, h := range hosts {
+		keep[h] = true
+	}
+	r := ConnectivityMatrix{
+		Nodes:   []ConnectivityNode{},
+		Probes:  []ConnectivityProbe{},
+		Timeout: m.Timeout,
+	}
+	for _, n := range m.Nodes {
+		if keep[n.Host] {
+			r.Nodes = append(r.Nodes, n)
+		}
+	}
+	for _, p := range m.Probes {
+		if keep[p.From] && keep[p.To] {
+			r.Probes = append(r.Probes, p)
+		}
+	}
+	return r
+}
+
+func (m ConnectivityMatrix) timeout() string {
+	if m.Timeout == "" {
+		return DefaultConnectivityTimeout
+	}
+	return m.Timeout
+}
+
+// CheckConnectivity verifies the connectivity matrix. A listener is started
+// on each port that is probed, and then each node is told to connect to the
+// ports of its peers. A result is returned for every probe of the matrix.
+func CheckConnectivity(m ConnectivityMatrix) ([]rule.Result, error) {
+	if errs := m.Validate(); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid connectivity matrix: %v", errs)
+	}
+	nodes := map[string]ConnectivityNode{}
+	for _, n := range m.Nodes {
+		nodes[n.Host] = n
+	}
+	// The listeners and the connections are kept open until the checks are
+	// closed, so make sure the checks are closed on all nodes when we are done
+	defer func() {
+		for _, n := range m.Nodes {
+			closeChecks(n.Inspector)
+		}
+	}()
+
+	// Start listening on the probed ports. The port might be in use by a
+	// service that is already running on the node, in which case the
+	// listener fails, but the probe can still connect to the service.
+	listeners := map[string][]rule.Rule{}
+	seen := map[string]map[int]bool{}
+	for _, p := range m.Probes {
+		if seen[p.To] == nil {
+			seen[p.To] = map[int]bool{}
+		}
+		if seen[p.To][p.Port] {
+			continue
+		}
+		seen[p.To][p.Port] = true
+		r := rule.TCPPortAvailable{
+			Meta: rule.Meta{Kind: "TCPPortAvailable"},
+			Port: p.Port,
+		}
+		listeners[p.To] = append(listeners[p.To], r)
+	}
+	forEachNode(listeners, func(host string, rules []rule.Rule) {
+		postRules(nodes[host].Inspector, rules)
+	})
+
+	// Tell each node to connect to its peers
+	probes := map[string][]rule.Rule{}
+	probesFrom := map[string][]ConnectivityProbe{}
+	for _, p := range m.Probes {
+		r := rule.PeerTCPPortAccessible{
+			Meta:    rule.Meta{Kind: "PeerTCPPortAccessible"},
+			PeerIP:  nodes[p.To].IP,
+			Port:    p.Port,
+			Timeout: m.timeout(),
+		}
+		probes[p.From] = append(probes[p.From], r)
+		probesFrom[p.From] = append(probesFrom[p.From], p)
+	}
+	var mu sync.Mutex
+	resultsFrom := map[string][]rule.Result{}
+	forEachNode(probes, func(host string, rules []rule.Rule) {
+		results, err := postRules(nodes[host].Inspector, rules)
+		if err == nil && len(results) != len(rules) {
+			err = fmt.Errorf("expected %d results, but got %d", len(rules), len(results))
+		}
+		res := make([]rule.Result, len(rules))
+		for i, p := range probesFrom[host] {
+			res[i] = rule.Result{Name: p.Name()}
+			if err != nil {
+				res[i].Error = fmt.Sprintf("error running probe on %s: %v", host, err)
+				continue
+			}
+			res[i].Success = results[i].Success
+			res[i].Error = results[i].Error
+			res[i].Remediation = results[i].Remediation
+			res[i].Duration = results[i].Duration
+		}
+		mu.Lock()
+		resultsFrom[host] = res
+		mu.Unlock()
+	})
+
+	// Report the results in a stable order
+	hosts := make([]string, 0, len(resultsFrom))
+	for h := range resultsFrom {
+		hosts = append(hosts, h)
+	}
+	sort.Strings(hosts)
+	results := []rule.Result{}
+	for _, h := range hosts {
+		results = append(results, resultsFrom[h]...)
+	}
+	return results, nil
+}
+
+// runs the function for each node concurrently, and waits for all of them
+func forEachNode(rules map[string][]rule.Rule, f func(host string, rules []rule.Rule)) {
+	var wg sync.WaitGroup
+	for host, r := range rules {
+		wg.Add(1)
+		go func(host string, r []rule.Rule) {
+			defer wg.Done()
+			f(host, r)
+		}(host, r)
+	}
+	wg.Wait()
+}
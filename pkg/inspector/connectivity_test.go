@@ -0,0 +1,37 @@
+package inspector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConnectivityMatrixWithNodes(t *testing.T) {
+	m := ConnectivityMatrix{
+		Nodes: []ConnectivityNode{
+			{Host: "etcd01", IP: "10.0.0.1", Inspector: "10.0.0.1:8888"},
+			{Host: "master01", IP: "10.0.0.2", Inspector: "10.0.0.2:8888"},
+			{Host: "worker01", IP: "10.0.0.3", Inspector: "10.0.0.3:8888"},
+		},
+		Probes: []ConnectivityProbe{
+			{From: "master01", To: "etcd01", Port: 2379},
+			{From: "worker01", To: "master01", Port: 6443},
+			{From: "master01", To: "worker01", Port: 10250},
+		},
+		Timeout: "1s",
+	}
+	r := m.WithNodes([]string{"master01", "etcd01"})
+	expectedNodes := []ConnectivityNode{m.Nodes[0], m.Nodes[1]}
+	if !reflect.DeepEqual(r.Nodes, expectedNodes) {
+		t.Errorf("expected nodes %v, got %v", expectedNodes, r.Nodes)
+	}
+	expectedProbes := []ConnectivityProbe{m.Probes[0]}
+	if !reflect.DeepEqual(r.Probes, expectedProbes) {
+		t.Errorf("expected probes %v, got %v", expectedProbes, r.Probes)
+	}
+	if r.Timeout != m.Timeout {
+		t.Errorf("expected timeout %q, got %q", m.Timeout, r.Timeout)
+	}
+	if errs := r.Validate(); len(errs) != 0 {
+		t.Errorf("expected a valid matrix, got %v", errs)
+	}
+}
@@ -17,6 +17,8 @@ type CheckMapper interface {
 // supported rules and checks.
 type DefaultCheckMapper struct {
 	PackageManager check.PackageManager
+	// Distro of the node where the checks run
+	Distro check.Distro
 	// IP of the remote node that is being inspected when in client mode
 	TargetNodeIP string
 }
@@ -30,9 +32,9 @@ func (m DefaultCheckMapper) GetCheckForRule(rule Rule) (check.Check, error) {
 		return nil, fmt.Errorf("Rule of type %T is not supported", r)
 	case PackageAvailable:
 		pkgQuery := check.PackageQuery{Name: r.PackageName, Version: r.PackageVersion}
-		c = &check.PackageAvailableCheck{PackageQuery: pkgQuery, PackageManager: m.PackageManager}
+		c = &check.PackageAvailableCheck{PackageQuery: pkgQuery, PackageManager: m.PackageManager, Distro: m.Distro}
 	case ExecutableInPath:
-		c = &check.ExecutableInPathCheck{Name: r.Executable}
+		c = &check.ExecutableInPathCheck{Name: r.Executable, Distro: m.Distro}
 	case FileContentMatches:
 		c = check.FileContentCheck{File: r.File, SearchString: r.ContentRegex}
 	case TCPPortAvailable:
@@ -43,8 +45,30 @@ func (m DefaultCheckMapper) GetCheckForRule(rule Rule) (check.Check, error) {
 			return nil, fmt.Errorf("invalid value %q provided for the timeout field of the TCPPortAccessible rule: %v", r.Timeout, err)
 		}
 		c = &check.TCPPortClientCheck{PortNumber: r.Port, IPAddress: m.TargetNodeIP, Timeout: timeout}
+	case PeerTCPPortAccessible:
+		timeout, err := time.ParseDuration(r.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q provided for the timeout field of the PeerTCPPortAccessible rule: %v", r.Timeout, err)
+		}
+		c = &check.TCPConnectCheck{PortNumber: r.Port, IPAddress: r.PeerIP, Timeout: timeout}
 	case Python2Version:
 		c = &check.Python2Check{SupportedVersions: r.SupportedVersions}
+	case MinimumCPUCores:
+		c = check.CPUCoresCheck{MinimumCores: r.Cores}
+	case MinimumMemoryMB:
+		c = check.MemoryCheck{MinimumMemoryMB: r.MemoryMB}
+	case FreeSpaceOnPath:
+		c = check.FreeSpaceCheck{Path: r.Path, MinimumFreeSpaceMB: r.FreeSpaceMB}
+	case KernelModule:
+		c = check.KernelModuleCheck{Module: r.Module}
+	case SysctlValue:
+		c = check.SysctlCheck{Parameter: r.Parameter, Value: r.Value}
+	case MinimumKernelVersion:
+		c = check.KernelVersionCheck{MinimumVersion: r.KernelVersion}
+	case SwapDisabled:
+		c = check.SwapDisabledCheck{}
+	case SELinuxMode:
+		c = check.SELinuxModeCheck{AllowedModes: r.Modes}
 	}
 	return c, nil
 }
@@ -43,6 +43,16 @@ type catchAllRule struct {
 	ContentRegex      string   `yaml:"contentRegex"`
 	Timeout           string   `yaml:"timeout"`
 	SupportedVersions []string `yaml:"supportedVersions"`
+	Cores             int      `yaml:"cores"`
+	MemoryMB          int      `yaml:"memoryMB"`
+	Path              string   `yaml:"path"`
+	FreeSpaceMB       int      `yaml:"freeSpaceMB"`
+	Module            string   `yaml:"module"`
+	Parameter         string   `yaml:"parameter"`
+	Value             string   `yaml:"value"`
+	KernelVersion     string   `yaml:"kernelVersion"`
+	Modes             []string `yaml:"modes"`
+	PeerIP            string   `yaml:"peerIP"`
 }
 
 // UnmarshalRulesYAML unmarshals the data into a list of rules
@@ -110,6 +120,14 @@ func buildRule(catchAll catchAllRule) (Rule, error) {
 		}
 		r.Meta = meta
 		return r, nil
+	case "peertcpportaccessible":
+		r := PeerTCPPortAccessible{
+			PeerIP:  catchAll.PeerIP,
+			Port:    catchAll.Port,
+			Timeout: catchAll.Timeout,
+		}
+		r.Meta = meta
+		return r, nil
 	case "filecontentmatches":
 		r := FileContentMatches{
 			File:         catchAll.File,
@@ -123,5 +141,53 @@ func buildRule(catchAll catchAllRule) (Rule, error) {
 		}
 		r.Meta = meta
 		return r, nil
+	case "minimumcpucores":
+		r := MinimumCPUCores{
+			Cores: catchAll.Cores,
+		}
+		r.Meta = meta
+		return r, nil
+	case "minimummemorymb":
+		r := MinimumMemoryMB{
+			MemoryMB: catchAll.MemoryMB,
+		}
+		r.Meta = meta
+		return r, nil
+	case "freespaceonpath":
+		r := FreeSpaceOnPath{
+			Path:        catchAll.Path,
+			FreeSpaceMB: catchAll.FreeSpaceMB,
+		}
+		r.Meta = meta
+		return r, nil
+	case "kernelmodule":
+		r := KernelModule{
+			Module: catchAll.Module,
+		}
+		r.Meta = meta
+		return r, nil
+	case "sysctlvalue":
+		r := SysctlValue{
+			Parameter: catchAll.Parameter,
+			Value:     catchAll.Value,
+		}
+		r.Meta = meta
+		return r, nil
+	case "minimumkernelversion":
+		r := MinimumKernelVersion{
+			KernelVersion: catchAll.KernelVersion,
+		}
+		r.Meta = meta
+		return r, nil
+	case "swapdisabled":
+		r := SwapDisabled{}
+		r.Meta = meta
+		return r, nil
+	case "selinuxmode":
+		r := SELinuxMode{
+			Modes: catchAll.Modes,
+		}
+		r.Meta = meta
+		return r, nil
 	}
 }
@@ -1,56 +1,188 @@
 package rule
 
 import (
+	"context"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/apprenda/kismatic/pkg/inspector/check"
 )
 
+const (
+	// DefaultConcurrency is the number of rules that the engine runs at the
+	// same time when the concurrency is not set
+	DefaultConcurrency = 10
+	// DefaultRuleTimeout is the amount of time the engine waits for a rule
+	// when the timeout is not set
+	DefaultRuleTimeout = 2 * time.Minute
+)
+
 // The Engine executes rules and reports the results
 type Engine struct {
 	RuleCheckMapper CheckMapper
-	mu              sync.Mutex
-	closableChecks  []check.ClosableCheck
+	// Concurrency is the maximum number of rules that run at the same time
+	Concurrency int
+	// RuleTimeout is the maximum amount of time the engine waits for a rule
+	RuleTimeout    time.Duration
+	mu             sync.Mutex
+	closableChecks []check.ClosableCheck
+	// locks for the resources that exclusive checks need
+	resourcesMu sync.Mutex
+	resources   map[string]chan struct{}
 }
 
 // ExecuteRules runs the rules that should be executed according to the facts,
 // and returns a collection of results. The number of results is not guaranteed
-// to equal the number of rules.
+// to equal the number of rules. The rules run concurrently, but the results
+// are in the same order as the rules.
 func (e *Engine) ExecuteRules(rules []Rule, facts []string) ([]Result, error) {
-	results := []Result{}
+	// Map all the rules to checks before running any of them, so that
+	// nothing is left running if a rule is not supported
+	toRun := []Rule{}
+	checks := []check.Check{}
 	for _, rule := range rules {
 		if !shouldExecuteRule(rule, facts) {
 			continue
 		}
-
-		// Map the rule to a check
 		c, err := e.RuleCheckMapper.GetCheckForRule(rule)
 		if err != nil {
 			return nil, err
 		}
+		toRun = append(toRun, rule)
+		checks = append(checks, c)
+	}
+
+	concurrency := e.Concurrency
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+	results := make([]Result, len(toRun))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range toRun {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = e.executeCheck(toRun[i], checks[i], sem)
+		}(i)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// executeCheck runs the check of the rule once there is room for it in the
+// semaphore, and gives up on it if it does not complete within the rule timeout
+func (e *Engine) executeCheck(rule Rule, c check.Check, sem chan struct{}) Result {
+	timeout := e.RuleTimeout
+	if timeout <= 0 {
+		timeout = DefaultRuleTimeout
+	}
+	// The timeout covers the time spent waiting to run, such that a check
+	// that hangs does not hold up the checks that are waiting for it
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	res := Result{Name: rule.Name()}
 
-		// Run the check and report result
-		ok, err := c.Check()
-		res := Result{
-			Name:        rule.Name(),
-			Success:     ok,
-			Remediation: "",
+	// Checks that need a resource for themselves wait for it before they
+	// take a slot, so that they do not hold up the other checks. The resource
+	// is released when the check completes, even if the engine gave up on it.
+	var resource chan struct{}
+	if ex, ok := c.(check.ExclusiveCheck); ok {
+		resource = e.resourceLock(ex.ExclusiveResource())
+		select {
+		case resource <- struct{}{}:
+		case <-ctx.Done():
+			res.Error = fmt.Sprintf("the check did not start within %v, because %q was in use by another check", timeout, ex.ExclusiveResource())
+			res.Duration = time.Since(start)
+			return res
 		}
-		if err != nil {
-			res.Error = err.Error()
+	}
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		if resource != nil {
+			<-resource
 		}
+		res.Error = fmt.Sprintf("the check did not start within %v", timeout)
+		res.Duration = time.Since(start)
+		return res
+	}
+	defer func() { <-sem }()
 
-		// We update the closables as we go to avoid leaking closables
-		// in the event where we have to return an error from within the loop.
-		if closeable, ok := c.(check.ClosableCheck); ok && res.Success {
-			e.mu.Lock()
-			e.closableChecks = append(e.closableChecks, closeable)
-			e.mu.Unlock()
+	var mu sync.Mutex
+	var ok, finished, abandoned bool
+	var err error
+	done := make(chan struct{})
+	go func() {
+		if resource != nil {
+			defer func() { <-resource }()
 		}
+		// Checks that run commands are stopped when the timeout fires
+		var checkOK bool
+		var checkErr error
+		if cc, isContextCheck := c.(check.ContextCheck); isContextCheck {
+			checkOK, checkErr = cc.CheckContext(ctx)
+		} else {
+			checkOK, checkErr = c.Check()
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if abandoned {
+			// Nobody is waiting for the result, so close the check if it
+			// left something running
+			if closeable, isClosable := c.(check.ClosableCheck); isClosable && checkOK {
+				closeable.Close()
+			}
+			return
+		}
+		ok, err, finished = checkOK, checkErr, true
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	mu.Lock()
+	// The check might have completed right after the timeout
+	abandoned = !finished
+	mu.Unlock()
 
-		results = append(results, res)
+	res.Success = ok
+	if err != nil {
+		res.Error = err.Error()
 	}
-	return results, nil
+	if abandoned {
+		res.Error = fmt.Sprintf("the check did not complete within %v", timeout)
+	}
+	res.Duration = time.Since(start)
+
+	if r, isRemediable := c.(check.RemediableCheck); isRemediable && !res.Success {
+		res.Remediation = r.Remediation()
+	}
+	// Keep track of the checks that left something running, such that
+	// they are closed when the checks are closed
+	if closeable, ok := c.(check.ClosableCheck); ok && res.Success {
+		e.mu.Lock()
+		e.closableChecks = append(e.closableChecks, closeable)
+		e.mu.Unlock()
+	}
+	return res
+}
+
+// returns the lock of the resource. The lock is a channel with room for one
+// check, such that waiting for it can be given up when the timeout fires.
+func (e *Engine) resourceLock(resource string) chan struct{} {
+	e.resourcesMu.Lock()
+	defer e.resourcesMu.Unlock()
+	if e.resources == nil {
+		e.resources = map[string]chan struct{}{}
+	}
+	if e.resources[resource] == nil {
+		e.resources[resource] = make(chan struct{}, 1)
+	}
+	return e.resources[resource]
 }
 
 // CloseChecks that need to be closed
@@ -1,9 +1,14 @@
 package rule
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/apprenda/kismatic/pkg/inspector/check"
 )
@@ -155,6 +160,10 @@ func TestEngine(t *testing.T) {
 			continue
 		}
 
+		// The duration of the rules is not deterministic
+		for i := range result {
+			result[i].Duration = 0
+		}
 		if !reflect.DeepEqual(test.expectedResults, result) {
 			t.Errorf("expected %+v, but got %+v", test.expectedResults, result)
 		}
@@ -218,3 +227,272 @@ func TestEngineClosableCheckFail(t *testing.T) {
 		t.Errorf("The check failed, and close was called on it")
 	}
 }
+
+type fakeRemediableCheck struct {
+	ok bool
+}
+
+func (c fakeRemediableCheck) Check() (bool, error) { return c.ok, nil }
+
+func (c fakeRemediableCheck) Remediation() string { return "install the package" }
+
+func TestEngineRemediableCheck(t *testing.T) {
+	e := Engine{
+		RuleCheckMapper: fakeRuleCheckMapper{check: fakeRemediableCheck{ok: false}},
+	}
+	results, err := e.ExecuteRules([]Rule{fakeRule{name: "FailRule"}}, []string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Remediation != "install the package" {
+		t.Errorf("expected the remediation of the failed check, got %+v", results)
+	}
+
+	e.RuleCheckMapper = fakeRuleCheckMapper{check: fakeRemediableCheck{ok: true}}
+	results, err = e.ExecuteRules([]Rule{fakeRule{name: "SuccessRule"}}, []string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Remediation != "" {
+		t.Errorf("expected no remediation for a successful check, got %+v", results)
+	}
+}
+
+// fakeSlowCheck records the number of checks that are running at the same time
+type fakeSlowCheck struct {
+	delay      time.Duration
+	ok         bool
+	resource   string
+	mu         *sync.Mutex
+	running    *int
+	maxRunning *int
+	closed     *bool
+}
+
+func (c fakeSlowCheck) Check() (bool, error) {
+	c.mu.Lock()
+	*c.running++
+	if *c.running > *c.maxRunning {
+		*c.maxRunning = *c.running
+	}
+	c.mu.Unlock()
+	time.Sleep(c.delay)
+	c.mu.Lock()
+	*c.running--
+	c.mu.Unlock()
+	return c.ok, nil
+}
+
+func (c fakeSlowCheck) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.closed = true
+	return nil
+}
+
+type fakeExclusiveCheck struct {
+	fakeSlowCheck
+}
+
+func (c fakeExclusiveCheck) ExclusiveResource() string { return c.resource }
+
+type fakeSlowCheckMapper struct {
+	checks map[string]check.Check
+}
+
+func (m fakeSlowCheckMapper) GetCheckForRule(r Rule) (check.Check, error) {
+	return m.checks[r.Name()], nil
+}
+
+func newFakeSlowChecks(count int, delay time.Duration, exclusive bool) ([]Rule, fakeSlowCheckMapper, *int, *sync.Mutex) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	rules := []Rule{}
+	mapper := fakeSlowCheckMapper{checks: map[string]check.Check{}}
+	for i := 0; i < count; i++ {
+		name := fmt.Sprintf("rule%02d", i)
+		var closed bool
+		c := fakeSlowCheck{delay: delay, ok: true, resource: "pkg", mu: &mu, running: &running, maxRunning: &maxRunning, closed: &closed}
+		rules = append(rules, fakeRule{name: name})
+		if exclusive {
+			mapper.checks[name] = fakeExclusiveCheck{c}
+		} else {
+			mapper.checks[name] = c
+		}
+	}
+	return rules, mapper, &maxRunning, &mu
+}
+
+func TestEngineConcurrency(t *testing.T) {
+	rules, mapper, maxRunning, mu := newFakeSlowChecks(12, 20*time.Millisecond, false)
+	e := Engine{RuleCheckMapper: mapper, Concurrency: 4}
+	results, err := e.ExecuteRules(rules, []string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != len(rules) {
+		t.Fatalf("expected %d results, got %d", len(rules), len(results))
+	}
+	for i, r := range results {
+		if r.Name != rules[i].Name() {
+			t.Errorf("expected the results in the order of the rules, got %q at %d", r.Name, i)
+		}
+		if !r.Success || r.Duration < 20*time.Millisecond {
+			t.Errorf("expected a successful result with the duration of the check, got %+v", r)
+		}
+	}
+	mu.Lock()
+	if *maxRunning > 4 {
+		t.Errorf("expected at most 4 checks running at the same time, got %d", *maxRunning)
+	}
+	if *maxRunning < 2 {
+		t.Errorf("expected the checks to run concurrently, got %d running at the same time", *maxRunning)
+	}
+	mu.Unlock()
+	// All the checks succeeded, so they must be closed when the checks are closed
+	e.CloseChecks()
+	mu.Lock()
+	defer mu.Unlock()
+	for _, c := range mapper.checks {
+		if !*c.(fakeSlowCheck).closed {
+			t.Errorf("expected the check to be closed")
+		}
+	}
+}
+
+func TestEngineExclusiveChecks(t *testing.T) {
+	rules, mapper, maxRunning, mu := newFakeSlowChecks(4, 10*time.Millisecond, true)
+	e := Engine{RuleCheckMapper: mapper, Concurrency: 4}
+	if _, err := e.ExecuteRules(rules, []string{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if *maxRunning != 1 {
+		t.Errorf("expected the checks that use the same resource to run one at a time, got %d", *maxRunning)
+	}
+}
+
+func TestEngineRuleTimeout(t *testing.T) {
+	rules, mapper, _, mu := newFakeSlowChecks(2, 100*time.Millisecond, false)
+	// The first check completes in time, the second one does not
+	fast := mapper.checks["rule00"].(fakeSlowCheck)
+	fast.delay = 0
+	mapper.checks["rule00"] = fast
+	e := Engine{RuleCheckMapper: mapper, RuleTimeout: 20 * time.Millisecond}
+	results, err := e.ExecuteRules(rules, []string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !results[0].Success || results[0].Error != "" {
+		t.Errorf("expected the first check to succeed, got %+v", results[0])
+	}
+	if results[1].Success || results[1].Error == "" {
+		t.Errorf("expected the second check to time out, got %+v", results[1])
+	}
+	if results[1].Duration > 90*time.Millisecond {
+		t.Errorf("expected the engine to give up on the check after the timeout, took %v", results[1].Duration)
+	}
+
+	// The check that timed out is not tracked by the engine, and it is
+	// closed once it completes
+	e.CloseChecks()
+	slow := mapper.checks["rule01"].(fakeSlowCheck)
+	time.Sleep(150 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if !*slow.closed {
+		t.Errorf("expected the check that timed out to be closed when it completed")
+	}
+}
+
+// fakeHungCheck uses the package manager and does not complete until it is
+// released, whether or not the engine gave up on it
+type fakeHungCheck struct {
+	release chan struct{}
+}
+
+func (c fakeHungCheck) Check() (bool, error) {
+	<-c.release
+	return true, nil
+}
+
+func (c fakeHungCheck) ExclusiveResource() string { return "pkg" }
+
+func TestEngineHungExclusiveCheck(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	// Both checks hang, so whichever gets the package manager first holds
+	// it for good, and the other one must give up waiting for it
+	mapper := fakeSlowCheckMapper{checks: map[string]check.Check{
+		"first":  fakeHungCheck{release: release},
+		"second": fakeHungCheck{release: release},
+	}}
+	rules := []Rule{fakeRule{name: "first"}, fakeRule{name: "second"}}
+	e := Engine{RuleCheckMapper: mapper, RuleTimeout: 50 * time.Millisecond}
+
+	done := make(chan []Result)
+	go func() {
+		results, _ := e.ExecuteRules(rules, []string{})
+		done <- results
+	}()
+	var results []Result
+	select {
+	case results = <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the hung check not to block the other check past the timeout")
+	}
+	waited := 0
+	for _, r := range results {
+		if r.Success || r.Error == "" {
+			t.Errorf("expected the check to time out, got %+v", r)
+		}
+		if r.Duration > 500*time.Millisecond {
+			t.Errorf("expected the check to give up after the timeout, took %v", r.Duration)
+		}
+		if strings.Contains(r.Error, "did not start") {
+			waited++
+		}
+	}
+	if waited != 1 {
+		t.Errorf("expected one of the checks to time out waiting for the resource, got %+v", results)
+	}
+}
+
+// fakeContextCheck runs until the context is done
+type fakeContextCheck struct {
+	stopped chan error
+}
+
+func (c fakeContextCheck) Check() (bool, error) {
+	return c.CheckContext(context.Background())
+}
+
+func (c fakeContextCheck) CheckContext(ctx context.Context) (bool, error) {
+	<-ctx.Done()
+	c.stopped <- ctx.Err()
+	return false, ctx.Err()
+}
+
+func TestEngineContextCheckStoppedOnTimeout(t *testing.T) {
+	stopped := make(chan error, 1)
+	mapper := fakeSlowCheckMapper{checks: map[string]check.Check{
+		"rule": fakeContextCheck{stopped: stopped},
+	}}
+	e := Engine{RuleCheckMapper: mapper, RuleTimeout: 20 * time.Millisecond}
+	results, err := e.ExecuteRules([]Rule{fakeRule{name: "rule"}}, []string{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Success || results[0].Error == "" {
+		t.Errorf("expected the check to time out, got %+v", results[0])
+	}
+	select {
+	case err := <-stopped:
+		if err != context.DeadlineExceeded {
+			t.Errorf("expected the check to be stopped by the timeout, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected the check to be stopped when the timeout fired")
+	}
+}
@@ -0,0 +1,141 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/inspector/check"
+)
+
+// KernelModule is a rule that ensures that the kernel module is loaded, or
+// that it can be loaded
+type KernelModule struct {
+	Meta
+	Module string
+}
+
+// Name is the name of the rule
+func (k KernelModule) Name() string {
+	return fmt.Sprintf("Kernel Module Available: %s", k.Module)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (k KernelModule) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (k KernelModule) Validate() []error {
+	if k.Module == "" {
+		return []error{errors.New("Module cannot be empty")}
+	}
+	if strings.ContainsAny(k.Module, " /") {
+		return []error{fmt.Errorf("Module %q is not a valid module name", k.Module)}
+	}
+	return nil
+}
+
+// SysctlValue is a rule that ensures that the kernel parameter has the value
+type SysctlValue struct {
+	Meta
+	Parameter string
+	Value     string
+}
+
+// Name is the name of the rule
+func (s SysctlValue) Name() string {
+	return fmt.Sprintf("Sysctl %s: %s", s.Parameter, s.Value)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (s SysctlValue) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (s SysctlValue) Validate() []error {
+	errs := []error{}
+	if s.Parameter == "" {
+		errs = append(errs, errors.New("Parameter cannot be empty"))
+	}
+	if strings.ContainsAny(s.Parameter, " /") {
+		errs = append(errs, fmt.Errorf("Parameter %q is not a valid kernel parameter", s.Parameter))
+	}
+	if strings.TrimSpace(s.Value) == "" {
+		errs = append(errs, errors.New("Value cannot be empty"))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// MinimumKernelVersion is a rule that ensures that the version of the
+// running kernel is at least the given version
+type MinimumKernelVersion struct {
+	Meta
+	KernelVersion string
+}
+
+// Name is the name of the rule
+func (m MinimumKernelVersion) Name() string {
+	return fmt.Sprintf("Minimum Kernel Version: %s", m.KernelVersion)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (m MinimumKernelVersion) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (m MinimumKernelVersion) Validate() []error {
+	if len(check.ParseKernelVersion(m.KernelVersion)) == 0 {
+		return []error{fmt.Errorf("KernelVersion %q is not a valid kernel version", m.KernelVersion)}
+	}
+	return nil
+}
+
+// SwapDisabled is a rule that ensures that the node does not use swap space
+type SwapDisabled struct {
+	Meta
+}
+
+// Name is the name of the rule
+func (s SwapDisabled) Name() string {
+	return "Swap Disabled"
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (s SwapDisabled) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (s SwapDisabled) Validate() []error { return nil }
+
+// SELinuxMode is a rule that ensures that SELinux is in one of the given
+// modes. The modes are "enforcing", "permissive" and "disabled".
+type SELinuxMode struct {
+	Meta
+	Modes []string
+}
+
+// Name is the name of the rule
+func (s SELinuxMode) Name() string {
+	return fmt.Sprintf("SELinux Mode in %v", s.Modes)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (s SELinuxMode) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (s SELinuxMode) Validate() []error {
+	if len(s.Modes) == 0 {
+		return []error{errors.New("List of SELinux modes is empty")}
+	}
+	errs := []error{}
+	for _, m := range s.Modes {
+		switch strings.ToLower(m) {
+		case check.SELinuxEnforcing, check.SELinuxPermissive, check.SELinuxDisabled:
+		default:
+			errs = append(errs, fmt.Errorf("SELinux mode %q is invalid. Valid modes are %q, %q and %q", m, check.SELinuxEnforcing, check.SELinuxPermissive, check.SELinuxDisabled))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package rule
+
+import "testing"
+
+func TestKernelModuleRuleValidation(t *testing.T) {
+	k := KernelModule{}
+	if errs := k.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	k.Module = "../br_netfilter"
+	if errs := k.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	k.Module = "br_netfilter"
+	if errs := k.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+}
+
+func TestSysctlValueRuleValidation(t *testing.T) {
+	s := SysctlValue{}
+	if errs := s.Validate(); len(errs) != 2 {
+		t.Errorf("expected 2 errors, but got %d", len(errs))
+	}
+	s.Parameter = "net/ipv4/ip_forward"
+	s.Value = "1"
+	if errs := s.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	s.Parameter = "net.ipv4.ip_forward"
+	if errs := s.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+}
+
+func TestMinimumKernelVersionRuleValidation(t *testing.T) {
+	m := MinimumKernelVersion{KernelVersion: "latest"}
+	if errs := m.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	m.KernelVersion = "3.10.0-327"
+	if errs := m.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+}
+
+func TestSELinuxModeRuleValidation(t *testing.T) {
+	s := SELinuxMode{}
+	if errs := s.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	s.Modes = []string{"permissive", "off"}
+	if errs := s.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	s.Modes = []string{"Permissive", "disabled"}
+	if errs := s.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+}
+
+func TestUnmarshalKernelRules(t *testing.T) {
+	data := []byte(`---
+- kind: KernelModule
+  when: ["worker", "centos"]
+  module: br_netfilter
+- kind: SysctlValue
+  parameter: net.ipv4.ip_forward
+  value: "1"
+- kind: MinimumKernelVersion
+  kernelVersion: 4.4.0
+- kind: SwapDisabled
+- kind: SELinuxMode
+  modes: ["permissive"]
+`)
+	rules, err := UnmarshalRulesYAML(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 5 {
+		t.Fatalf("expected 5 rules, got %d", len(rules))
+	}
+	if k, ok := rules[0].(KernelModule); !ok || k.Module != "br_netfilter" || len(k.When) != 2 {
+		t.Errorf("unexpected rule %+v", rules[0])
+	}
+	if s, ok := rules[1].(SysctlValue); !ok || s.Parameter != "net.ipv4.ip_forward" || s.Value != "1" {
+		t.Errorf("unexpected rule %+v", rules[1])
+	}
+	if m, ok := rules[2].(MinimumKernelVersion); !ok || m.KernelVersion != "4.4.0" {
+		t.Errorf("unexpected rule %+v", rules[2])
+	}
+	if _, ok := rules[3].(SwapDisabled); !ok {
+		t.Errorf("unexpected rule %+v", rules[3])
+	}
+	if s, ok := rules[4].(SELinuxMode); !ok || len(s.Modes) != 1 || s.Modes[0] != "permissive" {
+		t.Errorf("unexpected rule %+v", rules[4])
+	}
+}
@@ -0,0 +1,87 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// MinimumCPUCores is a rule that ensures that the node has at least the
+// given number of CPU cores
+type MinimumCPUCores struct {
+	Meta
+	Cores int
+}
+
+// Name is the name of the rule
+func (c MinimumCPUCores) Name() string {
+	return fmt.Sprintf("Minimum CPU Cores: %d", c.Cores)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (c MinimumCPUCores) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (c MinimumCPUCores) Validate() []error {
+	if c.Cores < 1 {
+		return []error{fmt.Errorf("Cores must be greater than 0, but was %d", c.Cores)}
+	}
+	return nil
+}
+
+// MinimumMemoryMB is a rule that ensures that the node has at least the
+// given amount of memory, in megabytes
+type MinimumMemoryMB struct {
+	Meta
+	MemoryMB int
+}
+
+// Name is the name of the rule
+func (m MinimumMemoryMB) Name() string {
+	return fmt.Sprintf("Minimum Memory: %d MB", m.MemoryMB)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (m MinimumMemoryMB) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (m MinimumMemoryMB) Validate() []error {
+	if m.MemoryMB < 1 {
+		return []error{fmt.Errorf("MemoryMB must be greater than 0, but was %d", m.MemoryMB)}
+	}
+	return nil
+}
+
+// FreeSpaceOnPath is a rule that ensures that the filesystem of the path
+// has at least the given amount of free space, in megabytes
+type FreeSpaceOnPath struct {
+	Meta
+	Path        string
+	FreeSpaceMB int
+}
+
+// Name is the name of the rule
+func (f FreeSpaceOnPath) Name() string {
+	return fmt.Sprintf("Free Space on %q: %d MB", f.Path, f.FreeSpaceMB)
+}
+
+// IsRemoteRule returns true if the rule is to be run from outside of the node
+func (f FreeSpaceOnPath) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (f FreeSpaceOnPath) Validate() []error {
+	errs := []error{}
+	if f.Path == "" {
+		errs = append(errs, errors.New("Path cannot be empty"))
+	}
+	if f.Path != "" && !filepath.IsAbs(f.Path) {
+		errs = append(errs, fmt.Errorf("Path must be absolute, but was %q", f.Path))
+	}
+	if f.FreeSpaceMB < 1 {
+		errs = append(errs, fmt.Errorf("FreeSpaceMB must be greater than 0, but was %d", f.FreeSpaceMB))
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
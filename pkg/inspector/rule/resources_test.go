@@ -0,0 +1,70 @@
+package rule
+
+import "testing"
+
+func TestMinimumCPUCoresRuleValidation(t *testing.T) {
+	c := MinimumCPUCores{}
+	if errs := c.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	c.Cores = 2
+	if errs := c.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+}
+
+func TestMinimumMemoryMBRuleValidation(t *testing.T) {
+	m := MinimumMemoryMB{MemoryMB: -1}
+	if errs := m.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	m.MemoryMB = 1024
+	if errs := m.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+}
+
+func TestFreeSpaceOnPathRuleValidation(t *testing.T) {
+	f := FreeSpaceOnPath{}
+	if errs := f.Validate(); len(errs) != 2 {
+		t.Errorf("expected 2 errors, but got %d", len(errs))
+	}
+	f.Path = "var/lib/docker"
+	f.FreeSpaceMB = 1024
+	if errs := f.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	f.Path = "/var/lib/docker"
+	if errs := f.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+}
+
+func TestUnmarshalResourceRules(t *testing.T) {
+	data := []byte(`---
+- kind: MinimumCPUCores
+  when: ["master"]
+  cores: 2
+- kind: MinimumMemoryMB
+  memoryMB: 2048
+- kind: FreeSpaceOnPath
+  path: /var/lib/etcd_k8s
+  freeSpaceMB: 4096
+`)
+	rules, err := UnmarshalRulesYAML(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 3 {
+		t.Fatalf("expected 3 rules, got %d", len(rules))
+	}
+	if c, ok := rules[0].(MinimumCPUCores); !ok || c.Cores != 2 || len(c.When) != 1 {
+		t.Errorf("unexpected rule %+v", rules[0])
+	}
+	if m, ok := rules[1].(MinimumMemoryMB); !ok || m.MemoryMB != 2048 {
+		t.Errorf("unexpected rule %+v", rules[1])
+	}
+	if f, ok := rules[2].(FreeSpaceOnPath); !ok || f.Path != "/var/lib/etcd_k8s" || f.FreeSpaceMB != 4096 {
+		t.Errorf("unexpected rule %+v", rules[2])
+	}
+}
@@ -16,6 +16,96 @@ const defaultRuleSet = `---
    - Python 2.6
    - Python 2.7
 
+# Hardware resources, based on the minimum requirements of each role.
+# The total memory reported by the kernel does not include the memory it
+# reserves, so the thresholds are lower than the installed memory.
+- kind: MinimumCPUCores
+  when: ["etcd"]
+  cores: 1
+- kind: MinimumCPUCores
+  when: ["master"]
+  cores: 1
+- kind: MinimumCPUCores
+  when: ["worker"]
+  cores: 1
+- kind: MinimumMemoryMB
+  when: ["etcd"]
+  memoryMB: 900
+- kind: MinimumMemoryMB
+  when: ["master"]
+  memoryMB: 1800
+- kind: MinimumMemoryMB
+  when: ["worker"]
+  memoryMB: 900
+- kind: MinimumMemoryMB
+  when: ["ingress"]
+  memoryMB: 900
+- kind: MinimumMemoryMB
+  when: ["storage"]
+  memoryMB: 900
+
+# Free space for the etcd data, and for the docker images and containers
+- kind: FreeSpaceOnPath
+  when: ["etcd"]
+  path: /var/lib/etcd_k8s
+  freeSpaceMB: 4096
+- kind: FreeSpaceOnPath
+  when: ["master"]
+  path: /var/lib/docker
+  freeSpaceMB: 2048
+- kind: FreeSpaceOnPath
+  when: ["worker"]
+  path: /var/lib/docker
+  freeSpaceMB: 4096
+- kind: FreeSpaceOnPath
+  when: ["ingress"]
+  path: /var/lib/docker
+  freeSpaceMB: 2048
+- kind: FreeSpaceOnPath
+  when: ["storage"]
+  path: /var/lib/docker
+  freeSpaceMB: 2048
+
+# Kernel prerequisites of docker, calico and kube-proxy.
+# br_netfilter exposes the bridged traffic to iptables, which kube-proxy
+# relies on, and ipip is used by calico in overlay mode. The modules are
+# loaded when they are needed, so it is enough that they can be loaded.
+# SELinux must not be enforcing, as the containers are not labeled.
+# The net.ipv4.ip_forward and swap rules are part of the optional rule set.
+- kind: MinimumKernelVersion
+  when: ["centos"]
+  kernelVersion: 3.10.0-327
+- kind: MinimumKernelVersion
+  when: ["rhel"]
+  kernelVersion: 3.10.0-327
+- kind: MinimumKernelVersion
+  when: ["ubuntu"]
+  kernelVersion: 4.4.0
+- kind: KernelModule
+  when: ["centos"]
+  module: br_netfilter
+- kind: KernelModule
+  when: ["rhel"]
+  module: br_netfilter
+- kind: KernelModule
+  when: ["ubuntu"]
+  module: br_netfilter
+- kind: KernelModule
+  when: ["centos"]
+  module: ipip
+- kind: KernelModule
+  when: ["rhel"]
+  module: ipip
+- kind: KernelModule
+  when: ["ubuntu"]
+  module: ipip
+- kind: SELinuxMode
+  when: ["centos"]
+  modes: ["permissive", "disabled"]
+- kind: SELinuxMode
+  when: ["rhel"]
+  modes: ["permissive", "disabled"]
+
 # Executables required by kubelet
 - kind: ExecutableInPath
   when: ["master","worker"]
@@ -83,6 +173,13 @@ const defaultRuleSet = `---
   port: 8443
   timeout: 5s
 
+# Port used by calico-node to export its metrics to the monitoring add-on.
+# A rule applies when the node has all the roles in its conditions, so the
+# port is checked on all nodes to have a single rule for nodes with several
+# roles. Access from the monitoring add-on is part of the connectivity checks.
+- kind: TCPPortAvailable
+  port: 9091
+
 # Port used by Ingress
 - kind: TCPPortAvailable
   when: ["ingress"]
@@ -235,6 +332,31 @@ const defaultRuleSet = `---
   timeout: 5s
 `
 
+// optionalRuleSet is the list of rules that are not run unless they are
+// added to the rules file. net.ipv4.ip_forward is enabled by docker when it
+// starts, so it is only disabled on nodes that are not yet installed, and
+// the kubelet supports nodes with swap enabled.
+// The rules are appended to the default rule set when dumped, so the set
+// must not start with a document separator.
+const optionalRuleSet = `
+# Optional rules, which are not run unless they are in the rules file.
+# Packets are forwarded between the pods and the network of the node
+- kind: SysctlValue
+  parameter: net.ipv4.ip_forward
+  value: "1"
+
+# Swap is disabled on the nodes that run pods, so that the memory
+# limits of the pods are enforced
+- kind: SwapDisabled
+  when: ["master"]
+- kind: SwapDisabled
+  when: ["worker"]
+- kind: SwapDisabled
+  when: ["ingress"]
+- kind: SwapDisabled
+  when: ["storage"]
+`
+
 // DefaultRules returns the list of rules that are built into the inspector
 func DefaultRules() []Rule {
 	rules, err := UnmarshalRulesYAML([]byte(defaultRuleSet))
@@ -254,3 +376,24 @@ func DumpDefaultRules(writer io.Writer) error {
 	}
 	return nil
 }
+
+// OptionalRules returns the list of rules that are built into the inspector,
+// but are not run unless they are added to the rules file
+func OptionalRules() []Rule {
+	rules, err := UnmarshalRulesYAML([]byte(optionalRuleSet))
+	if err != nil {
+		// The optional rules should not contain errors
+		panic(err)
+	}
+	return rules
+}
+
+// DumpDefaultAndOptionalRules writes the default rule set, followed by the
+// optional rule set, to a file
+func DumpDefaultAndOptionalRules(writer io.Writer) error {
+	if err := DumpDefaultRules(writer); err != nil {
+		return err
+	}
+	_, err := io.Copy(writer, strings.NewReader(optionalRuleSet))
+	return err
+}
@@ -1,6 +1,11 @@
 package rule
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
 
 func TestDefaultRules(t *testing.T) {
 	// This will panic if there are errors in the default rule
@@ -11,3 +16,72 @@ func TestDefaultRules(t *testing.T) {
 		}
 	}
 }
+
+func TestDefaultRulesJSONRoundTrip(t *testing.T) {
+	// The rules are sent to the inspector server as JSON
+	rules := DefaultRules()
+	data, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatalf("error marshaling rules: %v", err)
+	}
+	unmarshaled, err := UnmarshalRulesJSON(data)
+	if err != nil {
+		t.Fatalf("error unmarshaling rules: %v", err)
+	}
+	if !reflect.DeepEqual(rules, unmarshaled) {
+		t.Errorf("the rules changed when sent as JSON.\nExpected: %+v\nGot: %+v", rules, unmarshaled)
+	}
+}
+
+func TestOptionalRules(t *testing.T) {
+	// This will panic if there are errors in the optional rules
+	rules := OptionalRules()
+	for _, r := range rules {
+		if errs := r.Validate(); len(errs) != 0 {
+			t.Errorf("invalid optional rule was found: %+v. Errors are: %v", r, errs)
+		}
+	}
+}
+
+func TestDumpDefaultAndOptionalRules(t *testing.T) {
+	var b bytes.Buffer
+	if err := DumpDefaultAndOptionalRules(&b); err != nil {
+		t.Fatalf("error dumping rules: %v", err)
+	}
+	rules, err := UnmarshalRulesYAML(b.Bytes())
+	if err != nil {
+		t.Fatalf("error unmarshaling dumped rules: %v", err)
+	}
+	expected := len(DefaultRules()) + len(OptionalRules())
+	if len(rules) != expected {
+		t.Errorf("expected %d rules, but got %d", expected, len(rules))
+	}
+}
+
+func TestDefaultRulesBindEachPortOnce(t *testing.T) {
+	roles := []string{"etcd", "master", "worker", "ingress", "storage"}
+	// every combination of roles that a node can have
+	for i := 1; i < 1<<uint(len(roles)); i++ {
+		facts := []string{}
+		for j, r := range roles {
+			if i&(1<<uint(j)) != 0 {
+				facts = append(facts, r)
+			}
+		}
+		seen := map[string]bool{}
+		for _, r := range DefaultRules() {
+			switch r.(type) {
+			case TCPPortAvailable, TCPPortAccessible:
+			default:
+				continue
+			}
+			if !shouldExecuteRule(r, facts) {
+				continue
+			}
+			if seen[r.Name()] {
+				t.Errorf("rule %q runs more than once on a node with roles %v", r.Name(), facts)
+			}
+			seen[r.Name()] = true
+		}
+	}
+}
@@ -3,6 +3,7 @@ package rule
 import (
 	"errors"
 	"fmt"
+	"net"
 	"time"
 )
 
@@ -65,3 +66,33 @@ func (p TCPPortAccessible) Validate() []error {
 	}
 	return nil
 }
+
+// PeerTCPPortAccessible is a rule that ensures the given port on a peer
+// node is accessible from the node where the rule runs
+type PeerTCPPortAccessible struct {
+	Meta
+	PeerIP  string
+	Port    int
+	Timeout string
+}
+
+// Name returns the name of the rule
+func (p PeerTCPPortAccessible) Name() string {
+	return fmt.Sprintf("Port Accessible on Peer %s: %d", p.PeerIP, p.Port)
+}
+
+// IsRemoteRule returns true if the rule is to be run from a remote node
+func (p PeerTCPPortAccessible) IsRemoteRule() bool { return false }
+
+// Validate the rule
+func (p PeerTCPPortAccessible) Validate() []error {
+	errs := []error{}
+	if net.ParseIP(p.PeerIP) == nil {
+		errs = append(errs, fmt.Errorf("Invalid peer IP %q specified", p.PeerIP))
+	}
+	errs = append(errs, TCPPortAccessible{Port: p.Port, Timeout: p.Timeout}.Validate()...)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -47,3 +47,43 @@ func TestTCPPortAccessibleRuleValidation(t *testing.T) {
 		t.Errorf("expected 0 error, but got %d", len(errs))
 	}
 }
+
+func TestPeerTCPPortAccessibleRuleValidation(t *testing.T) {
+	p := PeerTCPPortAccessible{}
+	if errs := p.Validate(); len(errs) != 3 {
+		t.Errorf("expected 3 errors, but got %d", len(errs))
+	}
+	p.PeerIP = "10.0.1.24"
+	p.Port = 179
+	if errs := p.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+	p.Timeout = "5s"
+	if errs := p.Validate(); len(errs) != 0 {
+		t.Errorf("expected 0 errors, but got %d", len(errs))
+	}
+	p.PeerIP = "node01"
+	if errs := p.Validate(); len(errs) != 1 {
+		t.Errorf("expected 1 error, but got %d", len(errs))
+	}
+}
+
+func TestUnmarshalPeerTCPPortAccessibleRule(t *testing.T) {
+	data := []byte(`---
+- kind: PeerTCPPortAccessible
+  peerIP: 10.0.1.24
+  port: 2380
+  timeout: 5s
+`)
+	rules, err := UnmarshalRulesYAML(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(rules))
+	}
+	p, ok := rules[0].(PeerTCPPortAccessible)
+	if !ok || p.PeerIP != "10.0.1.24" || p.Port != 2380 || p.Timeout != "5s" {
+		t.Errorf("unexpected rule %+v", rules[0])
+	}
+}
@@ -46,6 +46,7 @@ func NewServer(nodeFacts []string, port int, enforcePackages bool) (*Server, err
 	engine := &rule.Engine{
 		RuleCheckMapper: rule.DefaultCheckMapper{
 			PackageManager: pkgMgr,
+			Distro:         distro,
 		},
 	}
 	s.rulesEngine = engine
@@ -0,0 +1,154 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// AddMaster adds a master node to the original cluster described in the plan.
+// If successful, the updated plan is returned.
+func (ae *ansibleExecutor) AddMaster(originalPlan *Plan, newMaster Node) (*Plan, error) {
+	if err := checkAddControlPlanePrereqs(ae.pki); err != nil {
+		return nil, err
+	}
+	runDirectory, err := ae.createRunDirectory("add-master")
+	if err != nil {
+		return nil, fmt.Errorf("error creating working directory for add-master: %v", err)
+	}
+	updatedPlan := addMasterToPlan(*originalPlan, newMaster)
+	fp := FilePlanner{
+		File: filepath.Join(runDirectory, "kismatic-cluster.yaml"),
+	}
+	if err = fp.Write(&updatedPlan); err != nil {
+		return nil, fmt.Errorf("error recording plan file to %s: %v", fp.File, err)
+	}
+	// The certificate must include the load balanced names of the master
+	util.PrintHeader(ae.stdout, "Generating Certificate For Master Node", '=')
+	ca, err := ae.pki.GetClusterCA()
+	if err != nil {
+		return nil, err
+	}
+	if err := ae.pki.RegenerateNodeCertificate(&updatedPlan, newMaster, ca); err != nil {
+		return nil, fmt.Errorf("error generating certificate for new master: %v", err)
+	}
+	inventory := buildInventoryFromPlan(&updatedPlan)
+	cc, err := ae.buildInstallExtraVars(&updatedPlan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ansible vars: %v", err)
+	}
+	ansibleLogFilename := filepath.Join(runDirectory, "ansible.log")
+	ansibleLogFile, err := os.Create(ansibleLogFilename)
+	if err != nil {
+		return nil, fmt.Errorf("error creating ansible log file %q: %v", ansibleLogFilename, err)
+	}
+	util.PrintHeader(ae.stdout, "Adding Master Node to Cluster", '=')
+	eventExplainer := &explain.DefaultEventExplainer{}
+	if err = ae.runPlaybookOnNodeWithExplainer("kubernetes-master.yaml", eventExplainer, inventory, *cc, newMaster.Host, ansibleLogFile, runDirectory); err != nil {
+		return nil, err
+	}
+	// The API server count changed, so reconfigure all API servers
+	util.PrintHeader(ae.stdout, "Reconfiguring API Servers", '=')
+	eventExplainer = &explain.DefaultEventExplainer{}
+	if err = ae.runPlaybookWithExplainer("_apiserver.yaml", eventExplainer, inventory, *cc, ansibleLogFile, runDirectory); err != nil {
+		return nil, fmt.Errorf("error reconfiguring API servers: %v", err)
+	}
+	if updatedPlan.Cluster.Networking.UpdateHostsFiles {
+		util.PrintHeader(ae.stdout, "Updating Hosts Files On All Nodes", '=')
+		eventExplainer = &explain.DefaultEventExplainer{}
+		if err = ae.runPlaybookWithExplainer("_hosts.yaml", eventExplainer, inventory, *cc, ansibleLogFile, runDirectory); err != nil {
+			return nil, fmt.Errorf("error updating hosts files on all nodes: %v", err)
+		}
+	}
+	// Verify that the node registered with API server
+	util.PrintHeader(ae.stdout, "Running New Master Smoke Test", '=')
+	cc.WorkerNode = newMaster.Host
+	eventExplainer = &explain.DefaultEventExplainer{}
+	if err = ae.runPlaybookWithExplainer("_worker-smoke-test.yaml", eventExplainer, inventory, *cc, ansibleLogFile, runDirectory); err != nil {
+		return nil, fmt.Errorf("error running new master smoke test: %v", err)
+	}
+	if updatedPlan.Master.LoadBalancedFQDN != "" {
+		util.PrettyPrintWarn(ae.stdout, "The new master must be added to the load balancer serving %q", updatedPlan.Master.LoadBalancedFQDN)
+	}
+	if err = recordSuccessfulRun(runDirectory); err != nil {
+		return nil, err
+	}
+	return &updatedPlan, nil
+}
+
+// AddEtcd adds a member to the etcd clusters of the original cluster described
+// in the plan. The existing members are reconfigured to include the new member,
+// and the etcd clients are updated with the new endpoint.
+// If successful, the updated plan is returned.
+func (ae *ansibleExecutor) AddEtcd(originalPlan *Plan, newEtcd Node) (*Plan, error) {
+	if err := checkAddControlPlanePrereqs(ae.pki); err != nil {
+		return nil, err
+	}
+	runDirectory, err := ae.createRunDirectory("add-etcd")
+	if err != nil {
+		return nil, fmt.Errorf("error creating working directory for add-etcd: %v", err)
+	}
+	updatedPlan := addEtcdToPlan(*originalPlan, newEtcd)
+	fp := FilePlanner{
+		File: filepath.Join(runDirectory, "kismatic-cluster.yaml"),
+	}
+	if err = fp.Write(&updatedPlan); err != nil {
+		return nil, fmt.Errorf("error recording plan file to %s: %v", fp.File, err)
+	}
+	util.PrintHeader(ae.stdout, "Generating Certificate For Etcd Node", '=')
+	ca, err := ae.pki.GetClusterCA()
+	if err != nil {
+		return nil, err
+	}
+	if err := ae.pki.RegenerateNodeCertificate(&updatedPlan, newEtcd, ca); err != nil {
+		return nil, fmt.Errorf("error generating certificate for new etcd node: %v", err)
+	}
+	inventory := buildInventoryFromPlan(&updatedPlan)
+	cc, err := ae.buildInstallExtraVars(&updatedPlan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ansible vars: %v", err)
+	}
+	cc.EtcdNode = newEtcd.Host
+	ansibleLogFilename := filepath.Join(runDirectory, "ansible.log")
+	ansibleLogFile, err := os.Create(ansibleLogFilename)
+	if err != nil {
+		return nil, fmt.Errorf("error creating ansible log file %q: %v", ansibleLogFilename, err)
+	}
+	util.PrintHeader(ae.stdout, "Adding Etcd Node to Cluster", '=')
+	eventExplainer := &explain.DefaultEventExplainer{}
+	if err = ae.runPlaybookWithExplainer("kubernetes-etcd.yaml", eventExplainer, inventory, *cc, ansibleLogFile, runDirectory); err != nil {
+		return nil, err
+	}
+	if err = recordSuccessfulRun(runDirectory); err != nil {
+		return nil, err
+	}
+	return &updatedPlan, nil
+}
+
+func addMasterToPlan(plan Plan, master Node) Plan {
+	plan.Master.ExpectedCount++
+	plan.Master.Nodes = append(append([]Node{}, plan.Master.Nodes...), master)
+	return plan
+}
+
+func addEtcdToPlan(plan Plan, etcd Node) Plan {
+	plan.Etcd.ExpectedCount++
+	plan.Etcd.Nodes = append(append([]Node{}, plan.Etcd.Nodes...), etcd)
+	return plan
+}
+
+// the CA is always required, as an existing certificate
+// might have to be replaced to include the new SANs
+func checkAddControlPlanePrereqs(pki PKI) error {
+	caExists, err := pki.CertificateAuthorityExists()
+	if err != nil {
+		return fmt.Errorf("error while checking if cluster CA exists: %v", err)
+	}
+	if !caExists {
+		return errMissingClusterCA
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package install
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+)
+
+var controlPlanePlan = Plan{
+	Cluster: Cluster{
+		Networking: NetworkConfig{
+			ServiceCIDRBlock: "10.0.0.0/16",
+			UpdateHostsFiles: true,
+		},
+	},
+	Etcd: NodeGroup{
+		ExpectedCount: 1,
+		Nodes:         []Node{{Host: "etcd01"}},
+	},
+	Master: MasterNodeGroup{
+		ExpectedCount: 1,
+		Nodes:         []Node{{Host: "master01", InternalIP: "10.10.2.20"}},
+	},
+	Worker: NodeGroup{
+		ExpectedCount: 1,
+		Nodes:         []Node{{Host: "worker01"}},
+	},
+}
+
+func TestAddMasterCAMissing(t *testing.T) {
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		pki:                 &fakePKI{nodeCertExists: true},
+		certsDir:            mustGetTempDir(t),
+	}
+	p := controlPlanePlan
+	newPlan, err := e.AddMaster(&p, Node{Host: "master02"})
+	if newPlan != nil {
+		t.Errorf("add master returned an updated plan")
+	}
+	if err != errMissingClusterCA {
+		t.Errorf("AddMaster did not return the expected error. Instead returned: %v", err)
+	}
+}
+
+func TestAddMasterPlanIsUpdated(t *testing.T) {
+	fakeRunner := fakeRunner{}
+	pki := &fakePKI{caExists: true}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		pki:                 pki,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		certsDir: mustGetTempDir(t),
+	}
+	p := controlPlanePlan
+	updatedPlan, err := e.AddMaster(&p, Node{Host: "master02"})
+	if err != nil {
+		t.Fatalf("unexpected error while adding master: %v", err)
+	}
+	if updatedPlan.Master.ExpectedCount != 2 || len(updatedPlan.Master.Nodes) != 2 {
+		t.Errorf("the updated plan does not include the new master: %+v", updatedPlan.Master)
+	}
+	if len(p.Master.Nodes) != 1 {
+		t.Errorf("the original plan was modified")
+	}
+	if !pki.regenerateNodeCertCalled {
+		t.Error("node certificate was not regenerated")
+	}
+	if !reflect.DeepEqual(fakeRunner.nodePlaybooks, []string{"master02:kubernetes-master.yaml"}) {
+		t.Errorf("expected master playbook to run against the new node, but got %v", fakeRunner.nodePlaybooks)
+	}
+	expected := []string{"_apiserver.yaml", "_hosts.yaml", "_worker-smoke-test.yaml"}
+	if !reflect.DeepEqual(fakeRunner.allNodesPlaybooks, expected) {
+		t.Errorf("expected playbooks %v, but got %v", expected, fakeRunner.allNodesPlaybooks)
+	}
+}
+
+func TestAddEtcdPlanIsUpdated(t *testing.T) {
+	fakeRunner := fakeRunner{}
+	pki := &fakePKI{caExists: true}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		pki:                 pki,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		certsDir: mustGetTempDir(t),
+	}
+	p := controlPlanePlan
+	updatedPlan, err := e.AddEtcd(&p, Node{Host: "etcd02"})
+	if err != nil {
+		t.Fatalf("unexpected error while adding etcd node: %v", err)
+	}
+	if updatedPlan.Etcd.ExpectedCount != 2 || len(updatedPlan.Etcd.Nodes) != 2 {
+		t.Errorf("the updated plan does not include the new etcd node: %+v", updatedPlan.Etcd)
+	}
+	if !pki.regenerateNodeCertCalled {
+		t.Error("node certificate was not regenerated")
+	}
+	if !reflect.DeepEqual(fakeRunner.allNodesPlaybooks, []string{"kubernetes-etcd.yaml"}) {
+		t.Errorf("expected etcd playbook to run, but got %v", fakeRunner.allNodesPlaybooks)
+	}
+}
+
+func TestAddEtcdPlanNotUpdatedAfterFailure(t *testing.T) {
+	e := ansibleExecutor{
+		options:                ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:                 ioutil.Discard,
+		consoleOutputFormat:    ansible.RawFormat,
+		pki:                    &fakePKI{caExists: true},
+		runnerExplainerFactory: fakeRunnerExplainer(errors.New("exec error")),
+		certsDir:               mustGetTempDir(t),
+	}
+	p := controlPlanePlan
+	updatedPlan, err := e.AddEtcd(&p, Node{Host: "etcd02"})
+	if err == nil {
+		t.Errorf("expected an error, but didn't get one")
+	}
+	if updatedPlan != nil {
+		t.Error("plan was updated, even though adding etcd node failed")
+	}
+}
@@ -11,7 +11,7 @@ import (
 )
 
 var errMissingClusterCA = errors.New("The Certificate Authority's private key and certificate used to install " +
-	"the cluster are required for adding worker nodes.")
+	"the cluster are required for adding nodes.")
 
 // AddWorker adds a worker node to the original cluster described in the plan.
 // If successful, the updated plan is returned.
@@ -123,6 +123,9 @@ func (ae *ansibleExecutor) AddWorker(originalPlan *Plan, newWorker Node) (*Plan,
 			return nil, fmt.Errorf("error adding new worker to volume allow list: %v", err)
 		}
 	}
+	if err = recordSuccessfulRun(runDirectory); err != nil {
+		return nil, err
+	}
 	return &updatedPlan, nil
 }
 
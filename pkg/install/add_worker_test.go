@@ -283,11 +283,14 @@ func TestAddWorkerHostsFilesDNSEnabled(t *testing.T) {
 
 //// Fakes for testing
 type fakePKI struct {
-	caExists               bool
-	nodeCertExists         bool
-	err                    error
-	generateCACalled       bool
-	generateNodeCertCalled bool
+	caExists                 bool
+	nodeCertExists           bool
+	err                      error
+	generateCACalled         bool
+	generateNodeCertCalled   bool
+	regenerateNodeCertCalled bool
+	rotateCalled             bool
+	rotateCA                 bool
 }
 
 func (f *fakePKI) CertificateAuthorityExists() (bool, error)     { return f.caExists, f.err }
@@ -296,27 +299,40 @@ func (f *fakePKI) GenerateNodeCertificate(plan *Plan, node Node, ca *tls.CA) err
 	f.generateNodeCertCalled = true
 	return f.err
 }
+func (f *fakePKI) RegenerateNodeCertificate(plan *Plan, node Node, ca *tls.CA) error {
+	f.regenerateNodeCertCalled = true
+	return f.err
+}
 func (f *fakePKI) GetClusterCA() (*tls.CA, error) { return nil, f.err }
 func (f *fakePKI) GenerateClusterCA(p *Plan) (*tls.CA, error) {
 	f.generateCACalled = true
 	return nil, f.err
 }
 func (f *fakePKI) GenerateClusterCertificates(p *Plan, ca *tls.CA, users []string) error { return f.err }
+func (f *fakePKI) RotateClusterCertificates(p *Plan, users []string, rotateCA bool) (string, error) {
+	f.rotateCalled = true
+	f.rotateCA = rotateCA
+	return "", f.err
+}
 
 type fakeRunner struct {
 	eventChan         chan ansible.Event
 	err               error
 	incomingCatalog   ansible.ClusterCatalog
+	incomingInventory ansible.Inventory
 	allNodesPlaybooks []string
+	nodePlaybooks     []string
 }
 
 func (f *fakeRunner) StartPlaybook(playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog) (<-chan ansible.Event, error) {
+	f.incomingInventory = inventory
 	f.allNodesPlaybooks = append(f.allNodesPlaybooks, playbookFile)
 	return f.eventChan, f.err
 }
 func (f *fakeRunner) WaitPlaybook() error { return f.err }
 func (f *fakeRunner) StartPlaybookOnNode(playbookFile string, inventory ansible.Inventory, cc ansible.ClusterCatalog, node string) (<-chan ansible.Event, error) {
 	f.incomingCatalog = cc
+	f.nodePlaybooks = append(f.nodePlaybooks, node+":"+playbookFile)
 	return f.eventChan, f.err
 }
 
@@ -0,0 +1,265 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// AddOn is a component that is deployed on the cluster once Kubernetes is running
+type AddOn struct {
+	// Name is used to refer to the add-on on the command line
+	Name        string
+	Description string
+	// PlanSection is the section of the plan file that configures the add-on
+	PlanSection string
+	// RequiredRoles are the node roles that the plan must have for the add-on
+	// to be enabled
+	RequiredRoles []string
+	// Playbooks install the add-on, in order
+	Playbooks []string
+	// RemovePlaybook removes the add-on from the cluster. The add-on cannot
+	// be disabled when it is empty.
+	RemovePlaybook string
+	// PodLabels are the labels of the pods of the add-on, as "key=value".
+	// A pod that has any of the labels belongs to the add-on.
+	PodLabels []string
+
+	enabled func(p *Plan) bool
+	// nil when the add-on is turned on and off by other settings of the plan
+	setEnabled func(p *Plan, enabled bool)
+	// validates the settings of the add-on when it is enabled
+	validate func(p *Plan, v *validator)
+	// the add-on uses the certificates generated by kismatic, which might not
+	// exist on clusters installed with an earlier version
+	usesCertificates bool
+}
+
+// the add-ons, in the order they are installed
+var addOns = []AddOn{
+	{
+		Name:        "network-policy",
+		Description: "Calico policy controller that enforces Kubernetes network policies",
+		PlanSection: "cluster.networking.policy_enabled",
+		Playbooks:   []string{"_calico.yaml", "_addon-network-policy.yaml"},
+		PodLabels:   []string{"k8s-app=calico-policy"},
+		enabled:     func(p *Plan) bool { return p.Cluster.Networking.PolicyEnabled },
+		setEnabled:  func(p *Plan, enabled bool) { p.Cluster.Networking.PolicyEnabled = enabled },
+	},
+	{
+		Name:        "dns",
+		Description: "Kubernetes DNS",
+		Playbooks:   []string{"_addon-kubernetes-dns.yaml"},
+		PodLabels:   []string{"k8s-app=kube-dns"},
+		enabled:     func(p *Plan) bool { return true },
+	},
+	{
+		Name:          "ingress",
+		Description:   "Nginx ingress controller, deployed on the ingress nodes",
+		PlanSection:   "ingress",
+		RequiredRoles: []string{"ingress"},
+		Playbooks:     []string{"_addon-kubernetes-ingress.yaml"},
+		PodLabels:     []string{"name=ingress", "app=default-http-backend"},
+		enabled:       func(p *Plan) bool { return len(p.Ingress.Nodes) > 0 },
+	},
+	{
+		Name:        "dashboard",
+		Description: "Kubernetes dashboard",
+		Playbooks:   []string{"_addon-kubernetes-dashboard.yaml"},
+		PodLabels:   []string{"app=kubernetes-dashboard"},
+		enabled:     func(p *Plan) bool { return true },
+	},
+	{
+		Name:        "nfs",
+		Description: "Persistent volumes backed by the NFS shares of the plan",
+		PlanSection: "nfs",
+		Playbooks:   []string{"_addon-nfs-volumes.yaml"},
+		enabled:     func(p *Plan) bool { return len(p.NFS.Volumes) > 0 },
+	},
+	{
+		Name:           "logging",
+		Description:    "Fluentd, Elasticsearch and Kibana",
+		PlanSection:    "logging",
+		Playbooks:      []string{"_addon-logging.yaml"},
+		RemovePlaybook: "_addon-logging-remove.yaml",
+		PodLabels:      []string{"k8s-app=elasticsearch-logging", "k8s-app=fluentd-logging", "k8s-app=kibana-logging", "k8s-app=elasticsearch-curator"},
+		enabled:        func(p *Plan) bool { return p.Logging.Enabled },
+		setEnabled:     func(p *Plan, enabled bool) { p.Logging.Enabled = enabled },
+		validate: func(p *Plan, v *validator) {
+			v.validateWithErrPrefix("Logging", &p.Logging, addOnStorage{p.Logging.AddOnStorage, len(p.Storage.Nodes)})
+		},
+	},
+	{
+		Name:        "monitoring",
+		Description: "Prometheus",
+		PlanSection: "monitoring",
+		// calico-node is restarted on the nodes where it does not export
+		// its metrics yet
+		Playbooks:      []string{"_calico.yaml", "_addon-monitoring.yaml"},
+		RemovePlaybook: "_addon-monitoring-remove.yaml",
+		PodLabels:      []string{"app=prometheus"},
+		enabled:        func(p *Plan) bool { return p.Monitoring.Enabled },
+		setEnabled:     func(p *Plan, enabled bool) { p.Monitoring.Enabled = enabled },
+		validate: func(p *Plan, v *validator) {
+			v.validateWithErrPrefix("Monitoring", addOnStorage{p.Monitoring.AddOnStorage, len(p.Storage.Nodes)})
+		},
+		usesCertificates: true,
+	},
+	{
+		Name:           "linkerd",
+		Description:    "linkerd service mesh",
+		PlanSection:    "linkerd",
+		Playbooks:      []string{"_addon-linkerd.yaml"},
+		RemovePlaybook: "_addon-linkerd-remove.yaml",
+		PodLabels:      []string{"app=l5d"},
+		enabled:        func(p *Plan) bool { return p.Linkerd.Enabled },
+		setEnabled:     func(p *Plan, enabled bool) { p.Linkerd.Enabled = enabled },
+	},
+}
+
+// AddOns returns the add-ons that can be deployed on the cluster
+func AddOns() []AddOn {
+	return addOns
+}
+
+// GetAddOn returns the add-on with the given name
+func GetAddOn(name string) (*AddOn, error) {
+	for i := range addOns {
+		if addOns[i].Name == name {
+			return &addOns[i], nil
+		}
+	}
+	return nil, fmt.Errorf("add-on %q is not supported", name)
+}
+
+// Enabled returns whether the add-on is enabled in the plan
+func (a AddOn) Enabled(p *Plan) bool {
+	return a.enabled(p)
+}
+
+// CanEnable returns whether the add-on can be enabled with the add-on command
+func (a AddOn) CanEnable() bool {
+	return a.setEnabled != nil
+}
+
+// CanDisable returns whether the add-on can be disabled and removed from the cluster
+func (a AddOn) CanDisable() bool {
+	return a.setEnabled != nil && a.RemovePlaybook != ""
+}
+
+// returns the names of the add-ons that are enabled in the plan
+func enabledAddOns(p *Plan) []string {
+	names := []string{}
+	for _, a := range addOns {
+		if a.Enabled(p) {
+			names = append(names, a.Name)
+		}
+	}
+	return names
+}
+
+// validates the add-ons that are enabled in the plan
+func validateAddOns(p *Plan, v *validator) {
+	for _, a := range addOns {
+		if !a.Enabled(p) {
+			continue
+		}
+		for _, role := range a.RequiredRoles {
+			if !p.hasRole(role) {
+				v.addError(fmt.Errorf("Add-on %q requires %s nodes", a.Name, role))
+			}
+		}
+		if a.validate != nil {
+			a.validate(p, v)
+		}
+	}
+}
+
+// EnableAddOn turns on the add-on in the plan. The settings of the add-on
+// are taken from the plan.
+func EnableAddOn(p *Plan, name string) error {
+	a, err := GetAddOn(name)
+	if err != nil {
+		return err
+	}
+	if !a.CanEnable() {
+		return fmt.Errorf("add-on %q is configured by the %q section of the plan file and cannot be enabled with this command", name, a.PlanSection)
+	}
+	a.setEnabled(p, true)
+	return nil
+}
+
+// DisableAddOn turns off the add-on in the plan
+func DisableAddOn(p *Plan, name string) error {
+	a, err := GetAddOn(name)
+	if err != nil {
+		return err
+	}
+	if !a.CanDisable() {
+		return fmt.Errorf("add-on %q cannot be disabled", name)
+	}
+	a.setEnabled(p, false)
+	return nil
+}
+
+// InstallAddOn installs the add-on on the cluster
+func (ae *ansibleExecutor) InstallAddOn(p *Plan, name string) error {
+	a, err := GetAddOn(name)
+	if err != nil {
+		return err
+	}
+	// Only the certificates that are missing are generated
+	if a.usesCertificates {
+		if err := ae.generateTLSAssets(p); err != nil {
+			return err
+		}
+	}
+	return ae.runAddOnPlaybooks(p, fmt.Sprintf("Installing Add-On %q", name), a.Playbooks)
+}
+
+// RemoveAddOn removes the add-on from the cluster
+func (ae *ansibleExecutor) RemoveAddOn(p *Plan, name string) error {
+	a, err := GetAddOn(name)
+	if err != nil {
+		return err
+	}
+	if a.RemovePlaybook == "" {
+		return fmt.Errorf("add-on %q cannot be disabled", name)
+	}
+	return ae.runAddOnPlaybooks(p, fmt.Sprintf("Removing Add-On %q", name), []string{a.RemovePlaybook})
+}
+
+func (ae *ansibleExecutor) runAddOnPlaybooks(p *Plan, header string, playbooks []string) error {
+	runDirectory, err := ae.createRunDirectory("addon")
+	if err != nil {
+		return fmt.Errorf("error creating working directory for add-on: %v", err)
+	}
+	fp := FilePlanner{
+		File: filepath.Join(runDirectory, "kismatic-cluster.yaml"),
+	}
+	if err = fp.Write(p); err != nil {
+		return fmt.Errorf("error recording plan file to %s: %v", fp.File, err)
+	}
+	inventory := buildInventoryFromPlan(p)
+	cc, err := ae.buildInstallExtraVars(p)
+	if err != nil {
+		return err
+	}
+	ansibleLogFilename := filepath.Join(runDirectory, "ansible.log")
+	ansibleLogFile, err := os.Create(ansibleLogFilename)
+	if err != nil {
+		return fmt.Errorf("error creating ansible log file %q: %v", ansibleLogFilename, err)
+	}
+	util.PrintHeader(ae.stdout, header, '=')
+	for _, playbook := range playbooks {
+		eventExplainer := &explain.DefaultEventExplainer{}
+		if err = ae.runPlaybookWithExplainer(playbook, eventExplainer, inventory, *cc, ansibleLogFile, runDirectory); err != nil {
+			return fmt.Errorf("error running playbook %q: %v", playbook, err)
+		}
+	}
+	// The plan of the run has the add-on turned on or off
+	return recordSuccessfulRun(runDirectory)
+}
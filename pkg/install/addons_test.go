@@ -0,0 +1,97 @@
+package install
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+)
+
+func TestEnabledAddOns(t *testing.T) {
+	p := &Plan{}
+	if got := enabledAddOns(p); !reflect.DeepEqual(got, []string{"dns", "dashboard"}) {
+		t.Errorf("unexpected add-ons enabled by default: %v", got)
+	}
+	p.Cluster.Networking.PolicyEnabled = true
+	p.Ingress.Nodes = []Node{{Host: "ingress01"}}
+	p.NFS.Volumes = []NFSVolume{{Host: "10.10.2.20", Path: "/foo"}}
+	p.Logging.Enabled = true
+	p.Monitoring.Enabled = true
+	p.Linkerd.Enabled = true
+	expected := []string{"network-policy", "dns", "ingress", "dashboard", "nfs", "logging", "monitoring", "linkerd"}
+	if got := enabledAddOns(p); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected add-ons %v, got %v", expected, got)
+	}
+}
+
+func TestEnableDisableAddOn(t *testing.T) {
+	p := &Plan{}
+	if err := EnableAddOn(p, "linkerd"); err != nil {
+		t.Fatalf("unexpected error enabling add-on: %v", err)
+	}
+	if !p.Linkerd.Enabled {
+		t.Errorf("expected linkerd to be enabled in the plan")
+	}
+	if err := DisableAddOn(p, "linkerd"); err != nil {
+		t.Fatalf("unexpected error disabling add-on: %v", err)
+	}
+	if p.Linkerd.Enabled {
+		t.Errorf("expected linkerd to be disabled in the plan")
+	}
+
+	// add-ons that are configured by other sections of the plan
+	if err := EnableAddOn(p, "ingress"); err == nil {
+		t.Errorf("expected an error enabling the ingress add-on")
+	}
+	if err := DisableAddOn(p, "network-policy"); err == nil {
+		t.Errorf("expected an error disabling the network-policy add-on")
+	}
+	if err := EnableAddOn(p, "foo"); err == nil {
+		t.Errorf("expected an error enabling an unsupported add-on")
+	}
+}
+
+func TestInstallMonitoringAddOn(t *testing.T) {
+	runsDir := mustGetTempDir(t)
+	defer os.RemoveAll(runsDir)
+	certsDir := mustGetTempDir(t)
+	defer os.RemoveAll(certsDir)
+	fakeRunner := fakeRunner{}
+	pki := &fakePKI{caExists: true}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: runsDir},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		pki:      pki,
+		certsDir: certsDir,
+	}
+	p := diffTestPlan()
+	p.Monitoring.Enabled = true
+	if err := e.InstallAddOn(&p, "monitoring"); err != nil {
+		t.Fatalf("unexpected error installing add-on: %v", err)
+	}
+	// The certificate of the monitoring user is generated if it is missing
+	if !pki.generateCACalled {
+		t.Errorf("expected the certificates to be generated before installing the add-on")
+	}
+	// calico-node must be configured to export its metrics
+	expected := []string{"_calico.yaml", "_addon-monitoring.yaml"}
+	if !reflect.DeepEqual(fakeRunner.allNodesPlaybooks, expected) {
+		t.Errorf("expected %v to run, got %v", expected, fakeRunner.allNodesPlaybooks)
+	}
+	// The run is recorded, so that apply --diff compares against the plan with the add-on
+	last, _, err := LastSuccessfulPlan(runsDir)
+	if err != nil {
+		t.Fatalf("unexpected error reading the last successful plan: %v", err)
+	}
+	if !last.Monitoring.Enabled {
+		t.Errorf("expected the plan of the add-on run to be the last successful plan")
+	}
+}
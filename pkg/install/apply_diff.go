@@ -0,0 +1,275 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// an applyStep is a playbook that must be run to apply
+// a plan diff to the cluster
+type applyStep struct {
+	header   string
+	playbook string
+	// the playbook is limited to this node when set
+	node string
+	// the inventory and vars are built from this plan
+	plan       *Plan
+	workerNode string
+	etcdNode   string
+}
+
+// ApplyDiff applies the changes between the previous plan, which
+// describes the running cluster, and the new plan. Only the playbooks
+// required for the changes are run.
+func (ae *ansibleExecutor) ApplyDiff(previous, plan *Plan) error {
+	diff := DiffPlans(previous, plan)
+	if ok, errs := ValidatePlanDiff(&diff); !ok {
+		return fmt.Errorf("the changes cannot be applied to a running cluster: %v", errs)
+	}
+	// New nodes, registries and users require certificates signed by the cluster CA
+	needCerts := len(diff.NodesAdded) > 0 || len(diff.DockerRegistry) > 0 || len(diff.Authorization) > 0
+	if needCerts {
+		if err := checkAddControlPlanePrereqs(ae.pki); err != nil {
+			return err
+		}
+	}
+	runDirectory, err := ae.createRunDirectory("apply-diff")
+	if err != nil {
+		return fmt.Errorf("error creating working directory for apply-diff: %v", err)
+	}
+	fp := FilePlanner{
+		File: filepath.Join(runDirectory, "kismatic-cluster.yaml"),
+	}
+	if err = fp.Write(plan); err != nil {
+		return fmt.Errorf("error recording plan file to %s: %v", fp.File, err)
+	}
+	if needCerts {
+		util.PrintHeader(ae.stdout, "Configuring Certificates", '=')
+		ca, err := ae.pki.GetClusterCA()
+		if err != nil {
+			return err
+		}
+		for _, n := range uniqueNodes(diff.NodesAdded) {
+			if err := ae.pki.RegenerateNodeCertificate(plan, n, ca); err != nil {
+				return fmt.Errorf("error generating certificate for node %q: %v", n.Host, err)
+			}
+		}
+		// Generate any other certificates that are missing
+		if err := ae.pki.GenerateClusterCertificates(plan, ca, clusterUsers); err != nil {
+			return fmt.Errorf("error generating certificates for the cluster: %v", err)
+		}
+	}
+	ansibleLogFilename := filepath.Join(runDirectory, "ansible.log")
+	ansibleLogFile, err := os.Create(ansibleLogFilename)
+	if err != nil {
+		return fmt.Errorf("error creating ansible log file %q: %v", ansibleLogFilename, err)
+	}
+	for _, s := range buildApplySteps(previous, plan, diff) {
+		util.PrintHeader(ae.stdout, s.header, '=')
+		inventory := buildInventoryFromPlan(s.plan)
+		cc, err := ae.buildInstallExtraVars(s.plan)
+		if err != nil {
+			return fmt.Errorf("failed to generate ansible vars: %v", err)
+		}
+		cc.WorkerNode = s.workerNode
+		cc.EtcdNode = s.etcdNode
+		eventExplainer := &explain.DefaultEventExplainer{}
+		if s.node != "" {
+			err = ae.runPlaybookOnNodeWithExplainer(s.playbook, eventExplainer, inventory, *cc, s.node, ansibleLogFile, runDirectory)
+		} else {
+			err = ae.runPlaybookWithExplainer(s.playbook, eventExplainer, inventory, *cc, ansibleLogFile, runDirectory)
+		}
+		if err != nil {
+			return fmt.Errorf("error running playbook %q: %v", s.playbook, err)
+		}
+	}
+	return recordSuccessfulRun(runDirectory)
+}
+
+// returns the playbooks that must be run, in order, to apply the diff
+func buildApplySteps(previous, plan *Plan, d PlanDiff) []applyStep {
+	steps := []applyStep{}
+	// The cluster is walked from the previous plan to the new plan, so that
+	// each playbook only sees the nodes that have already been configured
+	current := *previous
+
+	// Removed nodes must be in the inventory for ansible to reach them
+	removed := uniqueNodes(d.NodesRemoved)
+	for _, n := range removed {
+		steps = append(steps, applyStep{
+			header:     fmt.Sprintf("Removing Node %q From Cluster", n.Host),
+			playbook:   "kubernetes-remove-node.yaml",
+			node:       n.Host,
+			plan:       copyPlan(current),
+			workerNode: n.Host,
+		})
+		if len(plan.Storage.Nodes) > 0 {
+			steps = append(steps, applyStep{
+				header:     "Updating Allowed IPs On Storage Volumes",
+				playbook:   "_volume-remove-allowed.yaml",
+				plan:       copyPlan(current),
+				workerNode: n.Host,
+			})
+		}
+		current = removeNodeFromPlan(current, n.Host)
+	}
+
+	// Etcd members are added one at a time
+	for _, n := range d.NodesAddedTo("etcd") {
+		current = addEtcdToPlan(current, n)
+		steps = append(steps, applyStep{
+			header:   fmt.Sprintf("Adding Etcd Node %q To Cluster", n.Host),
+			playbook: "kubernetes-etcd.yaml",
+			plan:     copyPlan(current),
+			etcdNode: n.Host,
+		})
+	}
+
+	// The remaining nodes are configured with the complete plan
+	newMasters := d.NodesAddedTo("master")
+	for _, n := range newMasters {
+		steps = append(steps, applyStep{
+			header:   fmt.Sprintf("Adding Master Node %q To Cluster", n.Host),
+			playbook: "kubernetes-master.yaml",
+			node:     n.Host,
+			plan:     plan,
+		})
+	}
+	reconfigureAPIServers := len(newMasters) > 0 || HasFieldChange(d.Cluster, "admin_password") || len(d.Authorization) > 0
+	if reconfigureAPIServers {
+		steps = append(steps, applyStep{
+			header:   "Reconfiguring API Servers",
+			playbook: "_apiserver.yaml",
+			plan:     plan,
+		})
+	}
+	added := []Node{}
+	for _, n := range uniqueNodes(d.NodesAdded) {
+		// The master playbook configures masters that are also workers
+		if isMasterNode(*plan, n) || isOnlyEtcdNode(*plan, n) {
+			continue
+		}
+		added = append(added, n)
+	}
+	for _, n := range added {
+		steps = append(steps, applyStep{
+			header:     fmt.Sprintf("Adding Node %q To Cluster", n.Host),
+			playbook:   "kubernetes-worker.yaml",
+			node:       n.Host,
+			plan:       plan,
+			workerNode: n.Host,
+		})
+		if hasRole(*plan, n, "ingress") && !hasRole(*plan, n, "worker") {
+			steps = append(steps, applyStep{
+				header:   fmt.Sprintf("Configuring Ingress Node %q", n.Host),
+				playbook: "_kubelet-ingress.yaml",
+				node:     n.Host,
+				plan:     plan,
+			})
+		}
+	}
+	if len(d.NodesAddedTo("storage")) > 0 {
+		steps = append(steps, applyStep{
+			header:   "Configuring Storage Cluster",
+			playbook: "_storage.yaml",
+			plan:     plan,
+		})
+	}
+	if len(d.NodesAddedTo("ingress")) > 0 {
+		steps = append(steps, applyStep{
+			header:   "Configuring Ingress",
+			playbook: "_addon-kubernetes-ingress.yaml",
+			plan:     plan,
+		})
+	}
+	// Allow access to the storage volumes from the new nodes
+	if len(previous.Storage.Nodes) > 0 && len(plan.Storage.Nodes) > 0 {
+		for _, n := range added {
+			steps = append(steps, applyStep{
+				header:     "Updating Allowed IPs On Storage Volumes",
+				playbook:   "_volume-update-allowed.yaml",
+				plan:       plan,
+				workerNode: n.Host,
+			})
+		}
+	}
+	nodesChanged := len(d.NodesAdded) > 0 || len(d.NodesRemoved) > 0
+	if plan.Cluster.Networking.UpdateHostsFiles && (nodesChanged || HasFieldChange(d.Networking, "update_hosts_files")) {
+		steps = append(steps, applyStep{
+			header:   "Updating Hosts Files On All Nodes",
+			playbook: "_hosts.yaml",
+			plan:     plan,
+		})
+	}
+	if HasFieldChange(d.Networking, "policy_enabled") {
+		steps = append(steps,
+			applyStep{
+				header:   "Reconfiguring Cluster Network",
+				playbook: "_calico.yaml",
+				plan:     plan,
+			},
+			applyStep{
+				header:   "Enabling Network Policy",
+				playbook: "_addon-network-policy.yaml",
+				plan:     plan,
+			})
+	}
+	if len(d.DockerRegistry) > 0 {
+		steps = append(steps, applyStep{
+			header:   "Reconfiguring Docker",
+			playbook: "_docker.yaml",
+			plan:     plan,
+		})
+		if plan.DockerRegistry.SetupInternal {
+			steps = append(steps, applyStep{
+				header:   "Configuring Internal Docker Registry",
+				playbook: "_docker-registry-container.yaml",
+				plan:     plan,
+			})
+		}
+	}
+	if len(d.NFSVolumesAdded) > 0 {
+		steps = append(steps, applyStep{
+			header:   "Adding NFS Volumes",
+			playbook: "_addon-nfs-volumes.yaml",
+			plan:     plan,
+		})
+	}
+	return steps
+}
+
+func isOnlyEtcdNode(p Plan, n Node) bool {
+	roles := p.GetNodeRoles(n.Host)
+	return len(roles) == 1 && roles[0] == "etcd"
+}
+
+func hasRole(p Plan, n Node, role string) bool {
+	for _, r := range p.GetNodeRoles(n.Host) {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the nodes of the changes, without duplicates
+func uniqueNodes(changes []NodeChange) []Node {
+	seen := map[string]bool{}
+	nodes := []Node{}
+	for _, c := range changes {
+		if seen[c.Node.Host] {
+			continue
+		}
+		seen[c.Node.Host] = true
+		nodes = append(nodes, c.Node)
+	}
+	return nodes
+}
+
+func copyPlan(p Plan) *Plan {
+	return &p
+}
@@ -0,0 +1,49 @@
+package install
+
+import "github.com/apprenda/kismatic/pkg/ansible"
+
+// the policies that are always granted: read-only access to the API
+// discovery paths for everyone, read-only access to the resources for the
+// monitoring add-on, and full access for the admin user and the service accounts
+var defaultAuthorizationPolicies = []ansible.AuthorizationPolicy{
+	{User: "*", NonResourcePath: "*", Readonly: true},
+	{User: monitoringUser, Namespace: "*", Resource: "*", APIGroup: "*", Readonly: true},
+	{User: "admin", Namespace: "*", Resource: "*", APIGroup: "*"},
+	{Group: "system:serviceaccounts", Namespace: "*", Resource: "*", APIGroup: "*", NonResourcePath: "*"},
+}
+
+// returns the ABAC policies of the API server, granting the access of
+// the users and groups of the plan
+func authorizationPolicies(p *Plan) []ansible.AuthorizationPolicy {
+	policies := append([]ansible.AuthorizationPolicy{}, defaultAuthorizationPolicies...)
+	for _, g := range p.Groups {
+		for _, a := range g.Access {
+			policy := accessPolicy(a)
+			policy.Group = g.Name
+			policies = append(policies, policy)
+		}
+	}
+	for _, u := range p.Users {
+		for _, a := range u.Access {
+			policy := accessPolicy(a)
+			policy.User = u.Name
+			policies = append(policies, policy)
+		}
+	}
+	return policies
+}
+
+func accessPolicy(a Access) ansible.AuthorizationPolicy {
+	policy := ansible.AuthorizationPolicy{
+		Namespace: a.Namespace,
+		Resource:  "*",
+		APIGroup:  "*",
+		Readonly:  a.ReadOnly,
+	}
+	// access to all namespaces includes the paths that are not resources,
+	// such as /healthz and /metrics
+	if a.Namespace == "*" {
+		policy.NonResourcePath = "*"
+	}
+	return policy
+}
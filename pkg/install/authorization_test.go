@@ -0,0 +1,32 @@
+package install
+
+import (
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestAuthorizationPolicies(t *testing.T) {
+	p := &Plan{
+		Groups: []Group{
+			{Name: "developers", Access: []Access{{Namespace: "dev"}, {Namespace: "prod", ReadOnly: true}}},
+		},
+		Users: []User{
+			{Name: "jane", Groups: []string{"developers"}, Access: []Access{{Namespace: "*"}}},
+		},
+	}
+	policies := authorizationPolicies(p)
+	expected := append(append([]ansible.AuthorizationPolicy{}, defaultAuthorizationPolicies...),
+		ansible.AuthorizationPolicy{Group: "developers", Namespace: "dev", Resource: "*", APIGroup: "*"},
+		ansible.AuthorizationPolicy{Group: "developers", Namespace: "prod", Resource: "*", APIGroup: "*", Readonly: true},
+		ansible.AuthorizationPolicy{User: "jane", Namespace: "*", Resource: "*", APIGroup: "*", NonResourcePath: "*"},
+	)
+	if len(policies) != len(expected) {
+		t.Fatalf("expected %d policies, got %d: %v", len(expected), len(policies), policies)
+	}
+	for i := range expected {
+		if policies[i] != expected[i] {
+			t.Errorf("policy %d: expected %+v, got %+v", i, expected[i], policies[i])
+		}
+	}
+}
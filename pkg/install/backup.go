@@ -0,0 +1,274 @@
+package install
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/ssh"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// the directory on the etcd node where backups are staged
+const remoteBackupDir = "/tmp/kismatic-backup"
+
+// BackupOptions are used to configure the backup
+type BackupOptions struct {
+	// PlanFile is the plan file that is added to the backup
+	PlanFile string
+	// GeneratedAssetsDirectory contains the keys that are added to the backup
+	GeneratedAssetsDirectory string
+	// RunsDirectory is where the backup is stored
+	RunsDirectory string
+}
+
+// CreateBackup takes a backup of the etcd clusters from the first etcd node
+// in the plan. The backup is archived with the plan file and the cluster
+// keys, and the path to the archive is returned.
+func CreateBackup(p *Plan, opts BackupOptions) (string, error) {
+	return createBackup(p, opts, p.GetSSHClient, time.Now())
+}
+
+func createBackup(p *Plan, opts BackupOptions, sshClient func(host string) (ssh.Client, error), now time.Time) (archive string, err error) {
+	if len(p.Etcd.Nodes) == 0 {
+		return "", fmt.Errorf("the plan does not have any etcd nodes")
+	}
+	etcdNode := p.Etcd.Nodes[0]
+	client, err := sshClient(etcdNode.Host)
+	if err != nil {
+		return "", err
+	}
+	backupDir := filepath.Join(opts.RunsDirectory, "backup", now.Format("2006-01-02-15-04-05"))
+	if err := os.MkdirAll(backupDir, 0777); err != nil {
+		return "", fmt.Errorf("error creating directory %q: %v", backupDir, err)
+	}
+	archive = filepath.Join(backupDir, "kismatic-backup.tar.gz")
+	f, err := os.Create(archive)
+	if err != nil {
+		return "", fmt.Errorf("error creating backup archive %q: %v", archive, err)
+	}
+	// Do not leave a partial archive behind if the backup fails
+	defer func() {
+		f.Close()
+		if err != nil {
+			os.RemoveAll(backupDir)
+		}
+	}()
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+
+	// The etcd backup is streamed over SSH as a base64 encoded tarball. A pty
+	// is not requested, as it would mangle the output.
+	out, err := client.Output(false, remoteEtcdBackupCommand())
+	if err != nil {
+		return "", fmt.Errorf("error taking etcd backup on node %q: %v: %s", etcdNode.Host, err, out)
+	}
+	etcdTarball, err := base64.StdEncoding.DecodeString(strings.TrimSpace(out))
+	if err != nil {
+		return "", fmt.Errorf("error decoding etcd backup received from node %q: %v", etcdNode.Host, err)
+	}
+	if err := copyTarball(tw, bytes.NewReader(etcdTarball), "etcd"); err != nil {
+		return "", fmt.Errorf("error adding etcd backup to archive: %v", err)
+	}
+	if err := addFileToTar(tw, opts.PlanFile, "kismatic-cluster.yaml"); err != nil {
+		return "", fmt.Errorf("error adding plan file to archive: %v", err)
+	}
+	keysDir := filepath.Join(opts.GeneratedAssetsDirectory, "keys")
+	err = filepath.Walk(keysDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(keysDir, path)
+		if err != nil {
+			return err
+		}
+		return addFileToTar(tw, path, filepath.Join("keys", rel))
+	})
+	if err != nil {
+		return "", fmt.Errorf("error adding keys to archive: %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		return "", fmt.Errorf("error writing backup archive: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		return "", fmt.Errorf("error writing backup archive: %v", err)
+	}
+	return archive, nil
+}
+
+// returns the command that backs up the data directory of each etcd cluster
+// and writes the compressed backups to stdout. The command fails if any of
+// the steps fails, and the staging directory is always removed.
+func remoteEtcdBackupCommand() string {
+	script := []string{
+		"set -eo pipefail",
+		fmt.Sprintf("trap \"rm -rf %s\" EXIT", remoteBackupDir),
+		fmt.Sprintf("rm -rf %s", remoteBackupDir),
+	}
+	for _, c := range etcdClusters {
+		script = append(script, fmt.Sprintf("/usr/bin/etcdctl backup --data-dir %s --backup-dir %s/%s > /dev/null 2>&1", c.DataDirectory, remoteBackupDir, c.Service))
+	}
+	script = append(script, fmt.Sprintf("tar czf - -C %s . 2>/dev/null | base64", remoteBackupDir))
+	return fmt.Sprintf("sudo bash -c '%s'", strings.Join(script, "; "))
+}
+
+// copies the entries of the gzipped tarball into the archive, under the prefix
+func copyTarball(tw *tar.Writer, r io.Reader, prefix string) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(filepath.Clean(hdr.Name), "./")
+		if name == "." {
+			continue
+		}
+		hdr.Name = filepath.Join(prefix, name)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func addFileToTar(tw *tar.Writer, file string, name string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// extracts the etcd backups found in the archive into the directory
+func extractEtcdBackup(archive string, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gr)
+	found := map[string]bool{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.Clean(hdr.Name)
+		if !strings.HasPrefix(name, "etcd"+string(filepath.Separator)) || strings.Contains(name, "..") {
+			continue
+		}
+		rel := strings.TrimPrefix(name, "etcd"+string(filepath.Separator))
+		found[strings.Split(rel, string(filepath.Separator))[0]] = true
+		target := filepath.Join(dir, rel)
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			out.Close()
+		}
+	}
+	for _, c := range etcdClusters {
+		if !found[c.Service] {
+			return fmt.Errorf("the archive does not contain a backup of %s", c.Service)
+		}
+	}
+	return nil
+}
+
+// RestoreEtcd rebuilds the etcd clusters from the backup archive. The first
+// etcd node is restored from the backup, and the other members are added
+// back to the cluster one at a time.
+func (ae *ansibleExecutor) RestoreEtcd(p *Plan, archive string) error {
+	runDirectory, err := ae.createRunDirectory("restore")
+	if err != nil {
+		return fmt.Errorf("error creating working directory for restore: %v", err)
+	}
+	fp := FilePlanner{
+		File: filepath.Join(runDirectory, "kismatic-cluster.yaml"),
+	}
+	if err = fp.Write(p); err != nil {
+		return fmt.Errorf("error recording plan file to %s: %v", fp.File, err)
+	}
+	backupDir, err := filepath.Abs(filepath.Join(runDirectory, "etcd-backup"))
+	if err != nil {
+		return fmt.Errorf("error getting absolute path of backup directory: %v", err)
+	}
+	// The backup is copied to the etcd nodes by the playbook, so the
+	// extracted copy is not kept around once the restore is done
+	defer os.RemoveAll(backupDir)
+	if err = extractEtcdBackup(archive, backupDir); err != nil {
+		return fmt.Errorf("error extracting etcd backup from %q: %v", archive, err)
+	}
+	inventory := buildInventoryFromPlan(p)
+	cc, err := ae.buildInstallExtraVars(p)
+	if err != nil {
+		return fmt.Errorf("failed to generate ansible vars: %v", err)
+	}
+	cc.EtcdBackupDir = backupDir
+	// The etcd clients must reconnect to the restored clusters
+	cc.ForceAPIServerRestart = true
+	cc.ForceCalicoNodeRestart = true
+	ansibleLogFilename := filepath.Join(runDirectory, "ansible.log")
+	ansibleLogFile, err := os.Create(ansibleLogFilename)
+	if err != nil {
+		return fmt.Errorf("error creating ansible log file %q: %v", ansibleLogFilename, err)
+	}
+	util.PrintHeader(ae.stdout, "Restoring Etcd Clusters", '=')
+	eventExplainer := &explain.DefaultEventExplainer{}
+	if err = ae.runPlaybookWithExplainer("restore-etcd.yaml", eventExplainer, inventory, *cc, ansibleLogFile, runDirectory); err != nil {
+		return fmt.Errorf("error restoring etcd clusters: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package install
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/ssh"
+)
+
+// returns the base64 encoded tarball that is expected from the etcd node
+func fakeEtcdBackupOutput(t *testing.T, clusters ...string) string {
+	buf := &bytes.Buffer{}
+	gw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gw)
+	for _, c := range clusters {
+		content := []byte("data of " + c)
+		hdr := &tar.Header{
+			Name: "./" + c + "/member/snap/db",
+			Mode: 0600,
+			Size: int64(len(content)),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("error writing tar header: %v", err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			t.Fatalf("error writing tar content: %v", err)
+		}
+	}
+	tw.Close()
+	gw.Close()
+	return base64.StdEncoding.EncodeToString(buf.Bytes()) + "\r\n"
+}
+
+func backupTestFiles(t *testing.T) (planFile string, assetsDir string) {
+	dir := mustGetTempDir(t)
+	planFile = filepath.Join(dir, "kismatic-cluster.yaml")
+	p := diffTestPlan()
+	fp := FilePlanner{File: planFile}
+	if err := fp.Write(&p); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	assetsDir = filepath.Join(dir, "generated")
+	if err := os.MkdirAll(filepath.Join(assetsDir, "keys"), 0777); err != nil {
+		t.Fatalf("error creating keys dir: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(assetsDir, "keys", "ca.pem"), []byte("ca"), 0644); err != nil {
+		t.Fatalf("error writing key: %v", err)
+	}
+	return planFile, assetsDir
+}
+
+func TestCreateBackup(t *testing.T) {
+	planFile, assetsDir := backupTestFiles(t)
+	runsDir := mustGetTempDir(t)
+	defer os.RemoveAll(runsDir)
+	var backupHost string
+	sshClient := func(host string) (ssh.Client, error) {
+		backupHost = host
+		return &fakeSSHClient{output: fakeEtcdBackupOutput(t, "etcd_k8s", "etcd_networking")}, nil
+	}
+	opts := BackupOptions{
+		PlanFile:                 planFile,
+		GeneratedAssetsDirectory: assetsDir,
+		RunsDirectory:            runsDir,
+	}
+	p := diffTestPlan()
+	now := time.Date(2017, 1, 27, 10, 0, 0, 0, time.UTC)
+	archive, err := createBackup(&p, opts, sshClient, now)
+	if err != nil {
+		t.Fatalf("unexpected error creating backup: %v", err)
+	}
+	if backupHost != "etcd01" {
+		t.Errorf("expected backup to be taken on etcd01, but was taken on %q", backupHost)
+	}
+	expectedArchive := filepath.Join(runsDir, "backup", "2017-01-27-10-00-00", "kismatic-backup.tar.gz")
+	if archive != expectedArchive {
+		t.Errorf("expected archive %q, got %q", expectedArchive, archive)
+	}
+
+	// verify the contents of the archive
+	f, err := os.Open(archive)
+	if err != nil {
+		t.Fatalf("error opening archive: %v", err)
+	}
+	defer f.Close()
+	gr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("error reading archive: %v", err)
+	}
+	tr := tar.NewReader(gr)
+	files := map[string]bool{}
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		files[hdr.Name] = true
+	}
+	expected := []string{
+		"etcd/etcd_k8s/member/snap/db",
+		"etcd/etcd_networking/member/snap/db",
+		"kismatic-cluster.yaml",
+		"keys/ca.pem",
+	}
+	for _, e := range expected {
+		if !files[e] {
+			t.Errorf("expected %q in archive, got %v", e, files)
+		}
+	}
+
+	// the etcd backups can be extracted for restoring
+	restoreDir := mustGetTempDir(t)
+	defer os.RemoveAll(restoreDir)
+	if err := extractEtcdBackup(archive, restoreDir); err != nil {
+		t.Fatalf("unexpected error extracting backup: %v", err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(restoreDir, "etcd_k8s", "member", "snap", "db"))
+	if err != nil {
+		t.Fatalf("error reading extracted backup: %v", err)
+	}
+	if string(data) != "data of etcd_k8s" {
+		t.Errorf("unexpected content in extracted backup: %q", string(data))
+	}
+}
+
+func TestExtractEtcdBackupMissingCluster(t *testing.T) {
+	planFile, assetsDir := backupTestFiles(t)
+	runsDir := mustGetTempDir(t)
+	defer os.RemoveAll(runsDir)
+	sshClient := func(host string) (ssh.Client, error) {
+		return &fakeSSHClient{output: fakeEtcdBackupOutput(t, "etcd_k8s")}, nil
+	}
+	opts := BackupOptions{
+		PlanFile:                 planFile,
+		GeneratedAssetsDirectory: assetsDir,
+		RunsDirectory:            runsDir,
+	}
+	p := diffTestPlan()
+	archive, err := createBackup(&p, opts, sshClient, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error creating backup: %v", err)
+	}
+	if err := extractEtcdBackup(archive, mustGetTempDir(t)); err == nil {
+		t.Errorf("expected an error when the networking backup is missing")
+	}
+}
+
+func TestCreateBackupNoEtcdNodes(t *testing.T) {
+	p := Plan{}
+	sshClient := func(host string) (ssh.Client, error) {
+		t.Errorf("unexpected SSH connection to %q", host)
+		return &fakeSSHClient{}, nil
+	}
+	if _, err := createBackup(&p, BackupOptions{}, sshClient, time.Now()); err == nil {
+		t.Errorf("expected an error when there are no etcd nodes")
+	}
+}
+
+// records the commands that are run on the node
+type recordingSSHClient struct {
+	fakeSSHClient
+	pty  bool
+	cmds []string
+}
+
+func (c *recordingSSHClient) Output(pty bool, args ...string) (string, error) {
+	c.pty = pty
+	c.cmds = append(c.cmds, strings.Join(args, " "))
+	return c.fakeSSHClient.Output(pty, args...)
+}
+
+func TestCreateBackupRemoteCommand(t *testing.T) {
+	planFile, assetsDir := backupTestFiles(t)
+	runsDir := mustGetTempDir(t)
+	defer os.RemoveAll(runsDir)
+	client := &recordingSSHClient{fakeSSHClient: fakeSSHClient{output: fakeEtcdBackupOutput(t, "etcd_k8s", "etcd_networking")}}
+	sshClient := func(host string) (ssh.Client, error) {
+		return client, nil
+	}
+	opts := BackupOptions{
+		PlanFile:                 planFile,
+		GeneratedAssetsDirectory: assetsDir,
+		RunsDirectory:            runsDir,
+	}
+	p := diffTestPlan()
+	if _, err := createBackup(&p, opts, sshClient, time.Now()); err != nil {
+		t.Fatalf("unexpected error creating backup: %v", err)
+	}
+	if client.pty {
+		t.Errorf("expected the backup to be streamed without a pty")
+	}
+	if len(client.cmds) != 1 {
+		t.Fatalf("expected one command to be run on the node, got %v", client.cmds)
+	}
+	cmd := client.cmds[0]
+	expected := []string{
+		"set -eo pipefail",
+		"trap \"rm -rf /tmp/kismatic-backup\" EXIT",
+		"--backup-dir /tmp/kismatic-backup/etcd_k8s",
+		"--backup-dir /tmp/kismatic-backup/etcd_networking",
+	}
+	for _, e := range expected {
+		if !strings.Contains(cmd, e) {
+			t.Errorf("expected the command to contain %q, got %q", e, cmd)
+		}
+	}
+}
+
+func TestCreateBackupFailureRemovesArchive(t *testing.T) {
+	planFile, assetsDir := backupTestFiles(t)
+	runsDir := mustGetTempDir(t)
+	defer os.RemoveAll(runsDir)
+	sshClient := func(host string) (ssh.Client, error) {
+		return &fakeSSHClient{output: "etcdctl: command not found", err: errors.New("exit status 127")}, nil
+	}
+	opts := BackupOptions{
+		PlanFile:                 planFile,
+		GeneratedAssetsDirectory: assetsDir,
+		RunsDirectory:            runsDir,
+	}
+	p := diffTestPlan()
+	now := time.Date(2017, 1, 27, 10, 0, 0, 0, time.UTC)
+	if _, err := createBackup(&p, opts, sshClient, now); err == nil {
+		t.Fatalf("expected an error when the backup fails on the node")
+	}
+	backupDir := filepath.Join(runsDir, "backup", "2017-01-27-10-00-00")
+	if _, err := os.Stat(backupDir); !os.IsNotExist(err) {
+		t.Errorf("expected the partial backup %q to be removed, got %v", backupDir, err)
+	}
+}
+
+func TestRestoreEtcdThreeNodes(t *testing.T) {
+	planFile, assetsDir := backupTestFiles(t)
+	runsDir := mustGetTempDir(t)
+	defer os.RemoveAll(runsDir)
+	sshClient := func(host string) (ssh.Client, error) {
+		return &fakeSSHClient{output: fakeEtcdBackupOutput(t, "etcd_k8s", "etcd_networking")}, nil
+	}
+	opts := BackupOptions{
+		PlanFile:                 planFile,
+		GeneratedAssetsDirectory: assetsDir,
+		RunsDirectory:            runsDir,
+	}
+	p := diffTestPlan()
+	p.Etcd = NodeGroup{
+		ExpectedCount: 3,
+		Nodes: []Node{
+			{Host: "etcd01", IP: "10.0.0.1"},
+			{Host: "etcd02", IP: "10.0.0.4"},
+			{Host: "etcd03", IP: "10.0.0.5"},
+		},
+	}
+	archive, err := createBackup(&p, opts, sshClient, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error creating backup: %v", err)
+	}
+
+	fakeRunner := fakeRunner{}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: runsDir},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		certsDir: assetsDir,
+	}
+	if err := e.RestoreEtcd(&p, archive); err != nil {
+		t.Fatalf("unexpected error restoring etcd: %v", err)
+	}
+	expected := []string{"restore-etcd.yaml"}
+	if !reflect.DeepEqual(fakeRunner.allNodesPlaybooks, expected) {
+		t.Errorf("expected %v to run, got %v", expected, fakeRunner.allNodesPlaybooks)
+	}
+	// The members are added to the restored cluster one at a time, in the
+	// order of the etcd group, each one joining the members added before it
+	var etcdHosts []string
+	for _, r := range fakeRunner.incomingInventory.Roles {
+		if r.Name != "etcd" {
+			continue
+		}
+		for _, n := range r.Nodes {
+			etcdHosts = append(etcdHosts, n.Host)
+		}
+	}
+	expectedHosts := []string{"etcd01", "etcd02", "etcd03"}
+	if !reflect.DeepEqual(etcdHosts, expectedHosts) {
+		t.Errorf("expected etcd group %v, got %v", expectedHosts, etcdHosts)
+	}
+}
@@ -0,0 +1,231 @@
+package install
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
+	"github.com/apprenda/kismatic/pkg/tls"
+)
+
+// CertificateInfo describes a certificate of the cluster
+type CertificateInfo struct {
+	// Name of the certificate, i.e. the file name without the extension
+	Name string `json:"name"`
+	// Node where the certificate is deployed. Empty for the certificates
+	// in the generated keys directory.
+	Node         string    `json:"node,omitempty"`
+	Path         string    `json:"path"`
+	CommonName   string    `json:"commonName"`
+	SANs         []string  `json:"sans"`
+	Issuer       string    `json:"issuer"`
+	NotAfter     time.Time `json:"notAfter"`
+	DaysToExpiry int       `json:"daysToExpiry"`
+	// MatchesPlan is true if the certificate contains the CN and SANs
+	// that would be generated for the current plan
+	MatchesPlan bool     `json:"matchesPlan"`
+	MissingSANs []string `json:"missingSANs,omitempty"`
+}
+
+// the certificates that are deployed on a node, keyed by path
+func deployedCertificates(p *Plan, n Node) map[string]string {
+	certs := map[string]string{}
+	roles := p.GetNodeRoles(n.Host)
+	for _, r := range roles {
+		switch r {
+		case "etcd":
+			for _, c := range p.EtcdClusters() {
+				certs[c.CertsDirectory+"/etcd.pem"] = n.Host
+				certs[c.CertsDirectory+"/ca.pem"] = "ca"
+			}
+		default:
+			certs["/etc/kubernetes/kubenode.pem"] = n.Host
+			certs["/etc/kubernetes/ca.pem"] = "ca"
+			certs["/etc/kubernetes/service-account.pem"] = "service-account"
+		}
+	}
+	if p.DockerRegistry.SetupInternal && len(p.Master.Nodes) > 0 && p.Master.Nodes[0].Host == n.Host {
+		certs["/etc/docker/docker.pem"] = "docker"
+	}
+	return certs
+}
+
+// ListCertificates returns the certificates found in the directory,
+// compared against the certificates that would be generated for the plan
+func ListCertificates(p *Plan, certsDir string) ([]CertificateInfo, error) {
+	return listCertificates(p, certsDir, time.Now())
+}
+
+func listCertificates(p *Plan, certsDir string, now time.Time) ([]CertificateInfo, error) {
+	files, err := filepath.Glob(filepath.Join(certsDir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing certificates in %q: %v", certsDir, err)
+	}
+	sort.Strings(files)
+	certs := []CertificateInfo{}
+	for _, f := range files {
+		if strings.HasSuffix(f, "-key.pem") || filepath.Base(f) == caChainName+".pem" {
+			continue
+		}
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("error reading certificate %q: %v", f, err)
+		}
+		name := strings.TrimSuffix(filepath.Base(f), ".pem")
+		info, err := certificateInfo(p, name, "", f, b, now)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, *info)
+	}
+	return certs, nil
+}
+
+// ListDeployedCertificates connects to each node in the plan and returns
+// the certificates that are deployed on it
+func ListDeployedCertificates(p *Plan) ([]CertificateInfo, error) {
+	return listDeployedCertificates(p, p.GetSSHClient, time.Now())
+}
+
+func listDeployedCertificates(p *Plan, sshClient func(host string) (ssh.Client, error), now time.Time) ([]CertificateInfo, error) {
+	certs := []CertificateInfo{}
+	for _, n := range p.GetUniqueNodes() {
+		deployed := deployedCertificates(p, n)
+		paths := []string{}
+		for path := range deployed {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		client, err := sshClient(n.Host)
+		if err != nil {
+			return nil, err
+		}
+		// head prints the name of each file before its contents, and missing files are skipped
+		out, err := client.Output(true, fmt.Sprintf("sudo head -v -n 1000 %s 2>/dev/null; true", strings.Join(paths, " ")))
+		if err != nil {
+			return nil, fmt.Errorf("error reading certificates on node %q: %v", n.Host, err)
+		}
+		files := splitHeadOutput(out)
+		for _, path := range paths {
+			content, ok := files[path]
+			if !ok {
+				continue
+			}
+			info, err := certificateInfo(p, deployed[path], n.Host, path, []byte(content), now)
+			if err != nil {
+				return nil, fmt.Errorf("error reading certificate %q on node %q: %v", path, n.Host, err)
+			}
+			certs = append(certs, *info)
+		}
+	}
+	return certs, nil
+}
+
+// splits the output of "head -v" into the contents of each file
+func splitHeadOutput(out string) map[string]string {
+	files := map[string]string{}
+	var current string
+	for _, line := range strings.Split(strings.Replace(out, "\r", "", -1), "\n") {
+		if strings.HasPrefix(line, "==> ") && strings.HasSuffix(line, " <==") {
+			current = strings.TrimSuffix(strings.TrimPrefix(line, "==> "), " <==")
+			continue
+		}
+		if current != "" {
+			files[current] += line + "\n"
+		}
+	}
+	return files
+}
+
+func certificateInfo(p *Plan, name, node, path string, certPEM []byte, now time.Time) (*CertificateInfo, error) {
+	cert, err := tls.ParseLeafCertificatePEM(certPEM)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing certificate %q: %v", path, err)
+	}
+	info := &CertificateInfo{
+		Name:         name,
+		Node:         node,
+		Path:         path,
+		CommonName:   cert.Subject.CommonName,
+		SANs:         certificateSANs(cert),
+		Issuer:       cert.Issuer.CommonName,
+		NotAfter:     cert.NotAfter,
+		DaysToExpiry: int(cert.NotAfter.Sub(now).Hours() / 24),
+		MatchesPlan:  true,
+	}
+	expectedCN, expectedSANs, err := expectedCertificateNames(p, name)
+	if err != nil {
+		return nil, err
+	}
+	if expectedCN != "" && expectedCN != info.CommonName {
+		info.MatchesPlan = false
+	}
+	for _, san := range expectedSANs {
+		if !containsString(info.SANs, san) {
+			info.MissingSANs = append(info.MissingSANs, san)
+			info.MatchesPlan = false
+		}
+	}
+	return info, nil
+}
+
+// returns the CN and SANs that would be generated for the certificate with the
+// given name. An empty CN is returned when there are no expectations,
+// such as for the CA.
+func expectedCertificateNames(p *Plan, name string) (string, []string, error) {
+	switch name {
+	case "ca":
+		return "", nil, nil
+	case "service-account":
+		return "kube-service-account", nil, nil
+	case "docker":
+		if len(p.Master.Nodes) == 0 {
+			return "", nil, nil
+		}
+		n := p.Master.Nodes[0]
+		return n.Host, []string{n.Host, n.IP, n.InternalIP}, nil
+	}
+	for _, n := range p.GetUniqueNodes() {
+		if n.Host == name {
+			sans, err := nodeCertSubjectAlternateNames(p, n)
+			if err != nil {
+				return "", nil, err
+			}
+			return n.Host, nonEmpty(sans), nil
+		}
+	}
+	// user certificates
+	return name, []string{name}, nil
+}
+
+func certificateSANs(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
+
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func nonEmpty(list []string) []string {
+	res := []string{}
+	for _, s := range list {
+		if s != "" {
+			res = append(res, s)
+		}
+	}
+	return res
+}
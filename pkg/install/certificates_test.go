@@ -0,0 +1,145 @@
+package install
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
+)
+
+func generateTestCertificates(t *testing.T, p *Plan) LocalPKI {
+	pki := getPKI(t)
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+	return pki
+}
+
+func findCertificate(certs []CertificateInfo, name, node string) *CertificateInfo {
+	for _, c := range certs {
+		if c.Name == name && c.Node == node {
+			return &c
+		}
+	}
+	return nil
+}
+
+func TestListCertificates(t *testing.T) {
+	p := getPlan()
+	pki := generateTestCertificates(t, p)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	certs, err := listCertificates(p, pki.GeneratedCertsDirectory, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error listing certificates: %v", err)
+	}
+	for _, name := range []string{"ca", "etcd", "master", "worker", "admin", "service-account"} {
+		c := findCertificate(certs, name, "")
+		if c == nil {
+			t.Errorf("certificate %q was not listed", name)
+			continue
+		}
+		if !c.MatchesPlan {
+			t.Errorf("expected certificate %q to match the plan, but was missing %v", name, c.MissingSANs)
+		}
+	}
+	for _, c := range certs {
+		if strings.HasSuffix(c.Name, "-key") {
+			t.Errorf("private key %q was listed as a certificate", c.Path)
+		}
+	}
+	master := findCertificate(certs, "master", "")
+	if master == nil {
+		t.Fatalf("master certificate was not listed")
+	}
+	if !containsString(master.SANs, "someFQDN") {
+		t.Errorf("expected the master certificate SANs to contain the load balanced FQDN, got %v", master.SANs)
+	}
+
+	// the days to expiry are counted from the given time
+	now := master.NotAfter.Add(-30*24*time.Hour - time.Hour)
+	certs, err = listCertificates(p, pki.GeneratedCertsDirectory, now)
+	if err != nil {
+		t.Fatalf("unexpected error listing certificates: %v", err)
+	}
+	if c := findCertificate(certs, "master", ""); c.DaysToExpiry != 30 {
+		t.Errorf("expected master certificate to expire in 30 days, got %d", c.DaysToExpiry)
+	}
+}
+
+func TestListCertificatesPlanChanged(t *testing.T) {
+	p := getPlan()
+	pki := generateTestCertificates(t, p)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	p.Master.LoadBalancedFQDN = "newFQDN"
+	certs, err := listCertificates(p, pki.GeneratedCertsDirectory, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error listing certificates: %v", err)
+	}
+	master := findCertificate(certs, "master", "")
+	if master == nil {
+		t.Fatalf("master certificate was not listed")
+	}
+	if master.MatchesPlan {
+		t.Errorf("expected master certificate to not match the plan")
+	}
+	if len(master.MissingSANs) != 1 || master.MissingSANs[0] != "newFQDN" {
+		t.Errorf("expected newFQDN to be missing, got %v", master.MissingSANs)
+	}
+	worker := findCertificate(certs, "worker", "")
+	if worker == nil || !worker.MatchesPlan {
+		t.Errorf("expected worker certificate to match the plan")
+	}
+}
+
+func TestListDeployedCertificates(t *testing.T) {
+	p := getPlan()
+	pki := generateTestCertificates(t, p)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	workerCert, err := ioutil.ReadFile(filepath.Join(pki.GeneratedCertsDirectory, "worker.pem"))
+	if err != nil {
+		t.Fatalf("error reading certificate: %v", err)
+	}
+	caCert, err := ioutil.ReadFile(filepath.Join(pki.GeneratedCertsDirectory, "ca.pem"))
+	if err != nil {
+		t.Fatalf("error reading certificate: %v", err)
+	}
+	// the service account certificate is missing on the node
+	output := "==> /etc/kubernetes/ca.pem <==\r\n" + strings.Replace(string(caCert), "\n", "\r\n", -1) + "\r\n" +
+		"==> /etc/kubernetes/kubenode.pem <==\r\n" + strings.Replace(string(workerCert), "\n", "\r\n", -1)
+	sshClient := func(host string) (ssh.Client, error) {
+		if host == "worker" {
+			return &fakeSSHClient{output: output}, nil
+		}
+		return &fakeSSHClient{}, nil
+	}
+	certs, err := listDeployedCertificates(p, sshClient, time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error listing deployed certificates: %v", err)
+	}
+	if len(certs) != 2 {
+		t.Fatalf("expected 2 certificates, got %d: %v", len(certs), certs)
+	}
+	kubenode := findCertificate(certs, "worker", "worker")
+	if kubenode == nil {
+		t.Fatalf("deployed node certificate was not listed")
+	}
+	if kubenode.Path != "/etc/kubernetes/kubenode.pem" {
+		t.Errorf("unexpected path %q", kubenode.Path)
+	}
+	if !kubenode.MatchesPlan {
+		t.Errorf("expected deployed certificate to match the plan, but was missing %v", kubenode.MissingSANs)
+	}
+	if findCertificate(certs, "ca", "worker") == nil {
+		t.Errorf("deployed CA certificate was not listed")
+	}
+}
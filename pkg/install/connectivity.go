@@ -0,0 +1,134 @@
+package install
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/inspector"
+)
+
+// inspectorServerPort is the port of the inspector server that is started
+// on the nodes during preflight
+const inspectorServerPort = 8888
+
+// A connectivityRule defines a port that must be reachable on the nodes of
+// one role from the nodes of another role
+type connectivityRule struct {
+	from    []string
+	to      []string
+	port    int
+	service string
+	// when set, only the node with this host accepts the connections
+	toHost string
+}
+
+var clusterRoles = []string{"master", "worker", "ingress", "storage"}
+
+// the roles of the nodes that run pods
+var podRoles = []string{"worker", "ingress", "storage"}
+
+// returns the ports that must be reachable between the nodes. The ports are
+// the ones given to ansible in the cluster catalog.
+func connectivityRules(p *Plan, cc ansible.ClusterCatalog) []connectivityRule {
+	rules := []connectivityRule{
+		{from: []string{"etcd"}, to: []string{"etcd"}, port: cc.EtcdK8sPeerPort, service: "etcd peer"},
+		{from: []string{"etcd"}, to: []string{"etcd"}, port: cc.EtcdNetworkingPeerPort, service: "networking etcd peer"},
+		{from: []string{"master"}, to: []string{"etcd"}, port: cc.EtcdK8sClientPort, service: "etcd client"},
+		{from: clusterRoles, to: []string{"etcd"}, port: cc.EtcdNetworkingClientPort, service: "networking etcd client"},
+		{from: podRoles, to: []string{"master"}, port: cc.APIServerPort, service: "kubernetes API server"},
+		{from: []string{"master"}, to: clusterRoles, port: kubeletPort, service: "kubelet"},
+		{from: clusterRoles, to: clusterRoles, port: calicoBGPPort, service: "calico BGP"},
+	}
+	if p.Monitoring.Enabled {
+		// Prometheus runs in a pod, and scrapes calico-node on all nodes
+		rules = append(rules, connectivityRule{from: podRoles, to: clusterRoles, port: cc.CalicoFelixMetricsPort, service: "calico-node metrics"})
+	}
+	if p.DockerRegistry.SetupInternal && len(p.Master.Nodes) > 0 {
+		if port, err := strconv.Atoi(cc.DockerRegistryPort); err == nil {
+			rules = append(rules, connectivityRule{from: clusterRoles, to: []string{"master"}, toHost: p.Master.Nodes[0].Host, port: port, service: "docker registry"})
+		}
+	}
+	return rules
+}
+
+// buildConnectivityMatrix returns the ports that must be reachable between
+// the nodes of the plan, according to the roles of the nodes
+func buildConnectivityMatrix(p *Plan, cc ansible.ClusterCatalog) inspector.ConnectivityMatrix {
+	m := inspector.ConnectivityMatrix{
+		Nodes:   []inspector.ConnectivityNode{},
+		Probes:  []inspector.ConnectivityProbe{},
+		Timeout: inspector.DefaultConnectivityTimeout,
+	}
+	nodes := p.GetUniqueNodes()
+	for _, n := range nodes {
+		ip := n.InternalIP
+		if ip == "" {
+			ip = n.IP
+		}
+		m.Nodes = append(m.Nodes, inspector.ConnectivityNode{
+			Host:      n.Host,
+			IP:        ip,
+			Inspector: net.JoinHostPort(n.IP, strconv.Itoa(inspectorServerPort)),
+		})
+	}
+	type probe struct {
+		from, to string
+		port     int
+	}
+	seen := map[probe]bool{}
+	for _, r := range connectivityRules(p, cc) {
+		for _, from := range nodes {
+			if !hasAnyRole(p.GetNodeRoles(from.Host), r.from) {
+				continue
+			}
+			for _, to := range nodes {
+				if from.Host == to.Host || !hasAnyRole(p.GetNodeRoles(to.Host), r.to) {
+					continue
+				}
+				if r.toHost != "" && r.toHost != to.Host {
+					continue
+				}
+				k := probe{from: from.Host, to: to.Host, port: r.port}
+				if seen[k] {
+					continue
+				}
+				seen[k] = true
+				m.Probes = append(m.Probes, inspector.ConnectivityProbe{
+					From:    from.Host,
+					To:      to.Host,
+					Port:    r.port,
+					Service: r.service,
+				})
+			}
+		}
+	}
+	return m
+}
+
+func hasAnyRole(roles []string, want []string) bool {
+	for _, r := range roles {
+		for _, w := range want {
+			if r == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeConnectivityMatrix writes the connectivity matrix of the plan
+// to the file as JSON
+func writeConnectivityMatrix(p *Plan, cc ansible.ClusterCatalog, file string) error {
+	d, err := json.MarshalIndent(buildConnectivityMatrix(p, cc), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling connectivity matrix: %v", err)
+	}
+	if err := ioutil.WriteFile(file, d, 0644); err != nil {
+		return fmt.Errorf("error writing connectivity matrix to %q: %v", file, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package install
+
+import (
+	"os"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/inspector"
+)
+
+func TestBuildConnectivityMatrix(t *testing.T) {
+	p := &Plan{}
+	p.Etcd.Nodes = []Node{{Host: "etcd01", IP: "10.0.0.1"}, {Host: "etcd02", IP: "10.0.0.2"}}
+	p.Master.Nodes = []Node{{Host: "master01", IP: "10.0.0.3", InternalIP: "192.168.0.3"}}
+	p.Worker.Nodes = []Node{{Host: "worker01", IP: "10.0.0.4"}, {Host: "worker02", IP: "10.0.0.5"}}
+	// The worker is also an ingress node
+	p.Ingress.Nodes = []Node{{Host: "worker01", IP: "10.0.0.4"}}
+	p.Cluster.Networking.ServiceCIDRBlock = "172.16.0.0/16"
+
+	m := buildConnectivityMatrix(p, mustBuildCatalog(t, p))
+	if len(m.Nodes) != 5 {
+		t.Fatalf("expected 5 nodes, got %d", len(m.Nodes))
+	}
+	for _, n := range m.Nodes {
+		if n.Host == "master01" && (n.IP != "192.168.0.3" || n.Inspector != "10.0.0.3:8888") {
+			t.Errorf("expected the internal IP to be probed, and the inspector to use the IP, got %+v", n)
+		}
+	}
+	if errs := m.Validate(); len(errs) != 0 {
+		t.Errorf("expected a valid matrix, got %v", errs)
+	}
+
+	probes := map[inspector.ConnectivityProbe]bool{}
+	for _, pr := range m.Probes {
+		if pr.From == pr.To {
+			t.Errorf("unexpected probe from a node to itself: %+v", pr)
+		}
+		pr.Service = ""
+		if probes[pr] {
+			t.Errorf("duplicate probe %+v", pr)
+		}
+		probes[pr] = true
+	}
+	expected := []inspector.ConnectivityProbe{
+		{From: "etcd01", To: "etcd02", Port: 2380},
+		{From: "etcd02", To: "etcd01", Port: 6660},
+		{From: "master01", To: "etcd01", Port: 2379},
+		{From: "worker01", To: "etcd02", Port: 6666},
+		{From: "worker02", To: "master01", Port: 6443},
+		{From: "master01", To: "worker02", Port: 10250},
+		{From: "worker01", To: "worker02", Port: 179},
+		{From: "worker02", To: "master01", Port: 179},
+	}
+	for _, e := range expected {
+		if !probes[e] {
+			t.Errorf("expected probe %+v in the matrix", e)
+		}
+	}
+	unexpected := []inspector.ConnectivityProbe{
+		{From: "worker01", To: "etcd01", Port: 2379},
+		{From: "etcd01", To: "master01", Port: 10250},
+		{From: "master01", To: "etcd01", Port: 179},
+		{From: "worker01", To: "worker02", Port: 10250},
+	}
+	for _, u := range unexpected {
+		if probes[u] {
+			t.Errorf("unexpected probe %+v in the matrix", u)
+		}
+	}
+}
+
+func TestBuildConnectivityMatrixFromPlan(t *testing.T) {
+	p := &Plan{}
+	p.Etcd.Nodes = []Node{{Host: "etcd01", IP: "10.0.0.1"}}
+	p.Master.Nodes = []Node{{Host: "master01", IP: "10.0.0.2"}, {Host: "master02", IP: "10.0.0.3"}}
+	p.Worker.Nodes = []Node{{Host: "worker01", IP: "10.0.0.4"}}
+	p.Cluster.Networking.ServiceCIDRBlock = "172.16.0.0/16"
+	p.Monitoring.Enabled = true
+	p.DockerRegistry.SetupInternal = true
+
+	cc := mustBuildCatalog(t, p)
+	// The ports are taken from the catalog that is given to ansible
+	cc.APIServerPort = 16443
+	m := buildConnectivityMatrix(p, cc)
+	probes := map[inspector.ConnectivityProbe]bool{}
+	for _, pr := range m.Probes {
+		pr.Service = ""
+		probes[pr] = true
+	}
+	expected := []inspector.ConnectivityProbe{
+		{From: "worker01", To: "master01", Port: 16443},
+		{From: "worker01", To: "master02", Port: calicoFelixMetricsPort},
+		{From: "worker01", To: "master01", Port: internalDockerRegistryPort},
+		{From: "master02", To: "master01", Port: internalDockerRegistryPort},
+	}
+	for _, e := range expected {
+		if !probes[e] {
+			t.Errorf("expected probe %+v in the matrix", e)
+		}
+	}
+	unexpected := []inspector.ConnectivityProbe{
+		{From: "worker01", To: "master01", Port: apiServerPort},
+		// The registry only runs on the first master
+		{From: "worker01", To: "master02", Port: internalDockerRegistryPort},
+		{From: "master01", To: "worker01", Port: calicoFelixMetricsPort},
+	}
+	for _, u := range unexpected {
+		if probes[u] {
+			t.Errorf("unexpected probe %+v in the matrix", u)
+		}
+	}
+}
+
+func mustBuildCatalog(t *testing.T, p *Plan) ansible.ClusterCatalog {
+	certsDir := mustGetTempDir(t)
+	defer os.RemoveAll(certsDir)
+	ae := ansibleExecutor{certsDir: certsDir}
+	cc, err := ae.buildInstallExtraVars(p)
+	if err != nil {
+		t.Fatalf("error building cluster catalog: %v", err)
+	}
+	return *cc
+}
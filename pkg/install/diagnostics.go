@@ -0,0 +1,249 @@
+package install
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// the number of journal lines that are collected for each service
+const diagnosticsJournalLines = 5000
+
+// DiagnosticsOptions are used to configure the collection of diagnostics
+type DiagnosticsOptions struct {
+	// PlanFile is the plan file that is added to the diagnostics
+	PlanFile string
+	// RunDirectory is the run directory whose known files are added to the
+	// diagnostics. It is not added when empty.
+	RunDirectory string
+}
+
+// the files of a run directory that are added to the diagnostics
+var runDiagnosticFiles = []string{
+	"kismatic-cluster.yaml",
+	"ansible.log",
+	"inventory.ini",
+	"clustercatalog.yaml",
+	"connectivity-matrix.json",
+	playProgressFile,
+	successfulRunMarker,
+}
+
+// a diagnostic that is collected from a node by running a command
+type nodeDiagnostic struct {
+	file    string
+	command string
+}
+
+// CollectDiagnostics collects the logs and the state of the services
+// installed on every node of the plan, and writes them together with the
+// plan file and the known files of the run directory to a gzipped tarball. Secrets, such as
+// the admin password and private keys, are redacted. Diagnostics that
+// cannot be collected are recorded in the tarball instead of failing the
+// collection.
+func CollectDiagnostics(out io.Writer, p *Plan, opts DiagnosticsOptions, w io.Writer) error {
+	return collectDiagnostics(out, p, opts, w, p.GetSSHClient, time.Now())
+}
+
+func collectDiagnostics(out io.Writer, p *Plan, opts DiagnosticsOptions, w io.Writer, sshClient func(host string) (ssh.Client, error), now time.Time) error {
+	redact := newRedactor(p)
+	nodes := p.GetUniqueNodes()
+	results := make([]map[string]string, len(nodes))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n Node) {
+			defer wg.Done()
+			files, err := collectNodeDiagnostics(p, n, sshClient)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				util.PrettyPrintErr(out, "Collect diagnostics from %q: %v", n.Host, err)
+				files = map[string]string{"error.txt": err.Error() + "\n"}
+			} else {
+				util.PrettyPrintOk(out, "Collect diagnostics from %q", n.Host)
+			}
+			results[i] = files
+		}(i, n)
+	}
+	wg.Wait()
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for i, n := range nodes {
+		names := make([]string, 0, len(results[i]))
+		for name := range results[i] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := addContentToTar(tw, filepath.Join("nodes", n.Host, name), redact(results[i][name]), now); err != nil {
+				return fmt.Errorf("error adding diagnostics of node %q to archive: %v", n.Host, err)
+			}
+		}
+	}
+	if opts.PlanFile != "" {
+		b, err := ioutil.ReadFile(opts.PlanFile)
+		if err != nil {
+			return fmt.Errorf("error reading plan file: %v", err)
+		}
+		if err := addContentToTar(tw, "kismatic-cluster.yaml", redact(string(b)), now); err != nil {
+			return fmt.Errorf("error adding plan file to archive: %v", err)
+		}
+	}
+	if opts.RunDirectory != "" {
+		// Only the known files of the run are collected. Run directories can
+		// contain sensitive data, such as the etcd data extracted by a restore.
+		for _, name := range runDiagnosticFiles {
+			path := filepath.Join(opts.RunDirectory, name)
+			info, err := os.Stat(path)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("error adding %q to archive: %v", path, err)
+			}
+			if info.IsDir() {
+				continue
+			}
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("error adding %q to archive: %v", path, err)
+			}
+			if err := addContentToTar(tw, filepath.Join("run", name), redact(string(b)), info.ModTime()); err != nil {
+				return fmt.Errorf("error adding %q to archive: %v", path, err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("error writing diagnostics archive: %v", err)
+	}
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("error writing diagnostics archive: %v", err)
+	}
+	return nil
+}
+
+// runs the diagnostic commands on the node, and returns their output keyed by file name.
+// The output of a command that fails is kept, followed by the error.
+func collectNodeDiagnostics(p *Plan, n Node, sshClient func(host string) (ssh.Client, error)) (map[string]string, error) {
+	client, err := sshClient(n.Host)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, d := range nodeDiagnostics(p, n) {
+		out, err := client.Output(true, d.command)
+		if err != nil {
+			out = fmt.Sprintf("%s\nerror running %q: %v\n", out, d.command, err)
+		}
+		files[d.file] = out
+	}
+	return files, nil
+}
+
+// returns the diagnostics that are collected from the node, based on its roles
+func nodeDiagnostics(p *Plan, n Node) []nodeDiagnostic {
+	roles := p.GetNodeRoles(n.Host)
+	services := []string{"docker"}
+	for _, r := range roles {
+		switch r {
+		case "etcd":
+			for _, c := range p.EtcdClusters() {
+				services = append(services, c.Service)
+			}
+		case "master":
+			services = append(services, "kube-apiserver", "kube-controller-manager", "kube-scheduler")
+		}
+	}
+	runsKubelet := false
+	for _, r := range roles {
+		if r != "etcd" {
+			runsKubelet = true
+		}
+	}
+	if runsKubelet {
+		services = append(services, "kubelet", "kube-proxy", "calico-node")
+	}
+
+	var diags []nodeDiagnostic
+	for _, s := range services {
+		diags = append(diags, nodeDiagnostic{
+			file:    fmt.Sprintf("journal-%s.log", s),
+			command: fmt.Sprintf("sudo journalctl -u %s --no-pager -n %d", s, diagnosticsJournalLines),
+		})
+	}
+	diags = append(diags,
+		nodeDiagnostic{file: "docker-ps.txt", command: "sudo docker ps -a"},
+		nodeDiagnostic{file: "iptables-save.txt", command: "sudo iptables-save"},
+	)
+	if runsKubelet {
+		diags = append(diags, nodeDiagnostic{file: "calico-status.txt", command: "sudo calicoctl node status"})
+	}
+	var certs []string
+	for path := range deployedCertificates(p, n) {
+		certs = append(certs, path)
+	}
+	sort.Strings(certs)
+	// only the metadata of the certificates is collected, the keys are never read
+	diags = append(diags,
+		nodeDiagnostic{
+			file:    "certificates.txt",
+			command: fmt.Sprintf(`sudo sh -c 'for f in %s; do echo "== $f"; openssl x509 -in $f -noout -subject -issuer -dates 2>&1; done'`, strings.Join(certs, " ")),
+		},
+		nodeDiagnostic{file: "disk-usage.txt", command: "df -h"},
+		nodeDiagnostic{file: "memory-usage.txt", command: "free -m"},
+		nodeDiagnostic{file: "inspector.json", command: fmt.Sprintf("sudo /usr/bin/kismatic-inspector local -o json --node-roles %s", strings.Join(roles, ","))},
+	)
+	return diags
+}
+
+const redacted = "[REDACTED]"
+
+var (
+	secretFieldRE = regexp.MustCompile(`(?i)((?:password|passwd|token|secret|client-key-data)[\w-]*"?\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s,}]+)`)
+	privateKeyRE  = regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// returns a function that removes the secrets of the plan, the values of
+// fields that look like secrets and private keys from the content
+func newRedactor(p *Plan) func(string) string {
+	var secrets []string
+	if p.Cluster.AdminPassword != "" {
+		secrets = append(secrets, p.Cluster.AdminPassword)
+	}
+	return func(s string) string {
+		for _, secret := range secrets {
+			s = strings.Replace(s, secret, redacted, -1)
+		}
+		s = secretFieldRE.ReplaceAllString(s, "${1}"+redacted)
+		return privateKeyRE.ReplaceAllString(s, "[REDACTED PRIVATE KEY]")
+	}
+}
+
+func addContentToTar(tw *tar.Writer, name string, content string, modTime time.Time) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(content)),
+		ModTime: modTime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.WriteString(tw, content)
+	return err
+}
@@ -0,0 +1,279 @@
+package install
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NodeChange is a node that was added to or removed from a role
+type NodeChange struct {
+	Role string
+	Node Node
+}
+
+// FieldChange is a plan field that has a different value in the new plan
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// PlanDiff describes the differences between the plan of a running
+// cluster and a new plan
+type PlanDiff struct {
+	NodesAdded        []NodeChange
+	NodesRemoved      []NodeChange
+	NodesModified     []NodeChange
+	RolesChanged      []NodeChange
+	Cluster           []FieldChange
+	Networking        []FieldChange
+	Master            []FieldChange
+	DockerRegistry    []FieldChange
+	NFSVolumesAdded   []NFSVolume
+	NFSVolumesRemoved []NFSVolume
+	// Authorization are the changes to the users and groups
+	Authorization []FieldChange
+}
+
+// DiffPlans returns the differences between the old and new plans
+func DiffPlans(old, new *Plan) PlanDiff {
+	d := PlanDiff{}
+	groups := []struct {
+		role     string
+		old, new []Node
+	}{
+		{"etcd", old.Etcd.Nodes, new.Etcd.Nodes},
+		{"master", old.Master.Nodes, new.Master.Nodes},
+		{"worker", old.Worker.Nodes, new.Worker.Nodes},
+		{"ingress", old.Ingress.Nodes, new.Ingress.Nodes},
+		{"storage", old.Storage.Nodes, new.Storage.Nodes},
+	}
+	for _, g := range groups {
+		oldNodes := map[string]Node{}
+		for _, n := range g.old {
+			oldNodes[n.Host] = n
+		}
+		newNodes := map[string]Node{}
+		for _, n := range g.new {
+			newNodes[n.Host] = n
+			o, ok := oldNodes[n.Host]
+			if !ok {
+				d.NodesAdded = append(d.NodesAdded, NodeChange{Role: g.role, Node: n})
+				continue
+			}
+			if o != n {
+				d.NodesModified = append(d.NodesModified, NodeChange{Role: g.role, Node: n})
+			}
+		}
+		for _, n := range g.old {
+			if _, ok := newNodes[n.Host]; !ok {
+				d.NodesRemoved = append(d.NodesRemoved, NodeChange{Role: g.role, Node: n})
+			}
+		}
+	}
+	// Nodes that remain in the cluster with a different set of roles
+	// are not added or removed
+	d.NodesAdded, d.RolesChanged = splitRoleChanges(d.NodesAdded, old, d.RolesChanged)
+	d.NodesRemoved, d.RolesChanged = splitRoleChanges(d.NodesRemoved, new, d.RolesChanged)
+
+	d.Cluster = diffFields([]fieldPair{
+		{"name", old.Cluster.Name, new.Cluster.Name},
+		{"allow_package_installation", old.Cluster.AllowPackageInstallation, new.Cluster.AllowPackageInstallation},
+		{"disconnected_installation", old.Cluster.DisconnectedInstallation, new.Cluster.DisconnectedInstallation},
+		{"certificates.expiry", old.Cluster.Certificates.Expiry, new.Cluster.Certificates.Expiry},
+		{"certificates.ca_cert", old.Cluster.Certificates.CACert, new.Cluster.Certificates.CACert},
+		{"certificates.ca_chain", old.Cluster.Certificates.CAChain, new.Cluster.Certificates.CAChain},
+		{"ssh.user", old.Cluster.SSH.User, new.Cluster.SSH.User},
+		{"ssh.ssh_key", old.Cluster.SSH.Key, new.Cluster.SSH.Key},
+		{"ssh.ssh_port", old.Cluster.SSH.Port, new.Cluster.SSH.Port},
+	})
+	// Don't leak the password when printing the diff
+	if old.Cluster.AdminPassword != new.Cluster.AdminPassword {
+		d.Cluster = append(d.Cluster, FieldChange{Field: "admin_password", Old: "<redacted>", New: "<redacted>"})
+	}
+	d.Networking = diffFields([]fieldPair{
+		{"type", old.Cluster.Networking.Type, new.Cluster.Networking.Type},
+		{"pod_cidr_block", old.Cluster.Networking.PodCIDRBlock, new.Cluster.Networking.PodCIDRBlock},
+		{"service_cidr_block", old.Cluster.Networking.ServiceCIDRBlock, new.Cluster.Networking.ServiceCIDRBlock},
+		{"policy_enabled", old.Cluster.Networking.PolicyEnabled, new.Cluster.Networking.PolicyEnabled},
+		{"update_hosts_files", old.Cluster.Networking.UpdateHostsFiles, new.Cluster.Networking.UpdateHostsFiles},
+	})
+	d.Master = diffFields([]fieldPair{
+		{"load_balanced_fqdn", old.Master.LoadBalancedFQDN, new.Master.LoadBalancedFQDN},
+		{"load_balanced_short_name", old.Master.LoadBalancedShortName, new.Master.LoadBalancedShortName},
+	})
+	d.DockerRegistry = diffFields([]fieldPair{
+		{"setup_internal", old.DockerRegistry.SetupInternal, new.DockerRegistry.SetupInternal},
+		{"address", old.DockerRegistry.Address, new.DockerRegistry.Address},
+		{"port", old.DockerRegistry.Port, new.DockerRegistry.Port},
+		{"CA", old.DockerRegistry.CAPath, new.DockerRegistry.CAPath},
+	})
+
+	if !reflect.DeepEqual(old.Users, new.Users) {
+		d.Authorization = append(d.Authorization, FieldChange{Field: "users", Old: userNames(old.Users), New: userNames(new.Users)})
+	}
+	if !reflect.DeepEqual(old.Groups, new.Groups) {
+		d.Authorization = append(d.Authorization, FieldChange{Field: "groups", Old: groupNames(old.Groups), New: groupNames(new.Groups)})
+	}
+
+	for _, v := range new.NFS.Volumes {
+		if !containsNFSVolume(old.NFS.Volumes, v) {
+			d.NFSVolumesAdded = append(d.NFSVolumesAdded, v)
+		}
+	}
+	for _, v := range old.NFS.Volumes {
+		if !containsNFSVolume(new.NFS.Volumes, v) {
+			d.NFSVolumesRemoved = append(d.NFSVolumesRemoved, v)
+		}
+	}
+	return d
+}
+
+func userNames(users []User) string {
+	names := []string{}
+	for _, u := range users {
+		names = append(names, u.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func groupNames(groups []Group) string {
+	names := []string{}
+	for _, g := range groups {
+		names = append(names, g.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// Empty returns true if there are no differences between the plans
+func (d PlanDiff) Empty() bool {
+	return reflect.DeepEqual(d, PlanDiff{})
+}
+
+// NodesAddedTo returns the nodes that were added to the given role
+func (d PlanDiff) NodesAddedTo(role string) []Node {
+	return nodesWithRole(d.NodesAdded, role)
+}
+
+// NodesRemovedFrom returns the nodes that were removed from the given role
+func (d PlanDiff) NodesRemovedFrom(role string) []Node {
+	return nodesWithRole(d.NodesRemoved, role)
+}
+
+// HasFieldChange returns true if the field changed in any of the given changes
+func HasFieldChange(changes []FieldChange, field string) bool {
+	for _, c := range changes {
+		if c.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// validate returns errors for the changes that cannot be made to a running cluster
+func (d *PlanDiff) validate() (bool, []error) {
+	v := newValidator()
+	immutable := []struct {
+		section string
+		changes []FieldChange
+		fields  []string
+	}{
+		{"cluster", d.Cluster, []string{"name", "certificates.ca_cert", "certificates.ca_chain"}},
+		{"networking", d.Networking, []string{"type", "pod_cidr_block", "service_cidr_block"}},
+		{"master", d.Master, []string{"load_balanced_fqdn", "load_balanced_short_name"}},
+	}
+	for _, i := range immutable {
+		for _, c := range i.changes {
+			for _, f := range i.fields {
+				if c.Field == f {
+					v.addError(fmt.Errorf("%s.%s cannot be changed on a running cluster (%q -> %q)", i.section, c.Field, c.Old, c.New))
+				}
+			}
+		}
+	}
+	for _, c := range d.Networking {
+		if c.Field == "policy_enabled" && c.New == "false" {
+			v.addError(fmt.Errorf("networking.policy_enabled cannot be disabled on a running cluster"))
+		}
+	}
+	for _, c := range d.DockerRegistry {
+		if c.Field == "setup_internal" && c.New == "false" {
+			v.addError(fmt.Errorf("docker_registry.setup_internal cannot be disabled on a running cluster"))
+		}
+	}
+	for _, n := range d.NodesModified {
+		v.addError(fmt.Errorf("the IP addresses of %s node %q cannot be changed on a running cluster", n.Role, n.Node.Host))
+	}
+	for _, n := range d.RolesChanged {
+		v.addError(fmt.Errorf("the roles of node %q cannot be changed on a running cluster (%s role)", n.Node.Host, n.Role))
+	}
+	for _, n := range d.NodesRemoved {
+		if n.Role == "etcd" || n.Role == "master" {
+			v.addError(fmt.Errorf("%s node %q cannot be removed from a running cluster", n.Role, n.Node.Host))
+		}
+	}
+	for _, nfs := range d.NFSVolumesRemoved {
+		v.addError(fmt.Errorf("NFS volume %s:%s cannot be removed from a running cluster", nfs.Host, nfs.Path))
+	}
+	return v.valid()
+}
+
+// ValidatePlanDiff returns true if the changes described by the diff
+// can be applied to a running cluster
+func ValidatePlanDiff(d *PlanDiff) (bool, []error) {
+	v := newValidator()
+	v.validate(d)
+	return v.valid()
+}
+
+type fieldPair struct {
+	field    string
+	old, new interface{}
+}
+
+func diffFields(pairs []fieldPair) []FieldChange {
+	var changes []FieldChange
+	for _, p := range pairs {
+		if p.old != p.new {
+			changes = append(changes, FieldChange{
+				Field: p.field,
+				Old:   fmt.Sprintf("%v", p.old),
+				New:   fmt.Sprintf("%v", p.new),
+			})
+		}
+	}
+	return changes
+}
+
+// moves the changes of nodes that are part of the plan to roleChanges
+func splitRoleChanges(changes []NodeChange, p *Plan, roleChanges []NodeChange) ([]NodeChange, []NodeChange) {
+	var remaining []NodeChange
+	for _, c := range changes {
+		if len(p.GetNodeRoles(c.Node.Host)) > 0 {
+			roleChanges = append(roleChanges, c)
+			continue
+		}
+		remaining = append(remaining, c)
+	}
+	return remaining, roleChanges
+}
+
+func nodesWithRole(changes []NodeChange, role string) []Node {
+	nodes := []Node{}
+	for _, c := range changes {
+		if c.Role == role {
+			nodes = append(nodes, c.Node)
+		}
+	}
+	return nodes
+}
+
+func containsNFSVolume(volumes []NFSVolume, v NFSVolume) bool {
+	for _, vol := range volumes {
+		if vol == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,328 @@
+package install
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+)
+
+func diffTestPlan() Plan {
+	return Plan{
+		Cluster: Cluster{
+			Name: "test",
+			Networking: NetworkConfig{
+				Type:             "overlay",
+				PodCIDRBlock:     "172.16.0.0/16",
+				ServiceCIDRBlock: "172.17.0.0/16",
+			},
+		},
+		Etcd: NodeGroup{
+			ExpectedCount: 1,
+			Nodes:         []Node{{Host: "etcd01", IP: "10.0.0.1"}},
+		},
+		Master: MasterNodeGroup{
+			ExpectedCount: 1,
+			Nodes:         []Node{{Host: "master01", IP: "10.0.0.2"}},
+		},
+		Worker: NodeGroup{
+			ExpectedCount: 1,
+			Nodes:         []Node{{Host: "worker01", IP: "10.0.0.3"}},
+		},
+	}
+}
+
+func TestDiffPlansNoChanges(t *testing.T) {
+	old := diffTestPlan()
+	new := diffTestPlan()
+	d := DiffPlans(&old, &new)
+	if !d.Empty() {
+		t.Errorf("expected empty diff, got %+v", d)
+	}
+	if steps := buildApplySteps(&old, &new, d); len(steps) != 0 {
+		t.Errorf("expected no steps for an empty diff, got %+v", steps)
+	}
+}
+
+func TestDiffPlansNodesAddedAndRemoved(t *testing.T) {
+	old := diffTestPlan()
+	new := diffTestPlan()
+	new.Worker.Nodes = []Node{{Host: "worker02", IP: "10.0.0.4"}}
+	d := DiffPlans(&old, &new)
+	expectedAdded := []NodeChange{{Role: "worker", Node: Node{Host: "worker02", IP: "10.0.0.4"}}}
+	if !reflect.DeepEqual(d.NodesAdded, expectedAdded) {
+		t.Errorf("expected %+v to be added, got %+v", expectedAdded, d.NodesAdded)
+	}
+	expectedRemoved := []NodeChange{{Role: "worker", Node: Node{Host: "worker01", IP: "10.0.0.3"}}}
+	if !reflect.DeepEqual(d.NodesRemoved, expectedRemoved) {
+		t.Errorf("expected %+v to be removed, got %+v", expectedRemoved, d.NodesRemoved)
+	}
+	if ok, errs := ValidatePlanDiff(&d); !ok {
+		t.Errorf("expected diff to be valid, got errors: %v", errs)
+	}
+}
+
+func TestDiffPlansPasswordIsRedacted(t *testing.T) {
+	old := diffTestPlan()
+	old.Cluster.AdminPassword = "foo"
+	new := diffTestPlan()
+	new.Cluster.AdminPassword = "bar"
+	d := DiffPlans(&old, &new)
+	if len(d.Cluster) != 1 {
+		t.Fatalf("expected one cluster change, got %+v", d.Cluster)
+	}
+	if d.Cluster[0].Old == "foo" || d.Cluster[0].New == "bar" {
+		t.Errorf("admin password was not redacted: %+v", d.Cluster[0])
+	}
+}
+
+func TestValidatePlanDiffImmutableChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(p *Plan)
+	}{
+		{"pod CIDR", func(p *Plan) { p.Cluster.Networking.PodCIDRBlock = "192.168.0.0/16" }},
+		{"service CIDR", func(p *Plan) { p.Cluster.Networking.ServiceCIDRBlock = "192.168.0.0/16" }},
+		{"networking type", func(p *Plan) { p.Cluster.Networking.Type = "routed" }},
+		{"cluster name", func(p *Plan) { p.Cluster.Name = "other" }},
+		{"node IP", func(p *Plan) { p.Worker.Nodes[0].IP = "10.0.0.10" }},
+		{"master removed", func(p *Plan) { p.Master.Nodes = []Node{{Host: "master02", IP: "10.0.0.5"}} }},
+		{"etcd removed", func(p *Plan) { p.Etcd.Nodes = []Node{{Host: "etcd02", IP: "10.0.0.6"}} }},
+		{"role changed", func(p *Plan) { p.Ingress.Nodes = []Node{{Host: "worker01", IP: "10.0.0.3"}} }},
+	}
+	for _, test := range tests {
+		old := diffTestPlan()
+		new := diffTestPlan()
+		new.Worker.Nodes = append([]Node{}, old.Worker.Nodes...)
+		test.modify(&new)
+		d := DiffPlans(&old, &new)
+		if ok, _ := ValidatePlanDiff(&d); ok {
+			t.Errorf("%s: expected diff to be invalid", test.name)
+		}
+	}
+}
+
+func TestBuildApplyStepsAddNodes(t *testing.T) {
+	old := diffTestPlan()
+	new := diffTestPlan()
+	new.Etcd.Nodes = append(new.Etcd.Nodes, Node{Host: "etcd02"}, Node{Host: "etcd03"})
+	new.Master.Nodes = append(new.Master.Nodes, Node{Host: "master02"})
+	new.Worker.Nodes = append(new.Worker.Nodes, Node{Host: "worker02"})
+	new.Cluster.Networking.UpdateHostsFiles = true
+	old.Cluster.Networking.UpdateHostsFiles = true
+	d := DiffPlans(&old, &new)
+	steps := buildApplySteps(&old, &new, d)
+	expected := []string{
+		":kubernetes-etcd.yaml",
+		":kubernetes-etcd.yaml",
+		"master02:kubernetes-master.yaml",
+		":_apiserver.yaml",
+		"worker02:kubernetes-worker.yaml",
+		":_hosts.yaml",
+	}
+	actual := []string{}
+	for _, s := range steps {
+		actual = append(actual, s.node+":"+s.playbook)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected steps %v, got %v", expected, actual)
+	}
+	// etcd members are added one at a time
+	if len(steps[0].plan.Etcd.Nodes) != 2 || steps[0].etcdNode != "etcd02" {
+		t.Errorf("unexpected plan for first etcd step: %+v", steps[0].plan.Etcd)
+	}
+	if len(steps[1].plan.Etcd.Nodes) != 3 || steps[1].etcdNode != "etcd03" {
+		t.Errorf("unexpected plan for second etcd step: %+v", steps[1].plan.Etcd)
+	}
+	if steps[4].workerNode != "worker02" {
+		t.Errorf("expected worker node to be set, got %q", steps[4].workerNode)
+	}
+}
+
+func TestBuildApplyStepsRemoveNode(t *testing.T) {
+	old := diffTestPlan()
+	old.Worker.Nodes = append(old.Worker.Nodes, Node{Host: "worker02"})
+	new := diffTestPlan()
+	d := DiffPlans(&old, &new)
+	steps := buildApplySteps(&old, &new, d)
+	if len(steps) != 1 {
+		t.Fatalf("expected one step, got %+v", steps)
+	}
+	s := steps[0]
+	if s.playbook != "kubernetes-remove-node.yaml" || s.node != "worker02" || s.workerNode != "worker02" {
+		t.Errorf("unexpected step for removing node: %+v", s)
+	}
+	// the removed node must be in the inventory
+	if len(s.plan.Worker.Nodes) != 2 {
+		t.Errorf("expected removed node to be in the plan of the step")
+	}
+}
+
+func TestBuildApplyStepsConfigurationChanges(t *testing.T) {
+	old := diffTestPlan()
+	new := diffTestPlan()
+	new.Cluster.Networking.PolicyEnabled = true
+	new.DockerRegistry.SetupInternal = true
+	new.NFS.Volumes = []NFSVolume{{Host: "nfs01", Path: "/data"}}
+	d := DiffPlans(&old, &new)
+	steps := buildApplySteps(&old, &new, d)
+	expected := []string{
+		"_calico.yaml",
+		"_addon-network-policy.yaml",
+		"_docker.yaml",
+		"_docker-registry-container.yaml",
+		"_addon-nfs-volumes.yaml",
+	}
+	actual := []string{}
+	for _, s := range steps {
+		actual = append(actual, s.playbook)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected steps %v, got %v", expected, actual)
+	}
+}
+
+func TestBuildApplyStepsAuthorizationChanges(t *testing.T) {
+	old := diffTestPlan()
+	new := diffTestPlan()
+	new.Groups = []Group{{Name: "developers", Access: []Access{{Namespace: "dev"}}}}
+	new.Users = []User{{Name: "jane", Groups: []string{"developers"}}}
+	d := DiffPlans(&old, &new)
+	if len(d.Authorization) != 2 {
+		t.Fatalf("expected the users and groups to change, got %+v", d.Authorization)
+	}
+	steps := buildApplySteps(&old, &new, d)
+	if len(steps) != 1 || steps[0].playbook != "_apiserver.yaml" {
+		t.Errorf("expected the API servers to be reconfigured, got %+v", steps)
+	}
+}
+
+func TestApplyDiffRecordsSuccessfulRun(t *testing.T) {
+	fakeRunner := fakeRunner{}
+	runsDir := mustGetTempDir(t)
+	defer os.RemoveAll(runsDir)
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: runsDir},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		pki: &fakePKI{caExists: true},
+	}
+	old := diffTestPlan()
+	new := diffTestPlan()
+	new.Worker.Nodes = append(new.Worker.Nodes, Node{Host: "worker02"})
+	if err := e.ApplyDiff(&old, &new); err != nil {
+		t.Fatalf("unexpected error applying diff: %v", err)
+	}
+	expected := []string{"worker02:kubernetes-worker.yaml"}
+	if !reflect.DeepEqual(fakeRunner.nodePlaybooks, expected) {
+		t.Errorf("expected %v to run, got %v", expected, fakeRunner.nodePlaybooks)
+	}
+	p, _, err := LastSuccessfulPlan(runsDir)
+	if err != nil {
+		t.Fatalf("unexpected error reading last successful plan: %v", err)
+	}
+	if len(p.Worker.Nodes) != 2 {
+		t.Errorf("expected the applied plan to be recorded, got %+v", p.Worker)
+	}
+}
+
+func TestApplyDiffInvalidDiff(t *testing.T) {
+	fakeRunner := fakeRunner{}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		pki: &fakePKI{caExists: true},
+	}
+	old := diffTestPlan()
+	new := diffTestPlan()
+	new.Cluster.Networking.PodCIDRBlock = "192.168.0.0/16"
+	if err := e.ApplyDiff(&old, &new); err == nil {
+		t.Errorf("expected an error when changing the pod CIDR")
+	}
+	if len(fakeRunner.allNodesPlaybooks) != 0 || len(fakeRunner.nodePlaybooks) != 0 {
+		t.Errorf("playbooks were run for an invalid diff")
+	}
+}
+
+func TestLastSuccessfulPlan(t *testing.T) {
+	runsDir := mustGetTempDir(t)
+	defer os.RemoveAll(runsDir)
+	runs := []struct {
+		dir     string
+		name    string
+		success bool
+	}{
+		{"install/2017-01-01-10-00-00", "install", true},
+		{"add-worker/2017-01-02-10-00-00", "add-worker", true},
+		{"add-worker/2017-01-03-10-00-00", "failed", false},
+	}
+	for _, r := range runs {
+		dir := filepath.Join(runsDir, r.dir)
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			t.Fatalf("error creating run dir: %v", err)
+		}
+		p := diffTestPlan()
+		p.Cluster.Name = r.name
+		fp := FilePlanner{File: filepath.Join(dir, "kismatic-cluster.yaml")}
+		if err := fp.Write(&p); err != nil {
+			t.Fatalf("error writing plan: %v", err)
+		}
+		if r.success {
+			if err := recordSuccessfulRun(dir); err != nil {
+				t.Fatalf("error recording run: %v", err)
+			}
+		}
+	}
+	p, dir, err := LastSuccessfulPlan(runsDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Cluster.Name != "add-worker" {
+		t.Errorf("expected the plan of the latest successful run, got %q from %q", p.Cluster.Name, dir)
+	}
+}
+
+func TestLastSuccessfulPlanNoMarkedRuns(t *testing.T) {
+	runsDir := mustGetTempDir(t)
+	defer os.RemoveAll(runsDir)
+	// Runs of older versions are not marked as successful
+	for _, r := range []string{"install/2017-01-01-10-00-00", "install/2017-01-02-10-00-00", "add-worker/2017-01-03-10-00-00"} {
+		dir := filepath.Join(runsDir, r)
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			t.Fatalf("error creating run dir: %v", err)
+		}
+		p := diffTestPlan()
+		p.Cluster.Name = filepath.Base(r)
+		fp := FilePlanner{File: filepath.Join(dir, "kismatic-cluster.yaml")}
+		if err := fp.Write(&p); err != nil {
+			t.Fatalf("error writing plan: %v", err)
+		}
+	}
+	p, dir, err := LastSuccessfulPlan(runsDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Cluster.Name != "2017-01-02-10-00-00" {
+		t.Errorf("expected the plan of the latest install run, got %q from %q", p.Cluster.Name, dir)
+	}
+}
+
+func TestLastSuccessfulPlanNoRuns(t *testing.T) {
+	runsDir := mustGetTempDir(t)
+	defer os.RemoveAll(runsDir)
+	if _, _, err := LastSuccessfulPlan(runsDir); err == nil {
+		t.Errorf("expected an error when there are no successful runs")
+	}
+}
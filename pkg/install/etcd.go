@@ -0,0 +1,39 @@
+package install
+
+// EtcdCluster is an etcd cluster that is deployed on the etcd nodes
+type EtcdCluster struct {
+	Name string
+	// Service is the name of the service that runs the members of the cluster
+	Service string
+	// ClientPort is the port that the clients of the cluster connect to
+	ClientPort int
+	// CertsDirectory contains the certificates of the members of the cluster
+	CertsDirectory string
+	// DataDirectory contains the data of the members of the cluster
+	DataDirectory string
+}
+
+// the etcd clusters deployed on the etcd nodes. The ports are the ones given
+// to ansible in the cluster catalog, and the directories are the ones set in
+// the group variables of the clusters.
+var etcdClusters = []EtcdCluster{
+	{
+		Name:           "kubernetes",
+		Service:        "etcd_k8s",
+		ClientPort:     etcdK8sClientPort,
+		CertsDirectory: "/etc/etcd_k8s",
+		DataDirectory:  "/var/lib/etcd_k8s",
+	},
+	{
+		Name:           "networking",
+		Service:        "etcd_networking",
+		ClientPort:     etcdNetworkingClientPort,
+		CertsDirectory: "/etc/etcd_networking",
+		DataDirectory:  "/var/lib/etcd_networking",
+	},
+}
+
+// EtcdClusters returns the etcd clusters that are deployed on the etcd nodes of the plan
+func (p *Plan) EtcdClusters() []EtcdCluster {
+	return append([]EtcdCluster{}, etcdClusters...)
+}
@@ -2,6 +2,7 @@ package install
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -16,6 +17,7 @@ import (
 	"github.com/apprenda/kismatic/pkg/ansible"
 	"github.com/apprenda/kismatic/pkg/install/explain"
 	"github.com/apprenda/kismatic/pkg/util"
+	yaml "gopkg.in/yaml.v2"
 )
 
 // The PreFlightExecutor will run pre-flight checks against the
@@ -28,10 +30,20 @@ type PreFlightExecutor interface {
 type Executor interface {
 	PreFlightExecutor
 	Install(p *Plan) error
+	ResumeInstall(p *Plan) error
 	RunSmokeTest(*Plan) error
 	AddWorker(*Plan, Node) (*Plan, error)
+	AddMaster(*Plan, Node) (*Plan, error)
+	AddEtcd(*Plan, Node) (*Plan, error)
 	RunTask(string, *Plan) error
 	AddVolume(*Plan, StorageVolume) error
+	Upgrade(*Plan, []NodeVersion) error
+	RemoveNode(*Plan, string) (*Plan, error)
+	ApplyDiff(previous *Plan, plan *Plan) error
+	RestoreEtcd(p *Plan, archive string) error
+	RotateCertificates(p *Plan, rotateCA bool) error
+	InstallAddOn(p *Plan, name string) error
+	RemoveAddOn(p *Plan, name string) error
 }
 
 // ExecutorOptions are used to configure the executor
@@ -45,7 +57,8 @@ type ExecutorOptions struct {
 	// RestartServices determines whether the cluster services should be
 	// restarted during the installation.
 	RestartServices bool
-	// OutputFormat sets the format of the executor
+	// OutputFormat sets the format of the executor. It is one of "simple",
+	// "raw" or "json".
 	OutputFormat string
 	// Verbose output from the executor
 	Verbose bool
@@ -69,11 +82,18 @@ func NewExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOptions) (E
 	switch options.OutputFormat {
 	case "raw":
 		outFormat = ansible.RawFormat
-	case "simple":
+	case "simple", "json":
 		outFormat = ansible.JSONLinesFormat
 	default:
 		return nil, fmt.Errorf("Output format %q is not supported", options.OutputFormat)
 	}
+	// In json mode, only the events are written to stdout and the
+	// messages of the executor are written to errOut
+	var jsonOut io.Writer
+	if options.OutputFormat == "json" {
+		jsonOut = stdout
+		stdout = errOut
+	}
 	certsDir := filepath.Join(options.GeneratedAssetsDirectory, "keys")
 	pki := &LocalPKI{
 		CACsr:                   filepath.Join(ansibleDir, "playbooks", "tls", "ca-csr.json"),
@@ -85,6 +105,7 @@ func NewExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOptions) (E
 	return &ansibleExecutor{
 		options:             options,
 		stdout:              stdout,
+		jsonOut:             jsonOut,
 		consoleOutputFormat: outFormat,
 		ansibleDir:          ansibleDir,
 		certsDir:            certsDir,
@@ -103,15 +124,23 @@ func NewPreFlightExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOp
 	switch options.OutputFormat {
 	case "raw":
 		outFormat = ansible.RawFormat
-	case "simple":
+	case "simple", "json":
 		outFormat = ansible.JSONLinesFormat
 	default:
 		return nil, fmt.Errorf("Output format %q is not supported", options.OutputFormat)
 	}
+	// In json mode, only the events are written to stdout and the
+	// messages of the executor are written to errOut
+	var jsonOut io.Writer
+	if options.OutputFormat == "json" {
+		jsonOut = stdout
+		stdout = errOut
+	}
 
 	return &ansibleExecutor{
 		options:             options,
 		stdout:              stdout,
+		jsonOut:             jsonOut,
 		consoleOutputFormat: outFormat,
 		ansibleDir:          ansibleDir,
 	}, nil
@@ -120,6 +149,7 @@ func NewPreFlightExecutor(stdout io.Writer, errOut io.Writer, options ExecutorOp
 type ansibleExecutor struct {
 	options             ExecutorOptions
 	stdout              io.Writer
+	jsonOut             io.Writer // the events are written to jsonOut as JSON in json mode
 	consoleOutputFormat ansible.OutputFormat
 	ansibleDir          string
 	certsDir            string
@@ -131,6 +161,60 @@ type ansibleExecutor struct {
 
 // Install the cluster according to the installation plan
 func (ae *ansibleExecutor) Install(p *Plan) error {
+	return ae.install(p, "kubernetes.yaml", nil)
+}
+
+// ResumeInstall continues the most recent installation, which failed, from
+// the first play that did not complete. The plan must be the same as the
+// plan of the failed installation.
+func (ae *ansibleExecutor) ResumeInstall(p *Plan) error {
+	failedRun, err := lastInstallRun(ae.options.RunsDirectory)
+	if err != nil {
+		return fmt.Errorf("error finding the installation to resume: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(failedRun, successfulRunMarker)); err == nil {
+		return fmt.Errorf("the last installation in %q completed successfully, there is nothing to resume", failedRun)
+	}
+	fp := FilePlanner{File: filepath.Join(failedRun, "kismatic-cluster.yaml")}
+	if !fp.PlanExists() {
+		return fmt.Errorf("plan file was not found in run directory %q", failedRun)
+	}
+	failedPlan, err := fp.Read()
+	if err != nil {
+		return fmt.Errorf("error reading plan file of run %q: %v", failedRun, err)
+	}
+	if !samePlan(failedPlan, p) {
+		return fmt.Errorf("the plan file changed since the installation in %q failed, run the installation without resuming", failedRun)
+	}
+	progress, err := readPlayProgress(failedRun)
+	if err != nil {
+		return err
+	}
+	playbooksDir := filepath.Join(ae.ansibleDir, "playbooks")
+	includes, completed, err := resumeIncludes(playbooksDir, "kubernetes.yaml", progress)
+	if err != nil {
+		return fmt.Errorf("error resuming the installation in %q: %v", failedRun, err)
+	}
+	if len(includes) == 0 {
+		util.PrettyPrintOk(ae.stdout, "All the plays of the installation in %q completed", failedRun)
+		return ae.install(p, "", completed)
+	}
+	if len(completed) > 0 {
+		util.PrettyPrintOk(ae.stdout, "Skipping the %d plays that completed in %q, resuming from %q", len(completed), failedRun, includes[0].Include)
+	}
+	if err = writeIncludesPlaybook(playbooksDir, resumePlaybook, includes); err != nil {
+		return err
+	}
+	// The playbook is only valid for this run, and must not be left behind
+	// in the playbooks that ship with the installer
+	defer os.Remove(filepath.Join(playbooksDir, resumePlaybook))
+	return ae.install(p, resumePlaybook, completed)
+}
+
+// runs the installation playbook, and records the progress of its plays
+// after the plays that completed in a previous run. The playbook is not
+// run if it is empty.
+func (ae *ansibleExecutor) install(p *Plan, playbook string, completed []PlayStatus) error {
 	runDirectory, err := ae.createRunDirectory("install")
 	if err != nil {
 		return fmt.Errorf("error creating working directory for installation: %v", err)
@@ -142,6 +226,16 @@ func (ae *ansibleExecutor) Install(p *Plan) error {
 	if err = fp.Write(p); err != nil {
 		return fmt.Errorf("error recording plan file to %s: %v", fp.File, err)
 	}
+	progress := &playProgress{
+		file:  filepath.Join(runDirectory, playProgressFile),
+		Plays: completed,
+	}
+	if err = progress.write(); err != nil {
+		return err
+	}
+	if playbook == "" {
+		return recordSuccessfulRun(runDirectory)
+	}
 	// Generate private keys and certificates for the cluster
 	if err = ae.generateTLSAssets(p); err != nil {
 		return err
@@ -160,12 +254,18 @@ func (ae *ansibleExecutor) Install(p *Plan) error {
 	}
 	// Run the installation playbook
 	util.PrintHeader(ae.stdout, "Installing Cluster", '=')
-	playbook := "kubernetes.yaml"
 	eventExplainer := &explain.DefaultEventExplainer{}
-	if err = ae.runPlaybookWithExplainer(playbook, eventExplainer, inventory, *cc, ansibleLogFile, runDirectory); err != nil {
+	if err = ae.runPlaybook(playbook, eventExplainer, inventory, *cc, ansibleLogFile, runDirectory, progress); err != nil {
 		return err
 	}
-	return nil
+	return recordSuccessfulRun(runDirectory)
+}
+
+// returns true if the plans are the same once written to a plan file
+func samePlan(a, b *Plan) bool {
+	ay, errA := yaml.Marshal(a)
+	by, errB := yaml.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ay, by)
 }
 
 // creates the extra vars that are required for the installation playbook.
@@ -193,6 +293,12 @@ func (ae *ansibleExecutor) buildInstallExtraVars(p *Plan) (*ansible.ClusterCatal
 		EnablePackageInstallation: p.Cluster.AllowPackageInstallation,
 		KuberangPath:              filepath.Join("kuberang", "linux", "amd64", "kuberang"),
 		DisconnectedInstallation:  p.Cluster.DisconnectedInstallation,
+		EtcdK8sClientPort:         etcdK8sClientPort,
+		EtcdK8sPeerPort:           etcdK8sPeerPort,
+		EtcdNetworkingClientPort:  etcdNetworkingClientPort,
+		EtcdNetworkingPeerPort:    etcdNetworkingPeerPort,
+		APIServerPort:             apiServerPort,
+		CalicoFelixMetricsPort:    calicoFelixMetricsPort,
 	}
 
 	// Setup FQDN or default to first master
@@ -216,18 +322,15 @@ func (ae *ansibleExecutor) buildInstallExtraVars(p *Plan) (*ansible.ClusterCatal
 			cc.DockerRegistryAddress = p.Master.Nodes[0].InternalIP
 		}
 		cc.DockerCAPath = tlsDir + "/ca.pem"
-		cc.DockerRegistryPort = "8443"
+		cc.DockerRegistryPort = strconv.Itoa(internalDockerRegistryPort)
 	} // Else just use DockerHub
 
 	if ae.options.RestartServices {
 		cc.EnableRestart()
 	}
 
-	if p.Ingress.Nodes != nil && len(p.Ingress.Nodes) > 0 {
-		cc.EnableConfigureIngress = true
-	} else {
-		cc.EnableConfigureIngress = false
-	}
+	cc.EnabledAddOns = enabledAddOns(p)
+	cc.AuthorizationPolicies = authorizationPolicies(p)
 
 	for _, n := range p.NFS.Volumes {
 		cc.NFSVolumes = append(cc.NFSVolumes, ansible.NFSVolume{
@@ -237,6 +340,27 @@ func (ae *ansibleExecutor) buildInstallExtraVars(p *Plan) (*ansible.ClusterCatal
 	}
 	cc.EnableGluster = p.Storage.Nodes != nil && len(p.Storage.Nodes) > 0
 
+	if p.Logging.Enabled {
+		cc.LoggingBackend = p.Logging.Backend
+		if p.Logging.Backend == "nfs" {
+			cc.LoggingNFSServerIP = p.Logging.NFSServer
+			cc.LoggingNFSDir = p.Logging.NFSPath
+		}
+		cc.LoggingStorageSize = fmt.Sprintf("%dGi", p.Logging.StorageSizeGB)
+		cc.LoggingStorageSizeGB = p.Logging.StorageSizeGB
+		cc.LoggingRetentionDays = p.Logging.RetentionDays
+	}
+
+	if p.Monitoring.Enabled {
+		cc.MonitoringBackend = p.Monitoring.Backend
+		if p.Monitoring.Backend == "nfs" {
+			cc.MonitoringNFSServerIP = p.Monitoring.NFSServer
+			cc.MonitoringNFSDir = p.Monitoring.NFSPath
+		}
+		cc.MonitoringStorageSize = fmt.Sprintf("%dGi", p.Monitoring.StorageSizeGB)
+		cc.MonitoringStorageSizeGB = p.Monitoring.StorageSizeGB
+	}
+
 	return &cc, nil
 }
 
@@ -304,6 +428,16 @@ func (ae *ansibleExecutor) RunPreFlightCheck(p *Plan) error {
 	cc.KismaticPreflightCheckerLocal = filepath.Join(pwd, "ansible", "playbooks", "inspector", runtime.GOOS, runtime.GOARCH, "kismatic-inspector")
 	cc.EnablePackageInstallation = p.Cluster.AllowPackageInstallation
 
+	// Save the ports that must be reachable between the nodes
+	matrixFile, err := filepath.Abs(filepath.Join(runDirectory, "connectivity-matrix.json"))
+	if err != nil {
+		return fmt.Errorf("error getting absolute path of connectivity matrix: %v", err)
+	}
+	if err = writeConnectivityMatrix(p, *cc, matrixFile); err != nil {
+		return err
+	}
+	cc.KismaticConnectivityMatrix = matrixFile
+
 	// run the pre-flight playbook with pre-flight explainer
 	playbook := "preflight.yaml"
 	explainer := &explain.PreflightEventExplainer{
@@ -430,7 +564,7 @@ func (ae *ansibleExecutor) generateTLSAssets(p *Plan) error {
 	}
 
 	// Generate node and user certificates
-	err = ae.pki.GenerateClusterCertificates(p, ca, []string{"admin"})
+	err = ae.pki.GenerateClusterCertificates(p, ca, clusterUsers)
 	if err != nil {
 		return fmt.Errorf("error generating certificates for the cluster: %v", err)
 	}
@@ -439,6 +573,11 @@ func (ae *ansibleExecutor) generateTLSAssets(p *Plan) error {
 }
 
 func (ae *ansibleExecutor) runPlaybookWithExplainer(playbook string, eventExplainer explain.AnsibleEventExplainer, inv ansible.Inventory, cc ansible.ClusterCatalog, ansibleLog io.Writer, runDirectory string) error {
+	return ae.runPlaybook(playbook, eventExplainer, inv, cc, ansibleLog, runDirectory, nil)
+}
+
+// runs the playbook, and records the progress of its plays when progress is not nil
+func (ae *ansibleExecutor) runPlaybook(playbook string, eventExplainer explain.AnsibleEventExplainer, inv ansible.Inventory, cc ansible.ClusterCatalog, ansibleLog io.Writer, runDirectory string, progress *playProgress) error {
 	// Setup sinks for explainer and ansible stdout
 	runner, explainer, err := ae.getAnsibleRunnerAndExplainer(eventExplainer, ansibleLog, runDirectory)
 	if err != nil {
@@ -450,6 +589,9 @@ func (ae *ansibleExecutor) runPlaybookWithExplainer(playbook string, eventExplai
 	if err != nil {
 		return fmt.Errorf("error running ansible playbook: %v", err)
 	}
+	if progress != nil {
+		eventStream = progress.record(eventStream)
+	}
 	// Ansible blocks until explainer starts reading from stream. Start
 	// explainer in a separate go routine
 	go explainer.Explain(eventStream)
@@ -461,6 +603,26 @@ func (ae *ansibleExecutor) runPlaybookWithExplainer(playbook string, eventExplai
 	return nil
 }
 
+func (ae *ansibleExecutor) runPlaybookOnNodeWithExplainer(playbook string, eventExplainer explain.AnsibleEventExplainer, inv ansible.Inventory, cc ansible.ClusterCatalog, node string, ansibleLog io.Writer, runDirectory string) error {
+	runner, explainer, err := ae.getAnsibleRunnerAndExplainer(eventExplainer, ansibleLog, runDirectory)
+	if err != nil {
+		return err
+	}
+
+	// Start running ansible with the given playbook, limited to the node
+	eventStream, err := runner.StartPlaybookOnNode(playbook, inv, cc, node)
+	if err != nil {
+		return fmt.Errorf("error running ansible playbook: %v", err)
+	}
+	go explainer.Explain(eventStream)
+
+	// Wait until ansible exits
+	if err = runner.WaitPlaybook(); err != nil {
+		return fmt.Errorf("error running playbook: %v", err)
+	}
+	return nil
+}
+
 func (ae *ansibleExecutor) getAnsibleRunnerAndExplainer(explainer explain.AnsibleEventExplainer, ansibleLog io.Writer, runDirectory string) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
 	if ae.runnerExplainerFactory != nil {
 		return ae.runnerExplainerFactory(explainer, ansibleLog)
@@ -476,6 +638,10 @@ func (ae *ansibleExecutor) getAnsibleRunnerAndExplainer(explainer explain.Ansibl
 		explainerOut = ioutil.Discard
 		ansibleOut = io.MultiWriter(ae.stdout, timestampWriter(ansibleLog))
 	}
+	if ae.jsonOut != nil {
+		explainerOut = ae.jsonOut
+		explainer = &explain.JSONEventExplainer{}
+	}
 
 	// Send stdout and stderr to ansibleOut
 	runner, err := ansible.NewRunner(ansibleOut, ansibleOut, ae.ansibleDir, runDirectory)
@@ -0,0 +1,189 @@
+package explain
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+// JSONEventVersion is the version of the JSON events. It changes when
+// fields are removed or their meaning changes.
+const JSONEventVersion = "v1"
+
+// The types of the JSON events
+const (
+	JSONPlaybookStart   = "playbookStart"
+	JSONPlayStart       = "playStart"
+	JSONTaskStart       = "taskStart"
+	JSONHandlerStart    = "handlerStart"
+	JSONHostOK          = "hostOK"
+	JSONHostFailed      = "hostFailed"
+	JSONHostSkipped     = "hostSkipped"
+	JSONHostUnreachable = "hostUnreachable"
+	JSONItemOK          = "itemOK"
+	JSONItemFailed      = "itemFailed"
+	JSONItemRetry       = "itemRetry"
+	JSONSummary         = "summary"
+)
+
+// JSONEvent is the JSON representation of an ansible event. The play and
+// the task are set on every event that happens within them.
+type JSONEvent struct {
+	Version  string `json:"version"`
+	Type     string `json:"type"`
+	Playbook string `json:"playbook,omitempty"`
+	Play     string `json:"play,omitempty"`
+	Task     string `json:"task,omitempty"`
+	Host     string `json:"host,omitempty"`
+	Item     string `json:"item,omitempty"`
+	Message  string `json:"message,omitempty"`
+	Stdout   string `json:"stdout,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	// IgnoreErrors is true if the failure of the task does not fail the play
+	IgnoreErrors bool `json:"ignoreErrors,omitempty"`
+	// PlayCount is the number of plays of the playbook
+	PlayCount int `json:"playCount,omitempty"`
+	// Summary is set on the summary event, which is the last event of the playbook
+	Summary *JSONEventSummary `json:"summary,omitempty"`
+}
+
+// JSONEventSummary summarizes the run of a playbook
+type JSONEventSummary struct {
+	Success     bool     `json:"success"`
+	Plays       int      `json:"plays"`
+	Tasks       int      `json:"tasks"`
+	OK          int      `json:"ok"`
+	Failed      int      `json:"failed"`
+	Ignored     int      `json:"ignored"`
+	Skipped     int      `json:"skipped"`
+	Unreachable int      `json:"unreachable"`
+	Retries     int      `json:"retries"`
+	FailedHosts []string `json:"failedHosts"`
+}
+
+// JSONEventExplainer explains each event as a JSON object on its own
+// line, and ends with a summary of the playbook. All events are explained,
+// regardless of the verbosity.
+type JSONEventExplainer struct {
+	playbook    string
+	currentPlay string
+	currentTask string
+	summary     JSONEventSummary
+	failedHosts map[string]bool
+}
+
+// ExplainEvent returns the JSON representation of the event
+func (explainer *JSONEventExplainer) ExplainEvent(e ansible.Event, verbose bool) string {
+	je := &JSONEvent{
+		Version:  JSONEventVersion,
+		Playbook: explainer.playbook,
+		Play:     explainer.currentPlay,
+		Task:     explainer.currentTask,
+	}
+	switch event := e.(type) {
+	case *ansible.PlaybookStartEvent:
+		explainer.playbook = event.Name
+		explainer.currentPlay = ""
+		explainer.currentTask = ""
+		explainer.summary = JSONEventSummary{}
+		explainer.failedHosts = map[string]bool{}
+		je.Type = JSONPlaybookStart
+		je.Playbook = event.Name
+		je.PlayCount = event.Count
+	case *ansible.PlayStartEvent:
+		explainer.currentPlay = event.Name
+		explainer.currentTask = ""
+		explainer.summary.Plays++
+		je.Type = JSONPlayStart
+		je.Play = event.Name
+		je.Task = ""
+	case *ansible.TaskStartEvent:
+		explainer.currentTask = event.Name
+		explainer.summary.Tasks++
+		je.Type = JSONTaskStart
+		je.Task = event.Name
+	case *ansible.HandlerTaskStartEvent:
+		explainer.currentTask = event.Name
+		explainer.summary.Tasks++
+		je.Type = JSONHandlerStart
+		je.Task = event.Name
+	case *ansible.RunnerOKEvent:
+		explainer.summary.OK++
+		je.Type = JSONHostOK
+		je.Host = event.Host
+	case *ansible.RunnerSkippedEvent:
+		explainer.summary.Skipped++
+		je.Type = JSONHostSkipped
+		je.Host = event.Host
+	case *ansible.RunnerFailedEvent:
+		explainer.recordFailure(event.Host, event.IgnoreErrors)
+		je.Type = JSONHostFailed
+		je.Host = event.Host
+		je.IgnoreErrors = event.IgnoreErrors
+		je.Message = event.Result.Message
+		je.Stdout = event.Result.Stdout
+		je.Stderr = event.Result.Stderr
+	case *ansible.RunnerUnreachableEvent:
+		explainer.summary.Unreachable++
+		explainer.failedHost(event.Host)
+		je.Type = JSONHostUnreachable
+		je.Host = event.Host
+		je.Message = event.Result.Message
+	case *ansible.RunnerItemOKEvent:
+		je.Type = JSONItemOK
+		je.Host = event.Host
+		je.Item = event.Result.Item
+	case *ansible.RunnerItemFailedEvent:
+		je.Type = JSONItemFailed
+		je.Host = event.Host
+		je.Item = event.Result.Item
+		je.IgnoreErrors = event.IgnoreErrors
+		je.Message = event.Result.Message
+		je.Stdout = event.Result.Stdout
+		je.Stderr = event.Result.Stderr
+	case *ansible.RunnerItemRetryEvent:
+		explainer.summary.Retries++
+		je.Type = JSONItemRetry
+		je.Host = event.Host
+		je.Item = event.Result.Item
+		je.Message = event.Result.Message
+	case *ansible.PlaybookEndEvent:
+		summary := explainer.summary
+		summary.FailedHosts = []string{}
+		for h := range explainer.failedHosts {
+			summary.FailedHosts = append(summary.FailedHosts, h)
+		}
+		sort.Strings(summary.FailedHosts)
+		summary.Success = summary.Failed == 0 && summary.Unreachable == 0
+		je.Type = JSONSummary
+		je.Play = ""
+		je.Task = ""
+		je.Summary = &summary
+	default:
+		return ""
+	}
+	b, err := json.Marshal(je)
+	if err != nil {
+		return ""
+	}
+	return string(b) + "\n"
+}
+
+// the item failures are followed by the failure of the task, so only the
+// failures of the tasks are counted
+func (explainer *JSONEventExplainer) recordFailure(host string, ignoreErrors bool) {
+	if ignoreErrors {
+		explainer.summary.Ignored++
+		return
+	}
+	explainer.summary.Failed++
+	explainer.failedHost(host)
+}
+
+func (explainer *JSONEventExplainer) failedHost(host string) {
+	if explainer.failedHosts == nil {
+		explainer.failedHosts = map[string]bool{}
+	}
+	explainer.failedHosts[host] = true
+}
@@ -0,0 +1,84 @@
+package explain
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestJSONEventExplainer(t *testing.T) {
+	playbookStart := &ansible.PlaybookStartEvent{Count: 1}
+	playbookStart.Name = "kubernetes.yaml"
+	play := &ansible.PlayStartEvent{}
+	play.Name = "Install Docker"
+	task := &ansible.TaskStartEvent{}
+	task.Name = "install docker"
+	ok := &ansible.RunnerOKEvent{}
+	ok.Host = "worker01"
+	retry := &ansible.RunnerItemRetryEvent{}
+	retry.Host = "worker02"
+	ignored := &ansible.RunnerFailedEvent{}
+	ignored.Host = "worker01"
+	ignored.IgnoreErrors = true
+	failed := &ansible.RunnerFailedEvent{}
+	failed.Host = "worker02"
+	failed.Result.Message = "package not found"
+	unreachable := &ansible.RunnerUnreachableEvent{}
+	unreachable.Host = "worker03"
+	events := []ansible.Event{playbookStart, play, task, ok, retry, ignored, failed, unreachable, &ansible.PlaybookEndEvent{}}
+
+	explainer := &JSONEventExplainer{}
+	var out []JSONEvent
+	for _, e := range events {
+		line := explainer.ExplainEvent(e, false)
+		if !strings.HasSuffix(line, "\n") || strings.Count(line, "\n") != 1 {
+			t.Fatalf("expected a single line for event %T, got %q", e, line)
+		}
+		var je JSONEvent
+		if err := json.Unmarshal([]byte(line), &je); err != nil {
+			t.Fatalf("error unmarshalling event %q: %v", line, err)
+		}
+		if je.Version != JSONEventVersion {
+			t.Errorf("expected version %q, got %q", JSONEventVersion, je.Version)
+		}
+		out = append(out, je)
+	}
+
+	failure := out[6]
+	if failure.Type != JSONHostFailed || failure.Playbook != "kubernetes.yaml" || failure.Play != "Install Docker" || failure.Task != "install docker" || failure.Host != "worker02" || failure.Message != "package not found" {
+		t.Errorf("unexpected failure event %+v", failure)
+	}
+	summary := out[len(out)-1]
+	if summary.Type != JSONSummary || summary.Summary == nil {
+		t.Fatalf("expected the last event to be the summary, got %+v", summary)
+	}
+	expected := JSONEventSummary{
+		Success:     false,
+		Plays:       1,
+		Tasks:       1,
+		OK:          1,
+		Failed:      1,
+		Ignored:     1,
+		Unreachable: 1,
+		Retries:     1,
+		FailedHosts: []string{"worker02", "worker03"},
+	}
+	if !reflect.DeepEqual(*summary.Summary, expected) {
+		t.Errorf("expected summary %+v, got %+v", expected, *summary.Summary)
+	}
+}
+
+func TestJSONEventExplainerSuccessfulSummary(t *testing.T) {
+	explainer := &JSONEventExplainer{}
+	explainer.ExplainEvent(&ansible.PlaybookStartEvent{}, false)
+	var je JSONEvent
+	if err := json.Unmarshal([]byte(explainer.ExplainEvent(&ansible.PlaybookEndEvent{}, false)), &je); err != nil {
+		t.Fatalf("error unmarshalling summary: %v", err)
+	}
+	if je.Summary == nil || !je.Summary.Success || je.Summary.FailedHosts == nil {
+		t.Errorf("expected a successful summary with an empty list of failed hosts, got %+v", je.Summary)
+	}
+}
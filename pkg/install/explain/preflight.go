@@ -36,13 +36,17 @@ func (explainer *PreflightEventExplainer) ExplainEvent(e ansible.Event, verbose
 		// print info about pre-flight checks that failed
 		util.PrintColor(buf, util.Red, "\n=> The following checks failed on %q:\n", event.Host)
 		for _, r := range results {
-			if !r.Success && r.Error != "" {
-				util.PrintColor(buf, util.Red, "   - %s: %v\n", r.Name, r.Error)
+			if r.Success {
 				continue
 			}
-			if !r.Success {
+			if r.Error != "" {
+				util.PrintColor(buf, util.Red, "   - %s: %v\n", r.Name, r.Error)
+			} else {
 				util.PrintColor(buf, util.Red, "   - %s\n", r.Name)
 			}
+			if r.Remediation != "" {
+				util.PrintColor(buf, util.Orange, "     Remediation: %s\n", r.Remediation)
+			}
 		}
 		if verbose {
 			util.PrintColor(buf, util.Green, "=> Successful pre-flight checks:\n")
@@ -0,0 +1,22 @@
+package explain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+)
+
+func TestPreflightEventExplainerRemediation(t *testing.T) {
+	failed := &ansible.RunnerFailedEvent{}
+	failed.Host = "worker01"
+	failed.Result.Stdout = `[{"Name":"Port Available: 10250","Success":false,"Error":"","Remediation":"Port 10250 is in use by nginx (PID 1234)."},{"Name":"Executable In Path: iptables","Success":true,"Error":"","Remediation":""}]`
+	explainer := &PreflightEventExplainer{DefaultExplainer: &DefaultEventExplainer{}}
+	out := explainer.ExplainEvent(failed, false)
+	if !strings.Contains(out, "Port Available: 10250") || !strings.Contains(out, "Remediation: Port 10250 is in use by nginx (PID 1234).") {
+		t.Errorf("expected the failed check and its remediation, got %q", out)
+	}
+	if strings.Contains(out, "iptables") {
+		t.Errorf("expected the successful check not to be printed, got %q", out)
+	}
+}
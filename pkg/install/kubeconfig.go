@@ -2,12 +2,17 @@ package install
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
-	"html/template"
 	"io/ioutil"
 	"path/filepath"
+	"text/template"
+	"time"
 
+	"github.com/apprenda/kismatic/pkg/tls"
 	"github.com/apprenda/kismatic/pkg/util"
+	"github.com/cloudflare/cfssl/helpers"
+	yaml "gopkg.in/yaml.v2"
 )
 
 // ConfigOptions sds
@@ -42,48 +47,210 @@ users:
     client-key-data: {{.Key}}
 `
 
-// GenerateKubeconfig generate a kubeconfig file for a specific user
+// GenerateKubeconfig generates the kubeconfig file of the admin user, and a
+// kubeconfig file named kubeconfig-<user> for each user of the plan
 func GenerateKubeconfig(p *Plan, generatedAssetsDir string) error {
-	user := "admin"
-	server := "https://" + p.Master.LoadBalancedFQDN + ":6443"
-	cluster := p.Cluster.Name
-	context := p.Cluster.Name + "-" + user
+	kubeconfigs := map[string]string{"admin": filepath.Join(generatedAssetsDir, "kubeconfig")}
+	for _, u := range p.Users {
+		kubeconfigs[u.Name] = filepath.Join(generatedAssetsDir, "kubeconfig-"+u.Name)
+	}
+	for user, file := range kubeconfigs {
+		kubeconfig, err := generateKubeconfig(p, user, filepath.Join(generatedAssetsDir, "keys"))
+		if err != nil {
+			return err
+		}
+		// Write config file, which has the key of the user
+		if err = ioutil.WriteFile(file, kubeconfig, 0600); err != nil {
+			return fmt.Errorf("error writing kubeconfig file: %v", err)
+		}
+	}
+	return nil
+}
 
-	certsDir := filepath.Join(generatedAssetsDir, "keys")
+// GenerateUserKubeconfig returns a kubeconfig for the admin user or a user
+// of the plan. The certificate of the user is generated when it does not
+// exist or has expired. When an expiry is given, a new certificate that is
+// valid for that long is issued and embedded in the kubeconfig, and the
+// certificate of the user in the generated keys directory is left as is.
+func GenerateUserKubeconfig(p *Plan, pki *LocalPKI, user string, expiry time.Duration) ([]byte, error) {
+	var planUser *User
+	for i := range p.Users {
+		if p.Users[i].Name == user {
+			planUser = &p.Users[i]
+		}
+	}
+	if user != "admin" && planUser == nil {
+		return nil, fmt.Errorf("user %q is not declared in the plan file, add it to the users section to grant it access to the cluster", user)
+	}
+	ca, err := pki.GetClusterCA()
+	if err != nil {
+		return nil, err
+	}
+	issue := func() (key, cert []byte, err error) {
+		if planUser != nil {
+			return tls.NewCert(ca, planUserCertRequest(p, *planUser))
+		}
+		return generateCert(user, p, []string{user}, ca)
+	}
 
-	// Base64 encoded ca
-	caEncoded, err := util.Base64String(filepath.Join(certsDir, "ca.pem"))
+	if expiry != 0 {
+		ca.Expiry = expiry
+		key, cert, err := issue()
+		if err != nil {
+			return nil, fmt.Errorf("error generating certificate for user %q: %v", user, err)
+		}
+		return renderKubeconfig(p, user, ca.Cert, cert, key)
+	}
+
+	if planUser != nil {
+		err = pki.generatePlanUserCert(p, *planUser, ca)
+	} else {
+		err = pki.generateUserCert(p, user, ca)
+	}
+	if err != nil {
+		return nil, err
+	}
+	expired, err := certificateExpired(filepath.Join(pki.GeneratedCertsDirectory, user+".pem"))
 	if err != nil {
-		return fmt.Errorf("error reading ca file for kubeconfig: %v", err)
+		return nil, err
+	}
+	if expired {
+		util.PrettyPrintOk(pki.Log, "Certificate of user %q has expired, regenerating certificates", user)
+		key, cert, err := issue()
+		if err != nil {
+			return nil, fmt.Errorf("error generating certificate for user %q: %v", user, err)
+		}
+		if err = tls.WriteCert(key, cert, user, pki.GeneratedCertsDirectory); err != nil {
+			return nil, fmt.Errorf("error writing cert files for user %q: %v", user, err)
+		}
 	}
-	// Base64 encoded cert
-	certEncoded, err := util.Base64String(filepath.Join(certsDir, user+".pem"))
+	return generateKubeconfig(p, user, pki.GeneratedCertsDirectory)
+}
+
+// returns true if the certificate is no longer valid
+func certificateExpired(file string) (bool, error) {
+	b, err := ioutil.ReadFile(file)
 	if err != nil {
-		return fmt.Errorf("error reading certificate file for kubeconfig: %v", err)
+		return false, fmt.Errorf("error reading certificate %q: %v", file, err)
 	}
-	// Base64 encoded key
-	keyEncoded, err := util.Base64String(filepath.Join(certsDir, user+"-key.pem"))
+	cert, err := helpers.ParseCertificatePEM(b)
 	if err != nil {
-		return fmt.Errorf("error reading certificate key file for kubeconfig: %v", err)
+		return false, fmt.Errorf("error parsing certificate %q: %v", file, err)
 	}
+	return time.Now().After(cert.NotAfter), nil
+}
+
+// returns a kubeconfig for the user, with the certificate of the user
+// found in the certificates directory
+func generateKubeconfig(p *Plan, user string, certsDir string) ([]byte, error) {
+	ca, err := ioutil.ReadFile(filepath.Join(certsDir, "ca.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading ca file for kubeconfig: %v", err)
+	}
+	cert, err := ioutil.ReadFile(filepath.Join(certsDir, user+".pem"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading certificate file for kubeconfig: %v", err)
+	}
+	key, err := ioutil.ReadFile(filepath.Join(certsDir, user+"-key.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading certificate key file for kubeconfig: %v", err)
+	}
+	return renderKubeconfig(p, user, ca, cert, key)
+}
+
+// returns a kubeconfig that points at the load balanced master of the cluster,
+// with the CA and the certificate of the user embedded. The user and the
+// context are named <cluster>-<user>, so that they do not replace the users
+// of other clusters with the same name when they are merged.
+func renderKubeconfig(p *Plan, user string, ca, cert, key []byte) ([]byte, error) {
+	server := "https://" + p.Master.LoadBalancedFQDN + ":6443"
+	cluster := p.Cluster.Name
+	context := p.Cluster.Name + "-" + user
 
 	// Process template file
 	tmpl, err := template.New("kubeconfig").Parse(kubeconfigTemplate)
 	if err != nil {
-		return fmt.Errorf("error reading config template: %v", err)
+		return nil, fmt.Errorf("error reading config template: %v", err)
+	}
+	configOptions := ConfigOptions{
+		CA:      base64.StdEncoding.EncodeToString(ca),
+		Server:  server,
+		Cluster: cluster,
+		User:    context,
+		Context: context,
+		Cert:    base64.StdEncoding.EncodeToString(cert),
+		Key:     base64.StdEncoding.EncodeToString(key),
 	}
-	configOptions := ConfigOptions{caEncoded, server, cluster, user, context, certEncoded, keyEncoded}
 	var kubeconfig bytes.Buffer
 	err = tmpl.Execute(&kubeconfig, configOptions)
 	if err != nil {
-		return fmt.Errorf("error processing config template: %v", err)
+		return nil, fmt.Errorf("error processing config template: %v", err)
+	}
+	return kubeconfig.Bytes(), nil
+}
+
+// MergeKubeconfig adds the clusters, contexts and users of the kubeconfig to
+// an existing kubeconfig, replacing the entries that have the same name.
+// The current context is set to the context of the kubeconfig.
+func MergeKubeconfig(existing, kubeconfig []byte) ([]byte, error) {
+	var dst, src yaml.MapSlice
+	if err := yaml.Unmarshal(existing, &dst); err != nil {
+		return nil, fmt.Errorf("error reading existing kubeconfig: %v", err)
 	}
-	// Write config file
-	kubeconfigFile := filepath.Join(generatedAssetsDir, "kubeconfig")
-	err = ioutil.WriteFile(kubeconfigFile, kubeconfig.Bytes(), 0644)
+	if len(dst) == 0 {
+		return kubeconfig, nil
+	}
+	if err := yaml.Unmarshal(kubeconfig, &src); err != nil {
+		return nil, fmt.Errorf("error reading kubeconfig: %v", err)
+	}
+	for _, section := range []string{"clusters", "contexts", "users"} {
+		entries, _ := mapSliceValue(dst, section).([]interface{})
+		newEntries, _ := mapSliceValue(src, section).([]interface{})
+		for _, e := range newEntries {
+			entries = mergeNamedEntry(entries, e)
+		}
+		dst = setMapSliceValue(dst, section, entries)
+	}
+	dst = setMapSliceValue(dst, "current-context", mapSliceValue(src, "current-context"))
+	b, err := yaml.Marshal(dst)
 	if err != nil {
-		return fmt.Errorf("error writing kubeconfig file: %v", err)
+		return nil, fmt.Errorf("error writing kubeconfig: %v", err)
+	}
+	return b, nil
+}
+
+// replaces the entry of the list that has the same name, or appends it
+func mergeNamedEntry(entries []interface{}, entry interface{}) []interface{} {
+	name := mapSliceValue(entry, "name")
+	for i, e := range entries {
+		if mapSliceValue(e, "name") == name {
+			entries[i] = entry
+			return entries
+		}
 	}
+	return append(entries, entry)
+}
 
+// returns the value of the key, or nil if m is not a map or does not have the key
+func mapSliceValue(m interface{}, key string) interface{} {
+	ms, ok := m.(yaml.MapSlice)
+	if !ok {
+		return nil
+	}
+	for _, item := range ms {
+		if item.Key == key {
+			return item.Value
+		}
+	}
 	return nil
 }
+
+func setMapSliceValue(m yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for i := range m {
+		if m[i].Key == key {
+			m[i].Value = value
+			return m
+		}
+	}
+	return append(m, yaml.MapItem{Key: key, Value: value})
+}
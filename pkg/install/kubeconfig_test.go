@@ -0,0 +1,251 @@
+package install
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudflare/cfssl/helpers"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestGenerateKubeconfigPlanUsers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubeconfig-tests")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+	pki.GeneratedCertsDirectory = filepath.Join(dir, "keys")
+
+	p := getPlan()
+	p.Users = []User{{Name: "jane"}}
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+	if err = GenerateKubeconfig(p, dir); err != nil {
+		t.Fatalf("unexpected error generating kubeconfig files: %v", err)
+	}
+	for file, user := range map[string]string{"kubeconfig": "admin", "kubeconfig-jane": "jane"} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Errorf("error reading kubeconfig file %q: %v", file, err)
+			continue
+		}
+		if !strings.Contains(string(b), "current-context: someName-"+user+"\n") {
+			t.Errorf("expected kubeconfig file %q to use the context of %q:\n%s", file, user, string(b))
+		}
+		info, err := os.Stat(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatalf("error reading kubeconfig file %q: %v", file, err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("expected kubeconfig file %q to have mode 0600, got %v", file, info.Mode().Perm())
+		}
+	}
+}
+
+func TestGenerateUserKubeconfig(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	p := getPlan()
+	p.Users = []User{{Name: "jane", Groups: []string{"developers"}}}
+	if _, err := pki.GenerateClusterCA(p); err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	// the certificate of the user is generated when it does not exist
+	kubeconfig, err := GenerateUserKubeconfig(p, &pki, "jane", 0)
+	if err != nil {
+		t.Fatalf("unexpected error generating kubeconfig: %v", err)
+	}
+	if !strings.Contains(string(kubeconfig), "server: https://"+p.Master.LoadBalancedFQDN+":6443\n") {
+		t.Errorf("expected kubeconfig to point at the load balanced FQDN:\n%s", string(kubeconfig))
+	}
+	stored, err := ioutil.ReadFile(filepath.Join(pki.GeneratedCertsDirectory, "jane.pem"))
+	if err != nil {
+		t.Fatalf("expected the certificate of the user to be generated: %v", err)
+	}
+	if !strings.Contains(string(kubeconfig), base64.StdEncoding.EncodeToString(stored)) {
+		t.Errorf("expected kubeconfig to embed the certificate of the user")
+	}
+
+	// a new certificate is issued when an expiry is given, and the stored one is kept
+	kubeconfig, err = GenerateUserKubeconfig(p, &pki, "jane", 2*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error generating kubeconfig: %v", err)
+	}
+	var config struct {
+		Users []struct {
+			User struct {
+				Cert string `yaml:"client-certificate-data"`
+			}
+		}
+	}
+	if err = yaml.Unmarshal(kubeconfig, &config); err != nil || len(config.Users) != 1 {
+		t.Fatalf("error reading kubeconfig: %v", err)
+	}
+	certPEM, err := base64.StdEncoding.DecodeString(config.Users[0].User.Cert)
+	if err != nil {
+		t.Fatalf("error decoding certificate: %v", err)
+	}
+	cert, err := helpers.ParseCertificatePEM(certPEM)
+	if err != nil {
+		t.Fatalf("error parsing certificate: %v", err)
+	}
+	if cert.NotAfter.After(time.Now().Add(3 * time.Hour)) {
+		t.Errorf("expected certificate to expire in 2 hours, but it expires on %v", cert.NotAfter)
+	}
+	if len(cert.Subject.Organization) != 1 || cert.Subject.Organization[0] != "developers" {
+		t.Errorf("expected the groups of the user in the certificate, got %v", cert.Subject.Organization)
+	}
+	afterExpiry, _ := ioutil.ReadFile(filepath.Join(pki.GeneratedCertsDirectory, "jane.pem"))
+	if string(afterExpiry) != string(stored) {
+		t.Errorf("expected the stored certificate of the user to be left as is")
+	}
+
+	if _, err = GenerateUserKubeconfig(p, &pki, "john", 0); err == nil {
+		t.Errorf("expected an error for a user that is not in the plan")
+	}
+}
+
+func TestMergeKubeconfig(t *testing.T) {
+	existing := `apiVersion: v1
+clusters:
+- cluster:
+    server: https://other:6443
+  name: other
+- cluster:
+    server: https://old:6443
+  name: someName
+contexts:
+- context:
+    cluster: other
+    user: bob
+  name: other-bob
+current-context: other-bob
+kind: Config
+preferences:
+  colors: true
+users:
+- name: bob
+  user:
+    token: sometoken
+`
+	kubeconfig := `apiVersion: v1
+clusters:
+- cluster:
+    server: https://new:6443
+  name: someName
+contexts:
+- context:
+    cluster: someName
+    user: jane
+  name: someName-jane
+current-context: someName-jane
+kind: Config
+preferences: {}
+users:
+- name: jane
+  user:
+    client-certificate-data: somecert
+`
+	merged, err := MergeKubeconfig([]byte(existing), []byte(kubeconfig))
+	if err != nil {
+		t.Fatalf("unexpected error merging kubeconfig: %v", err)
+	}
+	var config struct {
+		Clusters []struct {
+			Name    string
+			Cluster struct{ Server string }
+		}
+		Contexts       []struct{ Name string }
+		CurrentContext string `yaml:"current-context"`
+		Preferences    map[string]bool
+		Users          []struct{ Name string }
+	}
+	if err = yaml.Unmarshal(merged, &config); err != nil {
+		t.Fatalf("error reading merged kubeconfig: %v", err)
+	}
+	if len(config.Clusters) != 2 || config.Clusters[0].Name != "other" || config.Clusters[1].Cluster.Server != "https://new:6443" {
+		t.Errorf("expected the cluster with the same name to be replaced, got %+v", config.Clusters)
+	}
+	if len(config.Contexts) != 2 || len(config.Users) != 2 {
+		t.Errorf("expected the context and user to be added, got %+v and %+v", config.Contexts, config.Users)
+	}
+	if config.CurrentContext != "someName-jane" {
+		t.Errorf("expected current context to be %q, got %q", "someName-jane", config.CurrentContext)
+	}
+	if !config.Preferences["colors"] {
+		t.Errorf("expected the other settings of the existing kubeconfig to be kept")
+	}
+
+	// the kubeconfig is used as is when there is no existing kubeconfig
+	merged, err = MergeKubeconfig(nil, []byte(kubeconfig))
+	if err != nil {
+		t.Fatalf("unexpected error merging kubeconfig: %v", err)
+	}
+	if string(merged) != kubeconfig {
+		t.Errorf("expected the kubeconfig to be used as is, got:\n%s", string(merged))
+	}
+}
+
+func TestMergeKubeconfigClustersWithSameUser(t *testing.T) {
+	first := getPlan()
+	first.Cluster.Name = "first"
+	second := getPlan()
+	second.Cluster.Name = "second"
+	a, err := renderKubeconfig(first, "jane", []byte("ca"), []byte("first cert"), []byte("key"))
+	if err != nil {
+		t.Fatalf("unexpected error rendering kubeconfig: %v", err)
+	}
+	b, err := renderKubeconfig(second, "jane", []byte("ca"), []byte("second cert"), []byte("key"))
+	if err != nil {
+		t.Fatalf("unexpected error rendering kubeconfig: %v", err)
+	}
+	merged, err := MergeKubeconfig(a, b)
+	if err != nil {
+		t.Fatalf("unexpected error merging kubeconfig: %v", err)
+	}
+	var config struct {
+		Contexts []struct {
+			Name    string
+			Context struct{ Cluster, User string }
+		}
+		Users []struct {
+			Name string
+			User struct {
+				Cert string `yaml:"client-certificate-data"`
+			}
+		}
+	}
+	if err = yaml.Unmarshal(merged, &config); err != nil {
+		t.Fatalf("error reading merged kubeconfig: %v", err)
+	}
+	// jane of the second cluster must not replace jane of the first cluster
+	certs := map[string]string{}
+	for _, u := range config.Users {
+		cert, _ := base64.StdEncoding.DecodeString(u.User.Cert)
+		certs[u.Name] = string(cert)
+	}
+	if certs["first-jane"] != "first cert" || certs["second-jane"] != "second cert" {
+		t.Errorf("expected a user for each cluster, got %+v", config.Users)
+	}
+	if len(config.Contexts) != 2 {
+		t.Fatalf("expected a context for each cluster, got %+v", config.Contexts)
+	}
+	for _, c := range config.Contexts {
+		if c.Context.User != c.Context.Cluster+"-jane" {
+			t.Errorf("expected the context %q to use the user of its cluster, got %q", c.Name, c.Context.User)
+		}
+	}
+}
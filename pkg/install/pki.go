@@ -1,15 +1,21 @@
 package install
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/apprenda/kismatic/pkg/tls"
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/cloudflare/cfssl/csr"
+	"github.com/cloudflare/cfssl/helpers"
 )
 
+// The default subject of the certificates generated for the cluster
 const (
 	certOrganization = "Apprenda"
 	certOrgUnit      = "Kismatic"
@@ -18,14 +24,27 @@ const (
 	certLocality     = "Troy"
 )
 
+// caChainName is the name of the file that contains the chain of an
+// intermediate cluster CA
+const caChainName = "ca-chain"
+
+// monitoringUser is the user of the monitoring add-on, which has read-only
+// access to the cluster for scraping the metrics of its components
+const monitoringUser = "kismatic-monitoring"
+
+// the users whose certificates are generated for every cluster
+var clusterUsers = []string{"admin", monitoringUser}
+
 // The PKI provides a way for generating certificates for the cluster described by the Plan
 type PKI interface {
 	CertificateAuthorityExists() (bool, error)
 	NodeCertificateExists(node Node) (bool, error)
 	GenerateNodeCertificate(plan *Plan, node Node, ca *tls.CA) error
+	RegenerateNodeCertificate(plan *Plan, node Node, ca *tls.CA) error
 	GetClusterCA() (*tls.CA, error)
 	GenerateClusterCA(p *Plan) (*tls.CA, error)
 	GenerateClusterCertificates(p *Plan, ca *tls.CA, users []string) error
+	RotateClusterCertificates(p *Plan, users []string, rotateCA bool) (string, error)
 }
 
 // LocalPKI is a file-based PKI
@@ -59,6 +78,11 @@ func (lp *LocalPKI) GetClusterCA() (*tls.CA, error) {
 	}
 	ca.Cert = cert
 	ca.Key = key
+	chain, err := ioutil.ReadFile(filepath.Join(lp.GeneratedCertsDirectory, caChainName+".pem"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("error reading CA chain: %v", err)
+	}
+	ca.Chain = chain
 	return ca, nil
 }
 
@@ -73,19 +97,22 @@ func (lp *LocalPKI) GenerateClusterCA(p *Plan) (*tls.CA, error) {
 		return nil, fmt.Errorf("error verifying CA certificate/key: %v", err)
 	}
 	if exists {
-		return lp.GetClusterCA()
+		ca, err = lp.GetClusterCA()
+		if err != nil {
+			return nil, err
+		}
+		if err = verifyProvidedCA(p, ca); err != nil {
+			return nil, err
+		}
+		return ca, nil
+	}
+	if p.Cluster.Certificates.CACert != "" {
+		return lp.importClusterCA(p)
 	}
 
 	util.PrettyPrintOk(lp.Log, "Generating cluster Certificate Authority")
 	// It doesn't exist, generate one
-	caSubject := tls.Subject{
-		Organization:       certOrganization,
-		OrganizationalUnit: certOrgUnit,
-		Country:            certCountry,
-		State:              certState,
-		Locality:           certLocality,
-	}
-	key, cert, err := tls.NewCACert(lp.CACsr, p.Cluster.Name, caSubject)
+	key, cert, err := tls.NewCACert(lp.CACsr, p.Cluster.Name, certSubject(p))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CA Cert: %v", err)
 	}
@@ -97,6 +124,108 @@ func (lp *LocalPKI) GenerateClusterCA(p *Plan) (*tls.CA, error) {
 	return ca, nil
 }
 
+// copies the CA provided in the plan to the generated certs directory
+func (lp *LocalPKI) importClusterCA(p *Plan) (*tls.CA, error) {
+	certs := p.Cluster.Certificates
+	util.PrettyPrintOk(lp.Log, "Using the Certificate Authority at %q", certs.CACert)
+	cert, err := ioutil.ReadFile(certs.CACert)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA certificate: %v", err)
+	}
+	key, err := ioutil.ReadFile(certs.CAKey)
+	if err != nil {
+		return nil, fmt.Errorf("error reading CA private key: %v", err)
+	}
+	var chain []byte
+	if certs.CAChain != "" {
+		chain, err = ioutil.ReadFile(certs.CAChain)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA chain: %v", err)
+		}
+	}
+	if err = tls.ValidateCA(key, cert, chain); err != nil {
+		return nil, fmt.Errorf("invalid Certificate Authority %q: %v", certs.CACert, err)
+	}
+	if err = tls.WriteCert(key, cert, "ca", lp.GeneratedCertsDirectory); err != nil {
+		return nil, fmt.Errorf("error writing CA files: %v", err)
+	}
+	if chain != nil {
+		// The chain is appended to the serving certificates of the cluster
+		// when they are generated
+		if err = ioutil.WriteFile(filepath.Join(lp.GeneratedCertsDirectory, caChainName+".pem"), chain, 0644); err != nil {
+			return nil, fmt.Errorf("error writing CA chain: %v", err)
+		}
+	}
+	return &tls.CA{
+		Key:        key,
+		Cert:       cert,
+		Chain:      chain,
+		ConfigFile: lp.CAConfigFile,
+		Profile:    lp.CASigningProfile,
+	}, nil
+}
+
+// returns an error if the plan refers to a CA that is not the one in use by the cluster
+func verifyProvidedCA(p *Plan, ca *tls.CA) error {
+	if p.Cluster.Certificates.CACert == "" {
+		return nil
+	}
+	same, err := isProvidedCA(p, ca)
+	if err != nil {
+		return err
+	}
+	if !same {
+		return fmt.Errorf("the CA certificate %q is not the CA of the cluster. Use \"kismatic certificates rotate --rotate-ca\" to replace the CA", p.Cluster.Certificates.CACert)
+	}
+	return nil
+}
+
+// returns true if the CA certificate provided in the plan is the certificate of the CA
+func isProvidedCA(p *Plan, ca *tls.CA) (bool, error) {
+	provided, err := ioutil.ReadFile(p.Cluster.Certificates.CACert)
+	if err != nil {
+		return false, fmt.Errorf("error reading CA certificate: %v", err)
+	}
+	providedCert, err := helpers.ParseCertificatePEM(provided)
+	if err != nil {
+		return false, fmt.Errorf("error parsing CA certificate %q: %v", p.Cluster.Certificates.CACert, err)
+	}
+	clusterCert, err := helpers.ParseCertificatePEM(ca.Cert)
+	if err != nil {
+		return false, fmt.Errorf("error parsing cluster CA certificate: %v", err)
+	}
+	return providedCert.Equal(clusterCert), nil
+}
+
+// returns the subject of the certificates generated for the cluster,
+// using the defaults for the fields that are not set in the plan
+func certSubject(p *Plan) tls.Subject {
+	s := tls.Subject{
+		Organization:       certOrganization,
+		OrganizationalUnit: certOrgUnit,
+		Country:            certCountry,
+		State:              certState,
+		Locality:           certLocality,
+	}
+	planSubject := p.Cluster.Certificates.Subject
+	if planSubject.Organization != "" {
+		s.Organization = planSubject.Organization
+	}
+	if planSubject.OrganizationalUnit != "" {
+		s.OrganizationalUnit = planSubject.OrganizationalUnit
+	}
+	if planSubject.Country != "" {
+		s.Country = planSubject.Country
+	}
+	if planSubject.State != "" {
+		s.State = planSubject.State
+	}
+	if planSubject.Locality != "" {
+		s.Locality = planSubject.Locality
+	}
+	return s
+}
+
 // GenerateClusterCertificates creates a Certificates for all nodes on the cluster
 func (lp *LocalPKI) GenerateClusterCertificates(p *Plan, ca *tls.CA, users []string) error {
 	if lp.Log == nil {
@@ -140,9 +269,70 @@ func (lp *LocalPKI) GenerateClusterCertificates(p *Plan, ca *tls.CA, users []str
 			return err
 		}
 	}
+	for _, user := range p.Users {
+		if err := lp.generatePlanUserCert(p, user, ca); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// RotateClusterCertificates moves the existing certificates of the cluster to a
+// backup directory and generates new ones. The CA is only replaced when rotateCA
+// is true. If the plan provides the CA, it must be a different CA than the one in
+// use by the cluster. The path to the backup directory is returned.
+func (lp *LocalPKI) RotateClusterCertificates(p *Plan, users []string, rotateCA bool) (string, error) {
+	if lp.Log == nil {
+		lp.Log = ioutil.Discard
+	}
+	caExists, err := lp.CertificateAuthorityExists()
+	if err != nil {
+		return "", fmt.Errorf("error while checking if cluster CA exists: %v", err)
+	}
+	if !caExists && !rotateCA {
+		return "", fmt.Errorf("the cluster CA was not found in %q", lp.GeneratedCertsDirectory)
+	}
+	if rotateCA && caExists && p.Cluster.Certificates.CACert != "" {
+		ca, err := lp.GetClusterCA()
+		if err != nil {
+			return "", err
+		}
+		same, err := isProvidedCA(p, ca)
+		if err != nil {
+			return "", err
+		}
+		if same {
+			return "", fmt.Errorf("the CA certificate %q is already the CA of the cluster. Set ca_cert and ca_key in the plan file to the new Certificate Authority to replace it", p.Cluster.Certificates.CACert)
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(lp.GeneratedCertsDirectory, "*.pem"))
+	if err != nil {
+		return "", fmt.Errorf("error listing certificates: %v", err)
+	}
+	backupDir := filepath.Join(filepath.Dir(lp.GeneratedCertsDirectory), "keys-backup", time.Now().Format("2006-01-02-15-04-05"))
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return "", fmt.Errorf("error creating directory %q: %v", backupDir, err)
+	}
+	for _, f := range files {
+		name := filepath.Base(f)
+		if !rotateCA && (name == "ca.pem" || name == "ca-key.pem" || name == caChainName+".pem") {
+			continue
+		}
+		if err := os.Rename(f, filepath.Join(backupDir, name)); err != nil {
+			return "", fmt.Errorf("error backing up %q: %v", f, err)
+		}
+	}
+	util.PrettyPrintOk(lp.Log, "Existing certificates were moved to %q", backupDir)
+	ca, err := lp.GenerateClusterCA(p)
+	if err != nil {
+		return "", fmt.Errorf("error generating CA for the cluster: %v", err)
+	}
+	if err := lp.GenerateClusterCertificates(p, ca, users); err != nil {
+		return "", fmt.Errorf("error generating certificates for the cluster: %v", err)
+	}
+	return backupDir, nil
+}
+
 // ValidateClusterCertificates validates all certificates in the cluster
 func (lp *LocalPKI) ValidateClusterCertificates(p *Plan, users []string) (warn []error, err []error) {
 	if lp.Log == nil {
@@ -165,7 +355,7 @@ func (lp *LocalPKI) ValidateClusterCertificates(p *Plan, users []string) (warn [
 		seenNodes[n.Host] = true
 		_, nodeWarn, nodeErr := lp.validateNodeCertificate(p, n)
 		warn = append(warn, nodeWarn...)
-		if err != nil {
+		if nodeErr != nil {
 			err = append(err, nodeErr)
 		}
 	}
@@ -173,21 +363,28 @@ func (lp *LocalPKI) ValidateClusterCertificates(p *Plan, users []string) (warn [
 	if p.DockerRegistry.SetupInternal {
 		_, dockerWarn, dockerErr := lp.validateDockerRegistryCert(p)
 		warn = append(warn, dockerWarn...)
-		if err != nil {
+		if dockerErr != nil {
 			err = append(err, dockerErr)
 		}
 	}
 	// Create key for service account signing
 	_, saWarn, saErr := lp.validateServiceAccountCert()
 	warn = append(warn, saWarn...)
-	if err != nil {
+	if saErr != nil {
 		err = append(err, saErr)
 	}
 	// Finally, create certs for user if they are missing
 	for _, user := range users {
 		_, userWarn, userErr := lp.validateUserCert(user)
 		warn = append(warn, userWarn...)
-		if err != nil {
+		if userErr != nil {
+			err = append(err, userErr)
+		}
+	}
+	for _, user := range p.Users {
+		_, userWarn, userErr := lp.validateUserCert(user.Name)
+		warn = append(warn, userWarn...)
+		if userErr != nil {
 			err = append(err, userErr)
 		}
 	}
@@ -197,20 +394,10 @@ func (lp *LocalPKI) ValidateClusterCertificates(p *Plan, users []string) (warn [
 // GenerateNodeCertificate creates a private key and certificate for the given node
 func (lp *LocalPKI) GenerateNodeCertificate(plan *Plan, node Node, ca *tls.CA) error {
 	CN := node.Host
-	// Build list of SANs
-	clusterSANs, err := clusterCertsSubjectAlternateNames(plan)
+	nodeSANs, err := nodeCertSubjectAlternateNames(plan, node)
 	if err != nil {
 		return err
 	}
-	nodeSANs := append(clusterSANs, node.Host, node.IP, node.InternalIP)
-	if isMasterNode(*plan, node) {
-		if plan.Master.LoadBalancedFQDN != "" {
-			nodeSANs = append(nodeSANs, plan.Master.LoadBalancedFQDN)
-		}
-		if plan.Master.LoadBalancedShortName != "" {
-			nodeSANs = append(nodeSANs, plan.Master.LoadBalancedShortName)
-		}
-	}
 
 	// Don't generate if the key pair exists and valid
 	valid, warn, err := tls.CertExistsAndValid(CN, nodeSANs, node.Host, lp.GeneratedCertsDirectory)
@@ -233,30 +420,42 @@ func (lp *LocalPKI) GenerateNodeCertificate(plan *Plan, node Node, ca *tls.CA) e
 	if err != nil {
 		return fmt.Errorf("error during cluster cert generation: %v", err)
 	}
-	err = tls.WriteCert(key, cert, node.Host, lp.GeneratedCertsDirectory)
+	err = tls.WriteCert(key, withCAChain(cert, ca), node.Host, lp.GeneratedCertsDirectory)
 	if err != nil {
 		return fmt.Errorf("error writing cert files for host %q: %v", node.Host, err)
 	}
 	return nil
 }
 
-func (lp *LocalPKI) validateNodeCertificate(p *Plan, node Node) (valid bool, warn []error, err error) {
-	CN := node.Host
-	// Build list of SANs
-	clusterSANs, err := clusterCertsSubjectAlternateNames(p)
+// RegenerateNodeCertificate creates a private key and certificate for the given node.
+// If the node's existing certificate does not match the plan, for example when the
+// node is taking on a new role, it is moved aside with a ".bak" suffix and replaced.
+func (lp *LocalPKI) RegenerateNodeCertificate(plan *Plan, node Node, ca *tls.CA) error {
+	if lp.Log == nil {
+		lp.Log = ioutil.Discard
+	}
+	_, warn, err := lp.validateNodeCertificate(plan, node)
 	if err != nil {
-		return false, nil, err
+		return err
 	}
-	nodeSANs := append(clusterSANs, node.Host, node.IP, node.InternalIP)
-	if isMasterNode(*p, node) {
-		if p.Master.LoadBalancedFQDN != "" {
-			nodeSANs = append(nodeSANs, p.Master.LoadBalancedFQDN)
-		}
-		if p.Master.LoadBalancedShortName != "" {
-			nodeSANs = append(nodeSANs, p.Master.LoadBalancedShortName)
+	if len(warn) > 0 {
+		util.PrettyPrintWarn(lp.Log, "Existing certificate for node %q does not match the plan and will be replaced", node.Host)
+		for _, name := range []string{node.Host + ".pem", node.Host + "-key.pem"} {
+			file := filepath.Join(lp.GeneratedCertsDirectory, name)
+			if err := os.Rename(file, file+".bak"); err != nil {
+				return fmt.Errorf("error backing up %q: %v", file, err)
+			}
 		}
 	}
+	return lp.GenerateNodeCertificate(plan, node, ca)
+}
 
+func (lp *LocalPKI) validateNodeCertificate(p *Plan, node Node) (valid bool, warn []error, err error) {
+	CN := node.Host
+	nodeSANs, err := nodeCertSubjectAlternateNames(p, node)
+	if err != nil {
+		return false, nil, err
+	}
 	return tls.CertExistsAndValid(CN, nodeSANs, node.Host, lp.GeneratedCertsDirectory)
 }
 
@@ -287,13 +486,27 @@ func (lp *LocalPKI) generateDockerRegistryCert(p *Plan, ca *tls.CA) error {
 	if err != nil {
 		return fmt.Errorf("error during user cert generation: %v", err)
 	}
-	err = tls.WriteCert(dockerKey, dockerCert, "docker", lp.GeneratedCertsDirectory)
+	err = tls.WriteCert(dockerKey, withCAChain(dockerCert, ca), "docker", lp.GeneratedCertsDirectory)
 	if err != nil {
 		return fmt.Errorf("error writing cert files for docker registry")
 	}
 	return nil
 }
 
+// returns the serving certificate followed by an intermediate CA and its
+// chain, so that clients that only trust the root CA can verify it
+func withCAChain(cert []byte, ca *tls.CA) []byte {
+	if len(ca.Chain) == 0 {
+		return cert
+	}
+	bundle := []byte{}
+	for _, b := range [][]byte{cert, ca.Cert, ca.Chain} {
+		bundle = append(bundle, bytes.TrimSpace(b)...)
+		bundle = append(bundle, '\n')
+	}
+	return bundle
+}
+
 func (lp *LocalPKI) validateDockerRegistryCert(p *Plan) (valid bool, warn []error, err error) {
 	// Default registry will be deployed on the first master
 	n := p.Master.Nodes[0]
@@ -374,6 +587,95 @@ func (lp *LocalPKI) generateUserCert(p *Plan, user string, ca *tls.CA) error {
 	return nil
 }
 
+// generates the certificate of a user of the plan, with the groups of the user
+// as the Organization. The certificate is replaced when the groups change.
+func (lp *LocalPKI) generatePlanUserCert(p *Plan, user User, ca *tls.CA) error {
+	SANs := []string{user.Name}
+	valid, warn, err := tls.CertExistsAndValid(user.Name, SANs, user.Name, lp.GeneratedCertsDirectory)
+	if err != nil {
+		return err
+	}
+	if warn != nil && len(warn) > 0 {
+		util.PrettyPrintErr(lp.Log, "Found key and certificate for user %q but it is not valid", user.Name)
+		util.PrintValidationErrors(lp.Log, warn)
+		return fmt.Errorf("error verifying certificates for user %q", user.Name)
+	}
+	if valid {
+		groups, err := certificateGroups(filepath.Join(lp.GeneratedCertsDirectory, user.Name+".pem"))
+		if err != nil {
+			return err
+		}
+		if sameStrings(groups, user.Groups) {
+			util.PrettyPrintOk(lp.Log, "Found key and certificate for user %q", user.Name)
+			return nil
+		}
+		util.PrettyPrintOk(lp.Log, "Groups of user %q changed, regenerating certificates", user.Name)
+	} else {
+		util.PrettyPrintOk(lp.Log, "Generating certificates for user %q", user.Name)
+	}
+
+	key, cert, err := tls.NewCert(ca, planUserCertRequest(p, user))
+	if err != nil {
+		return fmt.Errorf("error generating certs for user %q: %v", user.Name, err)
+	}
+	if err = tls.WriteCert(key, cert, user.Name, lp.GeneratedCertsDirectory); err != nil {
+		return fmt.Errorf("error writing cert files for user %q: %v", user.Name, err)
+	}
+	return nil
+}
+
+// returns the certificate request of a user of the plan
+func planUserCertRequest(p *Plan, user User) csr.CertificateRequest {
+	subject := certSubject(p)
+	req := csr.CertificateRequest{
+		CN: user.Name,
+		KeyRequest: &csr.BasicKeyRequest{
+			A: "rsa",
+			S: 2048,
+		},
+		Hosts: []string{user.Name},
+		Names: []csr.Name{
+			{
+				OU: subject.OrganizationalUnit,
+				C:  subject.Country,
+				ST: subject.State,
+				L:  subject.Locality,
+			},
+		},
+	}
+	// Kubernetes reads the groups of the user from the Organization
+	for _, g := range user.Groups {
+		req.Names = append(req.Names, csr.Name{O: g})
+	}
+	return req
+}
+
+// returns the groups of a user certificate, read from its Organization
+func certificateGroups(file string) ([]string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading certificate %q: %v", file, err)
+	}
+	cert, err := helpers.ParseCertificatePEM(b)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing certificate %q: %v", file, err)
+	}
+	return cert.Subject.Organization, nil
+}
+
+// returns true if the lists contain the same strings, in any order
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, s := range a {
+		if !containsString(b, s) {
+			return false
+		}
+	}
+	return true
+}
+
 func (lp *LocalPKI) validateUserCert(user string) (valid bool, warn []error, err error) {
 	SANs := []string{user}
 
@@ -381,6 +683,7 @@ func (lp *LocalPKI) validateUserCert(user string) (valid bool, warn []error, err
 }
 
 func generateCert(cnName string, p *Plan, hostList []string, ca *tls.CA) (key, cert []byte, err error) {
+	subject := certSubject(p)
 	req := csr.CertificateRequest{
 		CN: cnName,
 		KeyRequest: &csr.BasicKeyRequest{
@@ -390,11 +693,11 @@ func generateCert(cnName string, p *Plan, hostList []string, ca *tls.CA) (key, c
 		Hosts: hostList,
 		Names: []csr.Name{
 			{
-				O:  certOrganization,
-				OU: certOrgUnit,
-				C:  certCountry,
-				ST: certState,
-				L:  certLocality,
+				O:  subject.Organization,
+				OU: subject.OrganizationalUnit,
+				C:  subject.Country,
+				ST: subject.State,
+				L:  subject.Locality,
 			},
 		},
 	}
@@ -421,6 +724,24 @@ func clusterCertsSubjectAlternateNames(plan *Plan) ([]string, error) {
 	return defaultCertHosts, nil
 }
 
+// returns the SANs of the node's certificate
+func nodeCertSubjectAlternateNames(plan *Plan, node Node) ([]string, error) {
+	clusterSANs, err := clusterCertsSubjectAlternateNames(plan)
+	if err != nil {
+		return nil, err
+	}
+	nodeSANs := append(clusterSANs, node.Host, node.IP, node.InternalIP)
+	if isMasterNode(*plan, node) {
+		if plan.Master.LoadBalancedFQDN != "" {
+			nodeSANs = append(nodeSANs, plan.Master.LoadBalancedFQDN)
+		}
+		if plan.Master.LoadBalancedShortName != "" {
+			nodeSANs = append(nodeSANs, plan.Master.LoadBalancedShortName)
+		}
+	}
+	return nodeSANs, nil
+}
+
 func isMasterNode(plan Plan, node Node) bool {
 	for _, master := range plan.Master.Nodes {
 		if node == master {
@@ -1,9 +1,14 @@
 package install
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"net"
 	"os"
 	"path"
@@ -11,6 +16,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/apprenda/kismatic/pkg/tls"
 	"github.com/cloudflare/cfssl/helpers"
 )
 
@@ -527,6 +533,47 @@ func TestGenerateClusterCertificatesUserSubject(t *testing.T) {
 	}
 }
 
+func TestGenerateClusterCertificatesPlanUsers(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	p := getPlan()
+	p.Users = []User{{Name: "jane", Groups: []string{"developers", "operators"}}}
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+	certFile := filepath.Join(pki.GeneratedCertsDirectory, "jane.pem")
+	userCert := mustReadCertFile(certFile, t)
+	if userCert.Subject.CommonName != "jane" {
+		t.Errorf("common name mismatch: got %q, expected %q", userCert.Subject.CommonName, "jane")
+	}
+	if !sameStrings(userCert.Subject.Organization, []string{"developers", "operators"}) {
+		t.Errorf("expected the groups of the user in the organization, got %v", userCert.Subject.Organization)
+	}
+
+	// the certificate is kept when the groups are the same
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+	if cert := mustReadCertFile(certFile, t); cert.SerialNumber.Cmp(userCert.SerialNumber) != 0 {
+		t.Errorf("the certificate of the user was regenerated")
+	}
+
+	// and replaced when they change
+	p.Users[0].Groups = []string{"operators"}
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+	userCert = mustReadCertFile(certFile, t)
+	if !sameStrings(userCert.Subject.Organization, []string{"operators"}) {
+		t.Errorf("expected the certificate to be regenerated with the new groups, got %v", userCert.Subject.Organization)
+	}
+}
+
 func TestGenerateClusterCertificatesDockerCert(t *testing.T) {
 	pki := getPKI(t)
 	defer cleanup(pki.GeneratedCertsDirectory, t)
@@ -655,3 +702,415 @@ func TestBadDockerCertificate(t *testing.T) {
 		t.Fatalf("expected an error, got nil")
 	}
 }
+
+func TestRegenerateNodeCertificateForNewMaster(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	p := getPlan()
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+
+	// Promote the worker to a master
+	worker := p.Worker.Nodes[0]
+	p.Master.Nodes = append(p.Master.Nodes, worker)
+	if err = pki.RegenerateNodeCertificate(p, worker, ca); err != nil {
+		t.Fatalf("failed to regenerate certificate: %v", err)
+	}
+
+	certFile := filepath.Join(pki.GeneratedCertsDirectory, "worker.pem")
+	cert := mustReadCertFile(certFile, t)
+	found := false
+	for _, name := range cert.DNSNames {
+		if name == p.Master.LoadBalancedFQDN {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Errorf("load balanced FQDN was not found in regenerated certificate")
+	}
+	if _, err := os.Stat(certFile + ".bak"); err != nil {
+		t.Errorf("previous certificate was not backed up: %v", err)
+	}
+}
+
+func TestRotateClusterCertificates(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+	// backups are stored next to the keys directory
+	pki.GeneratedCertsDirectory = filepath.Join(pki.GeneratedCertsDirectory, "keys")
+
+	p := getPlan()
+	node := p.Worker.Nodes[0]
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+	certFile := filepath.Join(pki.GeneratedCertsDirectory, node.Host+".pem")
+	oldCert := mustReadCertFile(certFile, t)
+	oldCA := mustReadCertFile(filepath.Join(pki.GeneratedCertsDirectory, "ca.pem"), t)
+
+	backupDir, err := pki.RotateClusterCertificates(p, []string{"admin"}, false)
+	if err != nil {
+		t.Fatalf("unexpected error rotating certificates: %v", err)
+	}
+	newCert := mustReadCertFile(certFile, t)
+	if newCert.SerialNumber.Cmp(oldCert.SerialNumber) == 0 {
+		t.Errorf("node certificate was not regenerated")
+	}
+	newCA := mustReadCertFile(filepath.Join(pki.GeneratedCertsDirectory, "ca.pem"), t)
+	if !newCA.Equal(oldCA) {
+		t.Errorf("the CA was replaced when rotateCA was false")
+	}
+	backupCert := mustReadCertFile(filepath.Join(backupDir, node.Host+".pem"), t)
+	if !backupCert.Equal(oldCert) {
+		t.Errorf("the previous node certificate was not backed up")
+	}
+	for _, name := range []string{"admin.pem", "service-account.pem"} {
+		if _, err := os.Stat(filepath.Join(pki.GeneratedCertsDirectory, name)); err != nil {
+			t.Errorf("expected %s to be regenerated: %v", name, err)
+		}
+	}
+}
+
+func TestRotateClusterCertificatesNewCA(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+	pki.GeneratedCertsDirectory = filepath.Join(pki.GeneratedCertsDirectory, "keys")
+
+	p := getPlan()
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+	oldCA := mustReadCertFile(filepath.Join(pki.GeneratedCertsDirectory, "ca.pem"), t)
+	if _, err = pki.RotateClusterCertificates(p, []string{"admin"}, true); err != nil {
+		t.Fatalf("unexpected error rotating certificates: %v", err)
+	}
+	newCA := mustReadCertFile(filepath.Join(pki.GeneratedCertsDirectory, "ca.pem"), t)
+	if newCA.Equal(oldCA) {
+		t.Errorf("the CA was not replaced when rotateCA was true")
+	}
+	cert := mustReadCertFile(filepath.Join(pki.GeneratedCertsDirectory, p.Worker.Nodes[0].Host+".pem"), t)
+	if err := cert.CheckSignatureFrom(newCA); err != nil {
+		t.Errorf("node certificate was not signed by the new CA: %v", err)
+	}
+}
+
+func TestRotateClusterCertificatesMissingCA(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+	if _, err := pki.RotateClusterCertificates(getPlan(), []string{"admin"}, false); err == nil {
+		t.Errorf("expected an error when the CA does not exist")
+	}
+}
+
+func TestGenerateClusterCACustomSubject(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	p := getPlan()
+	p.Cluster.Certificates.Subject = CertSubject{
+		Organization:       "someOrg",
+		OrganizationalUnit: "someOrgUnit",
+		Country:            "DE",
+	}
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("failed to generate cluster CA: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+	for _, name := range []string{"ca.pem", "worker.pem"} {
+		cert := mustReadCertFile(filepath.Join(pki.GeneratedCertsDirectory, name), t)
+		if cert.Subject.Organization[0] != "someOrg" {
+			t.Errorf("%s: expected organization %q, got %q", name, "someOrg", cert.Subject.Organization[0])
+		}
+		if cert.Subject.OrganizationalUnit[0] != "someOrgUnit" {
+			t.Errorf("%s: expected organizational unit %q, got %q", name, "someOrgUnit", cert.Subject.OrganizationalUnit[0])
+		}
+		if cert.Subject.Country[0] != "DE" {
+			t.Errorf("%s: expected country %q, got %q", name, "DE", cert.Subject.Country[0])
+		}
+		// fields that are not set in the plan use the defaults
+		if cert.Subject.Locality[0] != certLocality {
+			t.Errorf("%s: expected locality %q, got %q", name, certLocality, cert.Subject.Locality[0])
+		}
+	}
+}
+
+func TestGenerateClusterCAProvidedCA(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	// An existing CA, created outside of the generated certs directory
+	providedDir := mustGetTempDir(t)
+	defer cleanup(providedDir, t)
+	key, cert, err := tls.NewCACert("test/ca-csr.json", "corporate", tls.Subject{Organization: "corp"})
+	if err != nil {
+		t.Fatalf("error creating CA for test: %v", err)
+	}
+	if err = tls.WriteCert(key, cert, "corporate-ca", providedDir); err != nil {
+		t.Fatalf("error writing CA for test: %v", err)
+	}
+	p := getPlan()
+	p.Cluster.Certificates.CACert = filepath.Join(providedDir, "corporate-ca.pem")
+	p.Cluster.Certificates.CAKey = filepath.Join(providedDir, "corporate-ca-key.pem")
+
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("unexpected error using provided CA: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+	caCert := mustReadCertFile(filepath.Join(pki.GeneratedCertsDirectory, "ca.pem"), t)
+	if caCert.Subject.CommonName != "corporate" {
+		t.Errorf("expected the provided CA to be used, got CA with CN %q", caCert.Subject.CommonName)
+	}
+	nodeCert := mustReadCertFile(filepath.Join(pki.GeneratedCertsDirectory, "worker.pem"), t)
+	if err = nodeCert.CheckSignatureFrom(caCert); err != nil {
+		t.Errorf("node certificate was not signed by the provided CA: %v", err)
+	}
+
+	// A different CA in the plan is rejected once the cluster has a CA
+	otherKey, otherCert, err := tls.NewCACert("test/ca-csr.json", "other", tls.Subject{})
+	if err != nil {
+		t.Fatalf("error creating CA for test: %v", err)
+	}
+	if err = tls.WriteCert(otherKey, otherCert, "other-ca", providedDir); err != nil {
+		t.Fatalf("error writing CA for test: %v", err)
+	}
+	p.Cluster.Certificates.CACert = filepath.Join(providedDir, "other-ca.pem")
+	p.Cluster.Certificates.CAKey = filepath.Join(providedDir, "other-ca-key.pem")
+	if _, err = pki.GenerateClusterCA(p); err == nil {
+		t.Errorf("expected an error when the plan CA does not match the cluster CA")
+	}
+}
+
+// writes a root CA and an intermediate CA signed by it to the directory
+func mustWriteIntermediateCA(t *testing.T, dir string) (rootFile, certFile, keyFile string) {
+	rootKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	caTemplate := func(cn string) *x509.Certificate {
+		return &x509.Certificate{
+			SerialNumber:          big.NewInt(time.Now().UnixNano()),
+			Subject:               pkix.Name{CommonName: cn},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(24 * time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+	}
+	root := caTemplate("root")
+	rootDER, err := x509.CreateCertificate(rand.Reader, root, root, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("error creating root CA: %v", err)
+	}
+	intermediateDER, err := x509.CreateCertificate(rand.Reader, caTemplate("intermediate"), root, &key.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("error creating intermediate CA: %v", err)
+	}
+	files := map[string]*pem.Block{
+		"root.pem":             {Type: "CERTIFICATE", Bytes: rootDER},
+		"intermediate.pem":     {Type: "CERTIFICATE", Bytes: intermediateDER},
+		"intermediate-key.pem": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+	}
+	for name, block := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatalf("error writing %s: %v", name, err)
+		}
+	}
+	return filepath.Join(dir, "root.pem"), filepath.Join(dir, "intermediate.pem"), filepath.Join(dir, "intermediate-key.pem")
+}
+
+// verifies the certificate, followed by its chain, against the root CA alone
+func verifyCertAgainstRoot(t *testing.T, certFile, rootFile string) {
+	certPEM, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("failed to read certificate file: %v", err)
+	}
+	certs, err := helpers.ParseCertificatesPEM(certPEM)
+	if err != nil {
+		t.Fatalf("error parsing %s: %v", certFile, err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(mustReadCertFile(rootFile, t))
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		t.Errorf("%s could not be verified against the root CA: %v", filepath.Base(certFile), err)
+	}
+}
+
+func TestGenerateClusterCertificatesIntermediateCA(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+	providedDir := mustGetTempDir(t)
+	defer cleanup(providedDir, t)
+	rootFile, certFile, keyFile := mustWriteIntermediateCA(t, providedDir)
+
+	p := getPlan()
+	p.DockerRegistry.SetupInternal = true
+	p.Cluster.Certificates.CACert = certFile
+	p.Cluster.Certificates.CAKey = keyFile
+	p.Cluster.Certificates.CAChain = rootFile
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("unexpected error using intermediate CA: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+	// The serving certificates are verified by clients that only trust the root
+	verifyCertAgainstRoot(t, filepath.Join(pki.GeneratedCertsDirectory, "worker.pem"), rootFile)
+	verifyCertAgainstRoot(t, filepath.Join(pki.GeneratedCertsDirectory, "docker.pem"), rootFile)
+
+	// The chain is used for the certificates of nodes added in later runs
+	ca, err = pki.GetClusterCA()
+	if err != nil {
+		t.Fatalf("unexpected error reading cluster CA: %v", err)
+	}
+	newNode := Node{Host: "worker2", IP: "10.0.0.10"}
+	if err = pki.GenerateNodeCertificate(p, newNode, ca); err != nil {
+		t.Fatalf("failed to generate node certificate: %v", err)
+	}
+	verifyCertAgainstRoot(t, filepath.Join(pki.GeneratedCertsDirectory, "worker2.pem"), rootFile)
+
+	// The existing certificates are still valid with the chain appended
+	if _, errs := pki.ValidateClusterCertificates(p, []string{"admin"}); len(errs) != 0 {
+		t.Errorf("unexpected errors validating certificates: %v", errs)
+	}
+}
+
+func TestGenerateClusterCAProvidedCAKeyMismatch(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+
+	providedDir := mustGetTempDir(t)
+	defer cleanup(providedDir, t)
+	_, cert, err := tls.NewCACert("test/ca-csr.json", "corporate", tls.Subject{})
+	if err != nil {
+		t.Fatalf("error creating CA for test: %v", err)
+	}
+	otherKey, _, err := tls.NewCACert("test/ca-csr.json", "other", tls.Subject{})
+	if err != nil {
+		t.Fatalf("error creating CA for test: %v", err)
+	}
+	if err = tls.WriteCert(otherKey, cert, "corporate-ca", providedDir); err != nil {
+		t.Fatalf("error writing CA for test: %v", err)
+	}
+	p := getPlan()
+	p.Cluster.Certificates.CACert = filepath.Join(providedDir, "corporate-ca.pem")
+	p.Cluster.Certificates.CAKey = filepath.Join(providedDir, "corporate-ca-key.pem")
+	if _, err = pki.GenerateClusterCA(p); err == nil {
+		t.Errorf("expected an error when the CA key does not match the certificate")
+	}
+	if exists, _ := pki.CertificateAuthorityExists(); exists {
+		t.Errorf("an invalid CA was written to the generated certs directory")
+	}
+}
+
+func TestValidateClusterCertificatesBadUserCert(t *testing.T) {
+	for _, user := range []string{"admin", "jane"} {
+		pki := getPKI(t)
+		defer cleanup(pki.GeneratedCertsDirectory, t)
+		p := getPlan()
+		p.Users = []User{{Name: "jane"}}
+		ca, err := pki.GenerateClusterCA(p)
+		if err != nil {
+			t.Fatalf("error generating CA for test: %v", err)
+		}
+		if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+			t.Fatalf("failed to generate certs: %v", err)
+		}
+		if _, errs := pki.ValidateClusterCertificates(p, []string{"admin"}); len(errs) != 0 {
+			t.Fatalf("unexpected errors validating certificates: %v", errs)
+		}
+		// A certificate that cannot be read must be reported
+		certFile := filepath.Join(pki.GeneratedCertsDirectory, user+".pem")
+		if err = os.Remove(certFile); err != nil {
+			t.Fatalf("error removing certificate: %v", err)
+		}
+		if err = os.Mkdir(certFile, 0700); err != nil {
+			t.Fatalf("error replacing certificate: %v", err)
+		}
+		if _, errs := pki.ValidateClusterCertificates(p, []string{"admin"}); len(errs) == 0 {
+			t.Errorf("expected an error when the certificate of user %q is invalid", user)
+		}
+	}
+}
+
+func TestRotateClusterCertificatesProvidedCA(t *testing.T) {
+	pki := getPKI(t)
+	defer cleanup(pki.GeneratedCertsDirectory, t)
+	pki.GeneratedCertsDirectory = filepath.Join(pki.GeneratedCertsDirectory, "keys")
+
+	providedDir := mustGetTempDir(t)
+	defer cleanup(providedDir, t)
+	for _, name := range []string{"corporate", "other"} {
+		key, cert, err := tls.NewCACert("test/ca-csr.json", name, tls.Subject{})
+		if err != nil {
+			t.Fatalf("error creating CA for test: %v", err)
+		}
+		if err = tls.WriteCert(key, cert, name+"-ca", providedDir); err != nil {
+			t.Fatalf("error writing CA for test: %v", err)
+		}
+	}
+	p := getPlan()
+	p.Cluster.Certificates.CACert = filepath.Join(providedDir, "corporate-ca.pem")
+	p.Cluster.Certificates.CAKey = filepath.Join(providedDir, "corporate-ca-key.pem")
+	ca, err := pki.GenerateClusterCA(p)
+	if err != nil {
+		t.Fatalf("error generating CA for test: %v", err)
+	}
+	if err = pki.GenerateClusterCertificates(p, ca, []string{"admin"}); err != nil {
+		t.Fatalf("failed to generate certs: %v", err)
+	}
+
+	// The CA of the plan is the CA of the cluster
+	if _, err = pki.RotateClusterCertificates(p, []string{"admin"}, true); err == nil {
+		t.Errorf("expected an error when rotating the CA to the CA of the cluster")
+	}
+	if exists, _ := pki.CertificateAuthorityExists(); !exists {
+		t.Fatalf("the CA was removed when the rotation was rejected")
+	}
+
+	// A new CA is provided in the plan
+	p.Cluster.Certificates.CACert = filepath.Join(providedDir, "other-ca.pem")
+	p.Cluster.Certificates.CAKey = filepath.Join(providedDir, "other-ca-key.pem")
+	if _, err = pki.RotateClusterCertificates(p, []string{"admin"}, true); err != nil {
+		t.Fatalf("unexpected error rotating to the new CA: %v", err)
+	}
+	newCA := mustReadCertFile(filepath.Join(pki.GeneratedCertsDirectory, "ca.pem"), t)
+	if newCA.Subject.CommonName != "other" {
+		t.Errorf("expected the new CA to be used, got CA with CN %q", newCA.Subject.CommonName)
+	}
+}
@@ -143,11 +143,27 @@ func WritePlanTemplate(p *Plan, w PlanReadWriter) error {
 
 	// Set Certificate defaults
 	p.Cluster.Certificates.Expiry = "17520h"
+	p.Cluster.Certificates.Subject = CertSubject{
+		Organization:       certOrganization,
+		OrganizationalUnit: certOrgUnit,
+		Country:            certCountry,
+		State:              certState,
+		Locality:           certLocality,
+	}
 	p.DockerRegistry.SetupInternal = false
 
 	// Set DockerRegistry defaults
 	p.DockerRegistry.Port = 8443
 
+	// Set Logging defaults
+	p.Logging.Backend = "nfs"
+	p.Logging.StorageSizeGB = 10
+	p.Logging.RetentionDays = 7
+
+	// Set Monitoring defaults
+	p.Monitoring.Backend = "nfs"
+	p.Monitoring.StorageSizeGB = 10
+
 	// Generate entries for all node types
 	n := Node{}
 	for i := 0; i < p.Etcd.ExpectedCount; i++ {
@@ -231,6 +247,10 @@ var commentMap = map[string]string{
 	"policy_enabled":           "When true, enables network policy enforcement on the Kubernetes Pod network. This is an advanced feature.",
 	"update_hosts_files":       "When true, the installer will add entries for all nodes to other nodes' hosts files. Use when you don't have access to DNS.",
 	"expiry":                   "Self-signed certificate expiration period in hours; default is 2 years.",
+	"ca_cert":                  "Absolute path to an existing CA certificate. Leave blank to generate a self-signed CA.",
+	"ca_key":                   "Absolute path to the private key of the CA certificate. Required when ca_cert is provided.",
+	"ca_chain":                 "If ca_cert is an intermediate CA, absolute path to its certificate chain up to and including the root CA.",
+	"subject":                  "The subject of the generated CA and certificates.",
 	"ssh_key":                  "Absolute path to the ssh private key we should use to manage nodes.",
 	"etcd":                     "Here you will identify all of the nodes that should play the etcd role on your cluster.",
 	"master":                   "Here you will identify all of the nodes that should play the master role.",
@@ -248,4 +268,12 @@ var commentMap = map[string]string{
 	"nfs":                      "A set of NFS volumes for use by on-cluster persistent workloads, managed by Kismatic.",
 	"nfs_host":                 "The host name or ip address of an NFS server.",
 	"mount_path":               "The mount path of an NFS share. Must start with /",
+	"logging":                  "The logging add-on collects the logs of the cluster with Fluentd, stores them in Elasticsearch and serves Kibana.",
+	"backend":                  "Storage for the add-on data: nfs to use an NFS share, or glusterfs to create a volume on the storage nodes.",
+	"nfs_server":               "The host name or ip address of the NFS server. Required when the backend is nfs.",
+	"nfs_path":                 "The path of the NFS share. Required when the backend is nfs. Must start with /",
+	"storage_size_gb":          "The size of the storage volume, in gigabytes.",
+	"retention_days":           "Logs older than this number of days are deleted.",
+	"monitoring":               "The monitoring add-on deploys Prometheus, configured to scrape the apiserver, etcd, kubelet and calico.",
+	"linkerd":                  "The linkerd add-on runs the linkerd service mesh on every node.",
 }
@@ -8,3 +8,21 @@ func TestGenerateAlphaNumericPassword(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestDockerRegistryAddress(t *testing.T) {
+	tests := []struct {
+		registry DockerRegistry
+		expected string
+	}{
+		{registry: DockerRegistry{}, expected: ""},
+		{registry: DockerRegistry{Address: "registry.example.com", Port: 5000}, expected: "registry.example.com:5000"},
+		{registry: DockerRegistry{SetupInternal: true}, expected: "192.168.0.2:8443"},
+	}
+	for _, test := range tests {
+		p := &Plan{DockerRegistry: test.registry}
+		p.Master.Nodes = []Node{{Host: "master01", IP: "10.0.0.2", InternalIP: "192.168.0.2"}}
+		if a := p.DockerRegistryAddress(); a != test.expected {
+			t.Errorf("expected address %q for registry %+v, got %q", test.expected, test.registry, a)
+		}
+	}
+}
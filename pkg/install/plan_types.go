@@ -18,6 +18,23 @@ type NetworkConfig struct {
 // CertsConfig describes the cluster's trust and certificate configuration
 type CertsConfig struct {
 	Expiry string
+	// CACert and CAKey are the paths to an existing Certificate Authority that
+	// is used instead of generating a self-signed one
+	CACert string `yaml:"ca_cert"`
+	CAKey  string `yaml:"ca_key"`
+	// CAChain is the path to the chain of an intermediate Certificate Authority,
+	// up to and including the root
+	CAChain string `yaml:"ca_chain"`
+	Subject CertSubject
+}
+
+// CertSubject describes the subject of the certificates generated for the cluster
+type CertSubject struct {
+	Organization       string
+	OrganizationalUnit string `yaml:"organizational_unit"`
+	Country            string
+	State              string
+	Locality           string
 }
 
 // SSHConfig describes the cluster's SSH configuration for accessing nodes
@@ -63,6 +80,61 @@ type NFSVolume struct {
 	Path string `yaml:"mount_path"`
 }
 
+// AddOnStorage describes the volume used by an add-on to store its data
+type AddOnStorage struct {
+	// Backend is either "nfs" to use an NFS share, or "glusterfs" to create
+	// a volume on the storage nodes
+	Backend string
+	// NFSServer and NFSPath are the NFS share used when the backend is "nfs"
+	NFSServer string `yaml:"nfs_server"`
+	NFSPath   string `yaml:"nfs_path"`
+	// StorageSizeGB is the size of the volume, in gigabytes
+	StorageSizeGB int `yaml:"storage_size_gb"`
+}
+
+// Logging describes the logging add-on, made up of Elasticsearch, Fluentd and Kibana
+type Logging struct {
+	Enabled      bool
+	AddOnStorage `yaml:",inline"`
+	// RetentionDays is the number of days the logs are kept
+	RetentionDays int `yaml:"retention_days"`
+}
+
+// Monitoring describes the monitoring add-on, made up of Prometheus
+type Monitoring struct {
+	Enabled      bool
+	AddOnStorage `yaml:",inline"`
+}
+
+// Linkerd describes the linkerd service mesh add-on
+type Linkerd struct {
+	Enabled bool
+}
+
+// User is a person or service that accesses the cluster with a client
+// certificate signed by the cluster CA
+type User struct {
+	Name string
+	// Groups the user belongs to, recorded in the Organization of its certificate
+	Groups []string
+	// Access is granted to the user in addition to the access of its groups
+	Access []Access
+}
+
+// Group grants access to the users that belong to it
+type Group struct {
+	Name   string
+	Access []Access
+}
+
+// Access grants permissions on the resources of a namespace
+type Access struct {
+	// Namespace of the resources. "*" grants access to all namespaces and
+	// to the resources that are not namespaced.
+	Namespace string
+	ReadOnly  bool `yaml:"read_only"`
+}
+
 // MasterNodeGroup is the collection of master nodes
 type MasterNodeGroup struct {
 	ExpectedCount         int    `yaml:"expected_count"`
@@ -89,6 +161,11 @@ type Plan struct {
 	Ingress        OptionalNodeGroup
 	Storage        OptionalNodeGroup
 	NFS            NFS
+	Logging        Logging
+	Monitoring     Monitoring
+	Linkerd        Linkerd
+	Users          []User  `yaml:"users,omitempty"`
+	Groups         []Group `yaml:"groups,omitempty"`
 }
 
 // StorageVolume managed by Kismatic
@@ -144,6 +221,56 @@ func (p *Plan) getAllNodes() []Node {
 	return nodes
 }
 
+// GetUniqueNodes returns all nodes in the plan, without duplicates
+func (p *Plan) GetUniqueNodes() []Node {
+	nodes := []Node{}
+	seen := map[string]bool{}
+	for _, n := range p.getAllNodes() {
+		if seen[n.Host] {
+			continue
+		}
+		seen[n.Host] = true
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// GetNodeRoles returns the roles of the node with the given hostname
+func (p *Plan) GetNodeRoles(host string) []string {
+	roles := []string{}
+	groups := []struct {
+		role  string
+		nodes []Node
+	}{
+		{"etcd", p.Etcd.Nodes},
+		{"master", p.Master.Nodes},
+		{"worker", p.Worker.Nodes},
+		{"ingress", p.Ingress.Nodes},
+		{"storage", p.Storage.Nodes},
+	}
+	for _, g := range groups {
+		for _, n := range g.nodes {
+			if n.Host == host {
+				roles = append(roles, g.role)
+				break
+			}
+		}
+	}
+	return roles
+}
+
+// returns whether any node of the plan has the role
+func (p *Plan) hasRole(role string) bool {
+	for _, n := range p.GetUniqueNodes() {
+		for _, r := range p.GetNodeRoles(n.Host) {
+			if r == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GetSSHConnection returns the SSHConnection struct containing the node and SSHConfig details
 func (p *Plan) GetSSHConnection(host string) (*SSHConnection, error) {
 	nodes := p.getAllNodes()
@@ -198,6 +325,24 @@ func (p *Plan) DockerRegistryProvided() bool {
 	return p.DockerRegistry.SetupInternal || p.DockerRegistry.Address != ""
 }
 
+// DockerRegistryAddress returns the address and port of the registry, or an
+// empty string if a local registry is not provided. The internal registry
+// runs on the first master node.
+func (p *Plan) DockerRegistryAddress() string {
+	if p.DockerRegistry.Address != "" {
+		return fmt.Sprintf("%s:%d", p.DockerRegistry.Address, p.DockerRegistry.Port)
+	}
+	if !p.DockerRegistry.SetupInternal || len(p.Master.Nodes) == 0 {
+		return ""
+	}
+	master := p.Master.Nodes[0]
+	ip := master.IP
+	if master.InternalIP != "" {
+		ip = master.InternalIP
+	}
+	return fmt.Sprintf("%s:%d", ip, internalDockerRegistryPort)
+}
+
 func firstIfItExists(nodes []Node) *Node {
 	if len(nodes) > 0 {
 		return &nodes[0]
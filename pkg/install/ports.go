@@ -0,0 +1,22 @@
+package install
+
+// The ports used by the components of the cluster. They are given to ansible
+// in the cluster catalog, so that the components are configured with the
+// ports that are checked during preflight.
+const (
+	etcdK8sClientPort        = 2379
+	etcdK8sPeerPort          = 2380
+	etcdNetworkingClientPort = 6666
+	etcdNetworkingPeerPort   = 6660
+	apiServerPort            = 6443
+	calicoFelixMetricsPort   = 9091
+	// the port of the docker registry that is set up on the first master
+	internalDockerRegistryPort = 8443
+)
+
+// The ports of the components that kismatic does not configure, which are
+// the defaults of the components
+const (
+	kubeletPort   = 10250
+	calicoBGPPort = 179
+)
@@ -0,0 +1,213 @@
+package install
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// the file in a run directory that records the progress of the plays of the playbook
+const playProgressFile = "plays.yaml"
+
+// the playbook that is generated to resume a failed installation
+const resumePlaybook = "kubernetes-resume.yaml"
+
+// PlayStatus is the progress of a play of a playbook run
+type PlayStatus struct {
+	Name      string `yaml:"name"`
+	Completed bool   `yaml:"completed"`
+	Failed    bool   `yaml:"failed,omitempty"`
+}
+
+// records the progress of the plays in the run directory, as the events of the playbook are received
+type playProgress struct {
+	file  string
+	Plays []PlayStatus `yaml:"plays"`
+}
+
+// record returns a stream with the events of the incoming stream, and
+// records the progress of the plays as the events go through
+func (pp *playProgress) record(in <-chan ansible.Event) <-chan ansible.Event {
+	out := make(chan ansible.Event)
+	go func() {
+		defer close(out)
+		for e := range in {
+			if pp.update(e) {
+				// Failing to record the progress only prevents resuming
+				// the run, so it does not stop the playbook
+				pp.write()
+			}
+			out <- e
+		}
+	}()
+	return out
+}
+
+// updates the progress with the event, and returns true if it changed.
+// A play is completed when the next play starts or the playbook ends,
+// as long as it did not fail.
+func (pp *playProgress) update(e ansible.Event) bool {
+	switch event := e.(type) {
+	case *ansible.PlayStartEvent:
+		pp.completeCurrentPlay()
+		pp.Plays = append(pp.Plays, PlayStatus{Name: event.Name})
+		return true
+	case *ansible.PlaybookEndEvent:
+		return pp.completeCurrentPlay()
+	case *ansible.RunnerFailedEvent:
+		if event.IgnoreErrors {
+			return false
+		}
+		return pp.failCurrentPlay()
+	case *ansible.RunnerUnreachableEvent:
+		return pp.failCurrentPlay()
+	}
+	return false
+}
+
+func (pp *playProgress) completeCurrentPlay() bool {
+	if len(pp.Plays) == 0 {
+		return false
+	}
+	current := &pp.Plays[len(pp.Plays)-1]
+	if current.Completed || current.Failed {
+		return false
+	}
+	current.Completed = true
+	return true
+}
+
+func (pp *playProgress) failCurrentPlay() bool {
+	if len(pp.Plays) == 0 {
+		return false
+	}
+	current := &pp.Plays[len(pp.Plays)-1]
+	if current.Failed {
+		return false
+	}
+	current.Failed = true
+	return true
+}
+
+func (pp *playProgress) write() error {
+	b, err := yaml.Marshal(pp)
+	if err != nil {
+		return fmt.Errorf("error marshalling play progress: %v", err)
+	}
+	if err := ioutil.WriteFile(pp.file, b, 0644); err != nil {
+		return fmt.Errorf("error writing play progress to %q: %v", pp.file, err)
+	}
+	return nil
+}
+
+// returns the progress of the plays recorded in the run directory.
+// No plays are returned if the run did not record any progress.
+func readPlayProgress(runDirectory string) ([]PlayStatus, error) {
+	b, err := ioutil.ReadFile(filepath.Join(runDirectory, playProgressFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading play progress: %v", err)
+	}
+	pp := &playProgress{}
+	if err := yaml.Unmarshal(b, pp); err != nil {
+		return nil, fmt.Errorf("error reading play progress: %v", err)
+	}
+	return pp.Plays, nil
+}
+
+// an entry of a playbook that includes other playbooks
+type playbookInclude struct {
+	Include string `yaml:"include"`
+	When    string `yaml:"when,omitempty"`
+}
+
+// the plays of the playbook, with the index of the include they belong to
+type includedPlay struct {
+	name    string
+	include int
+}
+
+// reads the includes of the playbook, and the plays of every included playbook in order
+func readPlaybookIncludes(playbooksDir string, playbook string) ([]playbookInclude, []includedPlay, error) {
+	b, err := ioutil.ReadFile(filepath.Join(playbooksDir, playbook))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading playbook %q: %v", playbook, err)
+	}
+	var includes []playbookInclude
+	if err := yaml.Unmarshal(b, &includes); err != nil {
+		return nil, nil, fmt.Errorf("error reading playbook %q: %v", playbook, err)
+	}
+	var plays []includedPlay
+	for i, inc := range includes {
+		b, err := ioutil.ReadFile(filepath.Join(playbooksDir, inc.Include))
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading playbook %q: %v", inc.Include, err)
+		}
+		var included []struct {
+			Name string `yaml:"name"`
+		}
+		if err := yaml.Unmarshal(b, &included); err != nil {
+			return nil, nil, fmt.Errorf("error reading playbook %q: %v", inc.Include, err)
+		}
+		for _, p := range included {
+			plays = append(plays, includedPlay{name: strings.TrimSpace(p.Name), include: i})
+		}
+	}
+	return includes, plays, nil
+}
+
+// resumeIncludes returns the includes of the playbook that must run to
+// continue from the first play that did not complete, and the progress of
+// the plays that are skipped. Ansible starts every play of the playbook in
+// order, even the ones that do not apply to the cluster, so the recorded
+// plays match the plays of the included playbooks one to one. The included
+// playbook that contains the first incomplete play is run again in full.
+func resumeIncludes(playbooksDir string, playbook string, progress []PlayStatus) ([]playbookInclude, []PlayStatus, error) {
+	includes, plays, err := readPlaybookIncludes(playbooksDir, playbook)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(progress) > len(plays) {
+		return nil, nil, fmt.Errorf("the recorded plays do not match the plays of playbook %q", playbook)
+	}
+	first := 0
+	for first < len(progress) && progress[first].Completed {
+		if strings.TrimSpace(progress[first].Name) != plays[first].name {
+			return nil, nil, fmt.Errorf("the recorded play %q does not match play %q of playbook %q", progress[first].Name, plays[first].name, playbook)
+		}
+		first++
+	}
+	if first == len(plays) {
+		return nil, progress, nil
+	}
+	resumeAt := plays[first].include
+	var skipped []PlayStatus
+	for i := 0; i < first && plays[i].include < resumeAt; i++ {
+		skipped = append(skipped, progress[i])
+	}
+	return includes[resumeAt:], skipped, nil
+}
+
+// writes a playbook with the includes to the playbooks directory
+func writeIncludesPlaybook(playbooksDir string, playbook string, includes []playbookInclude) error {
+	b, err := yaml.Marshal(includes)
+	if err != nil {
+		return fmt.Errorf("error marshalling playbook %q: %v", playbook, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(playbooksDir, playbook), append([]byte("---\n"), b...), 0644); err != nil {
+		return fmt.Errorf("error writing playbook %q: %v", playbook, err)
+	}
+	return nil
+}
+
+// lastInstallRun returns the most recent installation run directory
+func lastInstallRun(runsDirectory string) (string, error) {
+	return latestRunDirectory(filepath.Join(runsDirectory, "install", "*"))
+}
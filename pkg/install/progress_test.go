@@ -0,0 +1,276 @@
+package install
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+)
+
+func playStart(name string) *ansible.PlayStartEvent {
+	e := &ansible.PlayStartEvent{}
+	e.Name = name
+	return e
+}
+
+func TestPlayProgressRecord(t *testing.T) {
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+	ignored := &ansible.RunnerFailedEvent{}
+	ignored.IgnoreErrors = true
+	events := []ansible.Event{
+		&ansible.PlaybookStartEvent{},
+		playStart("Configure Cluster Prerequisites"),
+		ignored,
+		playStart("Install Kubernetes Etcd Cluster"),
+		&ansible.RunnerFailedEvent{},
+		&ansible.PlaybookEndEvent{},
+	}
+	in := make(chan ansible.Event, len(events))
+	for _, e := range events {
+		in <- e
+	}
+	close(in)
+	pp := &playProgress{file: filepath.Join(dir, playProgressFile)}
+	var received int
+	for range pp.record(in) {
+		received++
+	}
+	if received != len(events) {
+		t.Errorf("expected %d events to go through, got %d", len(events), received)
+	}
+	plays, err := readPlayProgress(dir)
+	if err != nil {
+		t.Fatalf("unexpected error reading progress: %v", err)
+	}
+	expected := []PlayStatus{
+		{Name: "Configure Cluster Prerequisites", Completed: true},
+		{Name: "Install Kubernetes Etcd Cluster", Failed: true},
+	}
+	if !reflect.DeepEqual(plays, expected) {
+		t.Errorf("expected plays %+v, got %+v", expected, plays)
+	}
+}
+
+func TestReadPlayProgressNotRecorded(t *testing.T) {
+	dir := mustGetTempDir(t)
+	defer os.RemoveAll(dir)
+	plays, err := readPlayProgress(dir)
+	if err != nil || plays != nil {
+		t.Errorf("expected no plays and no error, got %v and %v", plays, err)
+	}
+}
+
+// writes a playbook that includes three playbooks, the second one with two plays
+func resumeTestPlaybooks(t *testing.T) string {
+	dir := mustGetTempDir(t)
+	files := map[string]string{
+		"kubernetes.yaml": `---
+  - include: _all.yaml
+  - include: _storage.yaml
+    when: configure_storage|bool == true
+  - include: _addon-kubernetes-dashboard.yaml
+`,
+		"_all.yaml": `---
+  - hosts: all
+    name: "Configure Cluster Prerequisites"
+`,
+		"_storage.yaml": `---
+  - hosts: storage
+    name: "Bootstrap Persistent Storage Cluster"
+  - hosts: master[0]
+    name: Create NFS service on the cluster
+`,
+		"_addon-kubernetes-dashboard.yaml": `---
+  - hosts: master[0]
+    name: "Configure Kubernetes Dashboard"
+`,
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("error writing playbook: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestResumeIncludes(t *testing.T) {
+	dir := resumeTestPlaybooks(t)
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		progress         []PlayStatus
+		expectedIncludes []string
+		expectedSkipped  int
+	}{
+		{
+			progress:         nil,
+			expectedIncludes: []string{"_all.yaml", "_storage.yaml", "_addon-kubernetes-dashboard.yaml"},
+		},
+		{
+			progress: []PlayStatus{
+				{Name: "Configure Cluster Prerequisites", Completed: true},
+				{Name: "Bootstrap Persistent Storage Cluster", Completed: true},
+				{Name: "Create NFS service on the cluster", Failed: true},
+			},
+			// the included playbook with the failed play is run in full
+			expectedIncludes: []string{"_storage.yaml", "_addon-kubernetes-dashboard.yaml"},
+			expectedSkipped:  1,
+		},
+		{
+			progress: []PlayStatus{
+				{Name: "Configure Cluster Prerequisites", Completed: true},
+				{Name: "Bootstrap Persistent Storage Cluster", Completed: true},
+				{Name: "Create NFS service on the cluster", Completed: true},
+				{Name: "Configure Kubernetes Dashboard"},
+			},
+			expectedIncludes: []string{"_addon-kubernetes-dashboard.yaml"},
+			expectedSkipped:  3,
+		},
+	}
+	for i, test := range tests {
+		includes, skipped, err := resumeIncludes(dir, "kubernetes.yaml", test.progress)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+			continue
+		}
+		var names []string
+		for _, inc := range includes {
+			names = append(names, inc.Include)
+		}
+		if !reflect.DeepEqual(names, test.expectedIncludes) {
+			t.Errorf("test %d: expected includes %v, got %v", i, test.expectedIncludes, names)
+		}
+		if len(skipped) != test.expectedSkipped {
+			t.Errorf("test %d: expected %d skipped plays, got %v", i, test.expectedSkipped, skipped)
+		}
+	}
+	if includes, _, _ := resumeIncludes(dir, "kubernetes.yaml", nil); includes[1].When != "configure_storage|bool == true" {
+		t.Errorf("expected the condition of the include to be kept, got %+v", includes[1])
+	}
+}
+
+func TestResumeIncludesPlaysDoNotMatch(t *testing.T) {
+	dir := resumeTestPlaybooks(t)
+	defer os.RemoveAll(dir)
+	progress := []PlayStatus{
+		{Name: "Install Docker", Completed: true},
+		{Name: "Bootstrap Persistent Storage Cluster"},
+	}
+	if _, _, err := resumeIncludes(dir, "kubernetes.yaml", progress); err == nil {
+		t.Errorf("expected an error when the recorded plays do not match the playbook")
+	}
+}
+
+// returns an executor that runs the playbooks in the directory with a fake runner,
+// and the directory with a failed installation of the plan
+func resumeTestExecutor(t *testing.T, p *Plan, progress []PlayStatus) (*ansibleExecutor, *fakeRunner, string) {
+	ansibleDir := mustGetTempDir(t)
+	if err := os.Rename(resumeTestPlaybooks(t), filepath.Join(ansibleDir, "playbooks")); err != nil {
+		t.Fatalf("error moving playbooks: %v", err)
+	}
+	runner := &fakeRunner{}
+	e := &ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: filepath.Join(ansibleDir, "runs")},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		ansibleDir:          ansibleDir,
+		certsDir:            mustGetTempDir(t),
+		pki:                 &fakePKI{caExists: true},
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return runner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+	}
+	failedRun := filepath.Join(e.options.RunsDirectory, "install", "2017-01-27-10-00-00")
+	if err := os.MkdirAll(failedRun, 0777); err != nil {
+		t.Fatalf("error creating run directory: %v", err)
+	}
+	fp := FilePlanner{File: filepath.Join(failedRun, "kismatic-cluster.yaml")}
+	if err := fp.Write(p); err != nil {
+		t.Fatalf("error writing plan file: %v", err)
+	}
+	pp := &playProgress{file: filepath.Join(failedRun, playProgressFile), Plays: progress}
+	if err := pp.write(); err != nil {
+		t.Fatalf("error writing play progress: %v", err)
+	}
+	return e, runner, failedRun
+}
+
+func TestResumeInstall(t *testing.T) {
+	p := diffTestPlan()
+	progress := []PlayStatus{
+		{Name: "Configure Cluster Prerequisites", Completed: true},
+		{Name: "Bootstrap Persistent Storage Cluster", Failed: true},
+	}
+	e, runner, _ := resumeTestExecutor(t, &p, progress)
+	defer os.RemoveAll(e.ansibleDir)
+	// the resume playbook is read when the playbook is run, as it is removed afterwards
+	resumePlaybookFile := filepath.Join(e.ansibleDir, "playbooks", resumePlaybook)
+	var b []byte
+	e.runnerExplainerFactory = func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+		var err error
+		if b, err = ioutil.ReadFile(resumePlaybookFile); err != nil {
+			t.Fatalf("error reading resume playbook: %v", err)
+		}
+		return runner, &explain.AnsibleEventStreamExplainer{}, nil
+	}
+	if err := e.ResumeInstall(&p); err != nil {
+		t.Fatalf("unexpected error resuming the installation: %v", err)
+	}
+	if !reflect.DeepEqual(runner.allNodesPlaybooks, []string{resumePlaybook}) {
+		t.Fatalf("expected the resume playbook to run, got %v", runner.allNodesPlaybooks)
+	}
+	if _, err := os.Stat(resumePlaybookFile); !os.IsNotExist(err) {
+		t.Errorf("expected the resume playbook to be removed after the run")
+	}
+	if strings.Contains(string(b), "_all.yaml") || !strings.Contains(string(b), "_storage.yaml") || !strings.Contains(string(b), "_addon-kubernetes-dashboard.yaml") {
+		t.Errorf("unexpected resume playbook:\n%s", b)
+	}
+	// the resumed run records the skipped plays, so that it can be resumed too
+	resumedRun, err := lastInstallRun(e.options.RunsDirectory)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plays, err := readPlayProgress(resumedRun)
+	if err != nil {
+		t.Fatalf("unexpected error reading progress: %v", err)
+	}
+	if !reflect.DeepEqual(plays, progress[:1]) {
+		t.Errorf("expected the skipped plays to be recorded in the resumed run, got %+v", plays)
+	}
+	if _, err := os.Stat(filepath.Join(resumedRun, successfulRunMarker)); err != nil {
+		t.Errorf("expected the resumed run to be recorded as successful")
+	}
+}
+
+func TestResumeInstallPlanChanged(t *testing.T) {
+	p := diffTestPlan()
+	e, runner, _ := resumeTestExecutor(t, &p, []PlayStatus{{Name: "Configure Cluster Prerequisites", Failed: true}})
+	defer os.RemoveAll(e.ansibleDir)
+	changed := diffTestPlan()
+	changed.Worker.Nodes = append(changed.Worker.Nodes, Node{Host: "worker02", IP: "10.0.0.4"})
+	err := e.ResumeInstall(&changed)
+	if err == nil || !strings.Contains(err.Error(), "plan file changed") {
+		t.Errorf("expected an error when the plan changed, got %v", err)
+	}
+	if len(runner.allNodesPlaybooks) != 0 {
+		t.Errorf("expected no playbooks to run, got %v", runner.allNodesPlaybooks)
+	}
+}
+
+func TestResumeInstallLastRunSucceeded(t *testing.T) {
+	p := diffTestPlan()
+	e, _, failedRun := resumeTestExecutor(t, &p, nil)
+	defer os.RemoveAll(e.ansibleDir)
+	if err := recordSuccessfulRun(failedRun); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := e.ResumeInstall(&p); err == nil {
+		t.Errorf("expected an error when the last installation succeeded")
+	}
+}
@@ -0,0 +1,107 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// RemoveNode removes the worker, ingress or storage node with the given
+// hostname from the original cluster described in the plan.
+// If successful, the updated plan is returned.
+func (ae *ansibleExecutor) RemoveNode(originalPlan *Plan, host string) (*Plan, error) {
+	if err := checkRemoveNodePrereqs(*originalPlan, host); err != nil {
+		return nil, err
+	}
+	runDirectory, err := ae.createRunDirectory("remove-node")
+	if err != nil {
+		return nil, fmt.Errorf("error creating working directory for remove-node: %v", err)
+	}
+	updatedPlan := removeNodeFromPlan(*originalPlan, host)
+	fp := FilePlanner{
+		File: filepath.Join(runDirectory, "kismatic-cluster.yaml"),
+	}
+	if err = fp.Write(&updatedPlan); err != nil {
+		return nil, fmt.Errorf("error recording plan file to %s: %v", fp.File, err)
+	}
+	// The node must be in the inventory for ansible to reach it
+	inventory := buildInventoryFromPlan(originalPlan)
+	cc, err := ae.buildInstallExtraVars(originalPlan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ansible vars: %v", err)
+	}
+	cc.WorkerNode = host
+	ansibleLogFilename := filepath.Join(runDirectory, "ansible.log")
+	ansibleLogFile, err := os.Create(ansibleLogFilename)
+	if err != nil {
+		return nil, fmt.Errorf("error creating ansible log file %q: %v", ansibleLogFilename, err)
+	}
+	// Drain the node and stop the cluster services
+	util.PrintHeader(ae.stdout, "Removing Node From Cluster", '=')
+	eventExplainer := &explain.DefaultEventExplainer{}
+	if err = ae.runPlaybookOnNodeWithExplainer("kubernetes-remove-node.yaml", eventExplainer, inventory, *cc, host, ansibleLogFile, runDirectory); err != nil {
+		return nil, err
+	}
+	// Revoke access to any storage volumes defined
+	if len(updatedPlan.Storage.Nodes) > 0 {
+		util.PrintHeader(ae.stdout, "Updating Allowed IPs On Storage Volumes", '=')
+		eventExplainer = &explain.DefaultEventExplainer{}
+		if err = ae.runPlaybookWithExplainer("_volume-remove-allowed.yaml", eventExplainer, inventory, *cc, ansibleLogFile, runDirectory); err != nil {
+			return nil, fmt.Errorf("error removing node from volume allow list: %v", err)
+		}
+	}
+	if updatedPlan.Cluster.Networking.UpdateHostsFiles {
+		// The hosts files are rebuilt from the updated inventory
+		util.PrintHeader(ae.stdout, "Updating Hosts Files On All Nodes", '=')
+		updatedInventory := buildInventoryFromPlan(&updatedPlan)
+		eventExplainer = &explain.DefaultEventExplainer{}
+		if err = ae.runPlaybookWithExplainer("_hosts.yaml", eventExplainer, updatedInventory, *cc, ansibleLogFile, runDirectory); err != nil {
+			return nil, fmt.Errorf("error updating hosts files on all nodes: %v", err)
+		}
+	}
+	if err = recordSuccessfulRun(runDirectory); err != nil {
+		return nil, err
+	}
+	return &updatedPlan, nil
+}
+
+func removeNodeFromPlan(plan Plan, host string) Plan {
+	plan.Worker = NodeGroup(removeNodeFromGroup(OptionalNodeGroup(plan.Worker), host))
+	plan.Ingress = removeNodeFromGroup(plan.Ingress, host)
+	plan.Storage = removeNodeFromGroup(plan.Storage, host)
+	return plan
+}
+
+func removeNodeFromGroup(group OptionalNodeGroup, host string) OptionalNodeGroup {
+	nodes := []Node{}
+	for _, n := range group.Nodes {
+		if n.Host == host {
+			group.ExpectedCount--
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	group.Nodes = nodes
+	return group
+}
+
+// ensure the node can be safely removed from the cluster
+func checkRemoveNodePrereqs(plan Plan, host string) error {
+	roles := plan.GetNodeRoles(host)
+	if len(roles) == 0 {
+		return fmt.Errorf("node %q was not found in the plan file", host)
+	}
+	for _, r := range roles {
+		if r == "etcd" || r == "master" {
+			return fmt.Errorf("node %q has the %s role. Only worker, ingress and storage nodes can be removed", host, r)
+		}
+	}
+	updatedPlan := removeNodeFromPlan(plan, host)
+	if len(updatedPlan.Worker.Nodes) == 0 {
+		return fmt.Errorf("node %q is the last worker node in the cluster and cannot be removed", host)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package install
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+)
+
+var removeNodePlan = Plan{
+	Cluster: Cluster{
+		Networking: NetworkConfig{
+			ServiceCIDRBlock: "10.0.0.0/16",
+		},
+	},
+	Etcd: NodeGroup{
+		ExpectedCount: 1,
+		Nodes:         []Node{{Host: "etcd01"}},
+	},
+	Master: MasterNodeGroup{
+		ExpectedCount: 1,
+		Nodes:         []Node{{Host: "master01", InternalIP: "10.10.2.20"}},
+	},
+	Worker: NodeGroup{
+		ExpectedCount: 2,
+		Nodes:         []Node{{Host: "worker01"}, {Host: "worker02"}},
+	},
+	Ingress: OptionalNodeGroup{
+		ExpectedCount: 1,
+		Nodes:         []Node{{Host: "worker01"}},
+	},
+}
+
+func TestRemoveNodePlanIsUpdated(t *testing.T) {
+	fakeRunner := fakeRunner{}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		certsDir: mustGetTempDir(t),
+	}
+	originalPlan := removeNodePlan
+	updatedPlan, err := e.RemoveNode(&originalPlan, "worker01")
+	if err != nil {
+		t.Fatalf("unexpected error while removing node: %v", err)
+	}
+	if updatedPlan.Worker.ExpectedCount != 1 || len(updatedPlan.Worker.Nodes) != 1 || updatedPlan.Worker.Nodes[0].Host != "worker02" {
+		t.Errorf("node was not removed from the worker group: %+v", updatedPlan.Worker)
+	}
+	if updatedPlan.Ingress.ExpectedCount != 0 || len(updatedPlan.Ingress.Nodes) != 0 {
+		t.Errorf("node was not removed from the ingress group: %+v", updatedPlan.Ingress)
+	}
+	if len(originalPlan.Worker.Nodes) != 2 {
+		t.Errorf("the original plan was modified")
+	}
+	if len(fakeRunner.nodePlaybooks) != 1 || fakeRunner.nodePlaybooks[0] != "worker01:kubernetes-remove-node.yaml" {
+		t.Errorf("expected remove node playbook to run against the node, but got %v", fakeRunner.nodePlaybooks)
+	}
+}
+
+func TestRemoveNodePlanNotUpdatedAfterFailure(t *testing.T) {
+	e := ansibleExecutor{
+		options:                ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:                 ioutil.Discard,
+		consoleOutputFormat:    ansible.RawFormat,
+		runnerExplainerFactory: fakeRunnerExplainer(errors.New("exec error")),
+		certsDir:               mustGetTempDir(t),
+	}
+	originalPlan := removeNodePlan
+	updatedPlan, err := e.RemoveNode(&originalPlan, "worker01")
+	if err == nil {
+		t.Errorf("expected an error, but didn't get one")
+	}
+	if updatedPlan != nil {
+		t.Error("plan was updated, even though removing node failed")
+	}
+}
+
+func TestCheckRemoveNodePrereqs(t *testing.T) {
+	tests := []struct {
+		host  string
+		valid bool
+	}{
+		{host: "worker01", valid: true},
+		{host: "worker02", valid: true},
+		{host: "master01", valid: false},
+		{host: "etcd01", valid: false},
+		{host: "notFound", valid: false},
+	}
+	for _, test := range tests {
+		err := checkRemoveNodePrereqs(removeNodePlan, test.host)
+		if test.valid && err != nil {
+			t.Errorf("expected node %q to be removable, but got error: %v", test.host, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("expected an error when removing node %q, but didn't get one", test.host)
+		}
+	}
+}
+
+func TestCheckRemoveNodePrereqsLastWorker(t *testing.T) {
+	p := removeNodePlan
+	p.Worker = NodeGroup{
+		ExpectedCount: 1,
+		Nodes:         []Node{{Host: "worker01"}},
+	}
+	if err := checkRemoveNodePrereqs(p, "worker01"); err == nil {
+		t.Errorf("expected an error when removing the last worker, but didn't get one")
+	}
+}
@@ -0,0 +1,76 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// RotateCertificates regenerates the certificates of the cluster and deploys
+// them to the nodes. The components are restarted one node at a time, unless
+// the CA is replaced. Nodes cannot trust each other until all of them have
+// certificates signed by the new CA, so they are all restarted at once.
+func (ae *ansibleExecutor) RotateCertificates(p *Plan, rotateCA bool) error {
+	runDirectory, err := ae.createRunDirectory("rotate-certificates")
+	if err != nil {
+		return fmt.Errorf("error creating working directory for rotate-certificates: %v", err)
+	}
+	fp := FilePlanner{
+		File: filepath.Join(runDirectory, "kismatic-cluster.yaml"),
+	}
+	if err = fp.Write(p); err != nil {
+		return fmt.Errorf("error recording plan file to %s: %v", fp.File, err)
+	}
+	util.PrintHeader(ae.stdout, "Rotating Certificates", '=')
+	backupDir, err := ae.pki.RotateClusterCertificates(p, clusterUsers, rotateCA)
+	if err != nil {
+		return err
+	}
+	util.PrettyPrintOk(ae.stdout, "New certificates can be found in the %q directory", ae.options.GeneratedAssetsDirectory)
+	inventory := buildInventoryFromPlan(p)
+	cc, err := ae.buildInstallExtraVars(p)
+	if err != nil {
+		return err
+	}
+	// Docker only has to be restarted when it must trust a new CA
+	cc.ForceEtcdRestart = true
+	cc.ForceAPIServerRestart = true
+	cc.ForceControllerManagerRestart = true
+	cc.ForceSchedulerRestart = true
+	cc.ForceProxyRestart = true
+	cc.ForceKubeletRestart = true
+	cc.ForceCalicoNodeRestart = true
+	cc.RotationSerial = "1"
+	if rotateCA {
+		cc.RotationSerial = "100%"
+	}
+	ansibleLogFilename := filepath.Join(runDirectory, "ansible.log")
+	ansibleLogFile, err := os.Create(ansibleLogFilename)
+	if err != nil {
+		return fmt.Errorf("error creating ansible log file %q: %v", ansibleLogFilename, err)
+	}
+	util.PrintHeader(ae.stdout, "Deploying Certificates", '=')
+	eventExplainer := &explain.DefaultEventExplainer{}
+	if err = ae.runPlaybookWithExplainer("rotate-certificates.yaml", eventExplainer, inventory, *cc, ansibleLogFile, runDirectory); err != nil {
+		util.PrettyPrintWarn(ae.stdout, "The previous certificates can be found in the %q directory", backupDir)
+		return fmt.Errorf("error deploying certificates: %v", err)
+	}
+	if rotateCA {
+		util.PrintHeader(ae.stdout, "Configuring Docker To Trust The New CA", '=')
+		eventExplainer = &explain.DefaultEventExplainer{}
+		if err = ae.runPlaybookWithExplainer("_docker.yaml", eventExplainer, inventory, *cc, ansibleLogFile, runDirectory); err != nil {
+			return fmt.Errorf("error configuring docker: %v", err)
+		}
+	}
+	if p.DockerRegistry.SetupInternal {
+		util.PrintHeader(ae.stdout, "Deploying Docker Registry Certificates", '=')
+		eventExplainer = &explain.DefaultEventExplainer{}
+		if err = ae.runPlaybookWithExplainer("_docker-registry-container.yaml", eventExplainer, inventory, *cc, ansibleLogFile, runDirectory); err != nil {
+			return fmt.Errorf("error deploying docker registry certificates: %v", err)
+		}
+	}
+	return nil
+}
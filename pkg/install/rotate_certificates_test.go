@@ -0,0 +1,79 @@
+package install
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+)
+
+func TestRotateCertificates(t *testing.T) {
+	tests := []struct {
+		rotateCA          bool
+		registry          bool
+		expectedPlaybooks []string
+	}{
+		{
+			expectedPlaybooks: []string{"rotate-certificates.yaml"},
+		},
+		{
+			registry:          true,
+			expectedPlaybooks: []string{"rotate-certificates.yaml", "_docker-registry-container.yaml"},
+		},
+		{
+			rotateCA:          true,
+			expectedPlaybooks: []string{"rotate-certificates.yaml", "_docker.yaml"},
+		},
+	}
+	for _, test := range tests {
+		fakeRunner := fakeRunner{}
+		pki := &fakePKI{caExists: true}
+		runsDir := mustGetTempDir(t)
+		defer os.RemoveAll(runsDir)
+		e := ansibleExecutor{
+			options:             ExecutorOptions{RunsDirectory: runsDir},
+			stdout:              ioutil.Discard,
+			consoleOutputFormat: ansible.RawFormat,
+			runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+				return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+			},
+			pki: pki,
+		}
+		p := diffTestPlan()
+		p.DockerRegistry.SetupInternal = test.registry
+		if err := e.RotateCertificates(&p, test.rotateCA); err != nil {
+			t.Fatalf("unexpected error rotating certificates: %v", err)
+		}
+		if !pki.rotateCalled || pki.rotateCA != test.rotateCA {
+			t.Errorf("expected certificates to be rotated with rotateCA=%v", test.rotateCA)
+		}
+		if !reflect.DeepEqual(fakeRunner.allNodesPlaybooks, test.expectedPlaybooks) {
+			t.Errorf("expected playbooks %v, got %v", test.expectedPlaybooks, fakeRunner.allNodesPlaybooks)
+		}
+	}
+}
+
+func TestRotateCertificatesStopsAfterPKIFailure(t *testing.T) {
+	fakeRunner := fakeRunner{}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		pki: &fakePKI{err: errors.New("some error")},
+	}
+	p := diffTestPlan()
+	if err := e.RotateCertificates(&p, false); err == nil {
+		t.Errorf("expected an error when rotating the certificates fails")
+	}
+	if len(fakeRunner.allNodesPlaybooks) != 0 {
+		t.Errorf("playbooks were run after a failure: %v", fakeRunner.allNodesPlaybooks)
+	}
+}
@@ -0,0 +1,83 @@
+package install
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// the file that is created in a run directory when the run completes successfully
+const successfulRunMarker = "success"
+
+// records that the run completed successfully, which means that the plan
+// recorded in the run directory describes the state of the cluster
+func recordSuccessfulRun(runDirectory string) error {
+	f, err := os.Create(filepath.Join(runDirectory, successfulRunMarker))
+	if err != nil {
+		return fmt.Errorf("error recording successful run in %q: %v", runDirectory, err)
+	}
+	return f.Close()
+}
+
+// LastSuccessfulPlan returns the plan that was used by the most recent
+// successful run found in the runs directory. The runs of clusters that were
+// installed before successful runs were recorded are not marked, so the plan
+// of the most recent install run is used when no run is marked as successful.
+// An error is returned if there are no such runs.
+func LastSuccessfulPlan(runsDirectory string) (*Plan, string, error) {
+	runs, err := filepath.Glob(filepath.Join(runsDirectory, "*", "*", successfulRunMarker))
+	if err != nil {
+		return nil, "", fmt.Errorf("error listing runs in %q: %v", runsDirectory, err)
+	}
+	var runDirectory string
+	for _, r := range runs {
+		// Run directories are named after the time the run started
+		if runDirectory == "" || filepath.Base(filepath.Dir(r)) > filepath.Base(runDirectory) {
+			runDirectory = filepath.Dir(r)
+		}
+	}
+	if runDirectory == "" {
+		runDirectory, err = lastInstallRun(runsDirectory)
+		if err != nil {
+			return nil, "", fmt.Errorf("no successful runs were found in %q", runsDirectory)
+		}
+	}
+	fp := FilePlanner{File: filepath.Join(runDirectory, "kismatic-cluster.yaml")}
+	if !fp.PlanExists() {
+		return nil, "", fmt.Errorf("plan file was not found in run directory %q", runDirectory)
+	}
+	p, err := fp.Read()
+	if err != nil {
+		return nil, "", fmt.Errorf("error reading plan file of run %q: %v", runDirectory, err)
+	}
+	return p, runDirectory, nil
+}
+
+// LastRun returns the directory of the most recent run found in the runs
+// directory, whether it was successful or not. An error is returned if
+// there are no runs.
+func LastRun(runsDirectory string) (string, error) {
+	return latestRunDirectory(filepath.Join(runsDirectory, "*", "*"))
+}
+
+// returns the most recent run directory that matches the pattern
+func latestRunDirectory(pattern string) (string, error) {
+	runs, err := filepath.Glob(pattern)
+	if err != nil {
+		return "", fmt.Errorf("error listing runs in %q: %v", pattern, err)
+	}
+	var runDirectory string
+	for _, r := range runs {
+		if info, err := os.Stat(r); err != nil || !info.IsDir() {
+			continue
+		}
+		// Run directories are named after the time the run started
+		if runDirectory == "" || filepath.Base(r) > filepath.Base(runDirectory) {
+			runDirectory = r
+		}
+	}
+	if runDirectory == "" {
+		return "", fmt.Errorf("no runs were found in %q", filepath.Dir(filepath.Dir(pattern)))
+	}
+	return runDirectory, nil
+}
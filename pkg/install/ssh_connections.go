@@ -0,0 +1,90 @@
+package install
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
+)
+
+const (
+	// DefaultSSHValidationConcurrency is the number of nodes whose SSH
+	// access is checked at the same time
+	DefaultSSHValidationConcurrency = 20
+	// DefaultSSHValidationTimeout is the time allowed for checking the SSH
+	// access to all the nodes
+	DefaultSSHValidationTimeout = 2 * time.Minute
+)
+
+// SSHValidationOptions are the options for checking the SSH access to the nodes
+type SSHValidationOptions struct {
+	// Concurrency is the maximum number of nodes that are checked at the
+	// same time. DefaultSSHValidationConcurrency is used when it is not set.
+	Concurrency int
+	// Timeout is the time allowed for checking all the nodes. The nodes
+	// that were not checked in time are reported as failed.
+	// DefaultSSHValidationTimeout is used when it is not set.
+	Timeout time.Duration
+}
+
+// SSHConnectionReport is the result of checking the SSH access to a node
+type SSHConnectionReport struct {
+	Host string
+	IP   string
+	ssh.ConnectionCheck
+}
+
+// CheckPlanSSHConnections checks the SSH access to all nodes in the cluster,
+// and returns a report for each node.
+func CheckPlanSSHConnections(p *Plan, opts SSHValidationOptions) []SSHConnectionReport {
+	return CheckSSHConnections(p.Cluster.SSH, p.GetUniqueNodes(), opts)
+}
+
+// CheckSSHConnections checks the SSH access to the nodes concurrently, and
+// returns a report for each node in the order of the nodes.
+func CheckSSHConnections(sshConfig SSHConfig, nodes []Node, opts SSHValidationOptions) []SSHConnectionReport {
+	return checkSSHConnections(nodes, opts, func(ctx context.Context, n Node) ssh.ConnectionCheck {
+		return ssh.CheckConnection(ctx, n.IP, sshConfig.Port, sshConfig.User, sshConfig.Key)
+	})
+}
+
+func checkSSHConnections(nodes []Node, opts SSHValidationOptions, check func(ctx context.Context, n Node) ssh.ConnectionCheck) []SSHConnectionReport {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultSSHValidationConcurrency
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultSSHValidationTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	reports := make([]SSHConnectionReport, len(nodes))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, n := range nodes {
+		reports[i] = SSHConnectionReport{Host: n.Host, IP: n.IP}
+		wg.Add(1)
+		go func(r *SSHConnectionReport, n Node) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				r.Err = fmt.Errorf("the node was not checked within %v", timeout)
+				return
+			}
+			r.ConnectionCheck = check(ctx, n)
+			if r.Err != nil && ctx.Err() != nil {
+				r.Err = fmt.Errorf("the check did not complete within %v: %v", timeout, r.Err)
+			}
+		}(&reports[i], n)
+	}
+	wg.Wait()
+	return reports
+}
@@ -0,0 +1,101 @@
+package install
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/apprenda/kismatic/pkg/ssh"
+)
+
+func sshTestNodes(count int) []Node {
+	nodes := []Node{}
+	for i := 0; i < count; i++ {
+		nodes = append(nodes, Node{Host: fmt.Sprintf("node%02d", i), IP: fmt.Sprintf("10.0.0.%d", i)})
+	}
+	return nodes
+}
+
+func TestCheckSSHConnectionsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	var running, maxRunning int
+	check := func(ctx context.Context, n Node) ssh.ConnectionCheck {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if n.Host == "node03" {
+			return ssh.ConnectionCheck{Reachable: true, Err: errors.New("unable to authenticate")}
+		}
+		return ssh.ConnectionCheck{Reachable: true, Authenticated: true, Sudo: true, Latency: time.Millisecond}
+	}
+	nodes := sshTestNodes(10)
+	reports := checkSSHConnections(nodes, SSHValidationOptions{Concurrency: 3}, check)
+	if maxRunning > 3 {
+		t.Errorf("expected at most 3 nodes to be checked at the same time, got %d", maxRunning)
+	}
+	if len(reports) != len(nodes) {
+		t.Fatalf("expected a report for each node, got %d", len(reports))
+	}
+	for i, r := range reports {
+		if r.Host != nodes[i].Host || r.IP != nodes[i].IP {
+			t.Errorf("expected report %d to be for node %q, got %q", i, nodes[i].Host, r.Host)
+		}
+		if r.Host == "node03" {
+			if r.Err == nil || r.Authenticated {
+				t.Errorf("expected the authentication of node03 to fail, got %+v", r)
+			}
+			continue
+		}
+		if r.Err != nil || !r.Sudo || r.Latency != time.Millisecond {
+			t.Errorf("expected the check of %q to succeed, got %+v", r.Host, r)
+		}
+	}
+}
+
+func TestCheckSSHConnectionsTimeout(t *testing.T) {
+	// a node that never answers fails at the deadline
+	check := func(ctx context.Context, n Node) ssh.ConnectionCheck {
+		if n.Host == "node00" {
+			<-ctx.Done()
+			return ssh.ConnectionCheck{Reachable: true, Err: ctx.Err()}
+		}
+		return ssh.ConnectionCheck{Reachable: true, Authenticated: true, Sudo: true}
+	}
+	start := time.Now()
+	reports := checkSSHConnections(sshTestNodes(1), SSHValidationOptions{Timeout: 100 * time.Millisecond}, check)
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("the check did not stop at the deadline")
+	}
+	if reports[0].Err == nil || !reports[0].Reachable {
+		t.Errorf("expected the unresponsive node to fail, got %+v", reports[0])
+	}
+
+	// the nodes that are waiting for their turn are not checked after the deadline
+	var mu sync.Mutex
+	checked := map[string]bool{}
+	reports = checkSSHConnections(sshTestNodes(3), SSHValidationOptions{Concurrency: 1, Timeout: 100 * time.Millisecond}, func(ctx context.Context, n Node) ssh.ConnectionCheck {
+		mu.Lock()
+		checked[n.Host] = true
+		mu.Unlock()
+		<-ctx.Done()
+		return ssh.ConnectionCheck{Err: ctx.Err()}
+	})
+	for _, r := range reports {
+		if r.Err == nil {
+			t.Errorf("expected %q to fail when the deadline is exceeded", r.Host)
+		}
+	}
+	if len(checked) != 1 {
+		t.Errorf("expected a single node to be checked before the deadline, got %v", checked)
+	}
+}
@@ -0,0 +1,239 @@
+package install
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/ssh"
+	"github.com/apprenda/kismatic/pkg/util"
+)
+
+// The version of the Kismatic packages bundled with this installer. Must be
+// kept in sync with kismatic_apt_version in ansible/group_vars/all.yaml
+const kismaticPackageVersion = "1.5.2-3"
+
+// NodeVersion is the version of the Kismatic packages installed on a node
+type NodeVersion struct {
+	Node    Node
+	Roles   []string
+	Version string
+}
+
+// ListVersions connects to each node in the plan and returns the version
+// of the Kismatic packages that are installed on it.
+func ListVersions(p *Plan) ([]NodeVersion, error) {
+	return listVersions(p, p.GetSSHClient)
+}
+
+func listVersions(p *Plan, sshClient func(host string) (ssh.Client, error)) ([]NodeVersion, error) {
+	versions := []NodeVersion{}
+	for _, n := range p.GetUniqueNodes() {
+		roles := p.GetNodeRoles(n.Host)
+		client, err := sshClient(n.Host)
+		if err != nil {
+			return nil, err
+		}
+		// etcd nodes that are not part of the Kubernetes cluster only have the etcd package
+		pkg := "kismatic-kubernetes-node"
+		if len(roles) == 1 && roles[0] == "etcd" {
+			pkg = "kismatic-etcd"
+		}
+		cmd := fmt.Sprintf("rpm -q --queryformat '%%{VERSION}-%%{RELEASE}' %[1]s 2>/dev/null || dpkg-query -W -f='${Version}' %[1]s", pkg)
+		out, err := client.Output(false, cmd)
+		if err != nil {
+			return nil, fmt.Errorf("error getting installed version on node %q: %v", n.Host, err)
+		}
+		versions = append(versions, NodeVersion{
+			Node:    n,
+			Roles:   roles,
+			Version: strings.TrimSpace(out),
+		})
+	}
+	return versions, nil
+}
+
+// Upgrade the cluster to the version of Kubernetes that is bundled with
+// this installer. Etcd nodes are upgraded first, followed by the masters.
+// Worker nodes are cordoned, drained and upgraded one at a time. Nodes that
+// are already at the version of the installer are skipped, and the upgrade
+// fails if any node is at a newer version.
+func (ae *ansibleExecutor) Upgrade(p *Plan, versions []NodeVersion) error {
+	upToDate, err := nodesAtInstallerVersion(versions)
+	if err != nil {
+		return err
+	}
+	etcdNodes := nodesToUpgrade(p.Etcd.Nodes, upToDate)
+	masterNodes := nodesToUpgrade(p.Master.Nodes, upToDate)
+	workers := nodesToUpgrade(p.getUpgradeableWorkers(), upToDate)
+	if len(etcdNodes) == 0 && len(masterNodes) == 0 && len(workers) == 0 {
+		fmt.Fprintf(ae.stdout, "All nodes are at version %s, there is nothing to upgrade\n", kismaticPackageVersion)
+		return nil
+	}
+
+	runDirectory, err := ae.createRunDirectory("upgrade")
+	if err != nil {
+		return fmt.Errorf("error creating working directory for upgrade: %v", err)
+	}
+	fp := FilePlanner{
+		File: filepath.Join(runDirectory, "kismatic-cluster.yaml"),
+	}
+	if err = fp.Write(p); err != nil {
+		return fmt.Errorf("error recording plan file to %s: %v", fp.File, err)
+	}
+	inventory := buildInventoryFromPlan(p)
+	cc, err := ae.buildInstallExtraVars(p)
+	if err != nil {
+		return err
+	}
+	// Services must be restarted to pick up the new binaries. Docker is
+	// not upgraded, so there is no need to restart it.
+	cc.ForceEtcdRestart = true
+	cc.ForceAPIServerRestart = true
+	cc.ForceControllerManagerRestart = true
+	cc.ForceSchedulerRestart = true
+	cc.ForceProxyRestart = true
+	cc.ForceKubeletRestart = true
+	cc.ForceCalicoNodeRestart = true
+
+	ansibleLogFilename := filepath.Join(runDirectory, "ansible.log")
+	ansibleLogFile, err := os.Create(ansibleLogFilename)
+	if err != nil {
+		return fmt.Errorf("error creating ansible log file %q: %v", ansibleLogFilename, err)
+	}
+
+	if len(etcdNodes) > 0 {
+		util.PrintHeader(ae.stdout, "Upgrading Etcd Nodes", '=')
+		eventExplainer := &explain.DefaultEventExplainer{}
+		if err = ae.runUpgradePlaybook("upgrade-etcd.yaml", eventExplainer, inventory, *cc, etcdNodes, len(p.Etcd.Nodes), ansibleLogFile, runDirectory); err != nil {
+			return fmt.Errorf("error upgrading etcd nodes: %v", err)
+		}
+	}
+
+	if len(masterNodes) > 0 {
+		util.PrintHeader(ae.stdout, "Upgrading Master Nodes", '=')
+		eventExplainer := &explain.DefaultEventExplainer{}
+		if err = ae.runUpgradePlaybook("upgrade-master.yaml", eventExplainer, inventory, *cc, masterNodes, len(p.Master.Nodes), ansibleLogFile, runDirectory); err != nil {
+			return fmt.Errorf("error upgrading master nodes: %v", err)
+		}
+	}
+
+	for i, n := range workers {
+		util.PrintHeader(ae.stdout, fmt.Sprintf("Upgrading Node %q (%d/%d)", n.Host, i+1, len(workers)), '=')
+		eventExplainer := &explain.DefaultEventExplainer{}
+		if err = ae.runPlaybookOnNodeWithExplainer("upgrade-worker.yaml", eventExplainer, inventory, *cc, n.Host, ansibleLogFile, runDirectory); err != nil {
+			return fmt.Errorf("error upgrading node %q: %v", n.Host, err)
+		}
+	}
+	return recordSuccessfulRun(runDirectory)
+}
+
+// runs the playbook on the whole group, or limits it to the given nodes
+// when some of the nodes in the group are already upgraded
+func (ae *ansibleExecutor) runUpgradePlaybook(playbook string, eventExplainer explain.AnsibleEventExplainer, inv ansible.Inventory, cc ansible.ClusterCatalog, nodes []Node, groupSize int, ansibleLog io.Writer, runDirectory string) error {
+	if len(nodes) == groupSize {
+		return ae.runPlaybookWithExplainer(playbook, eventExplainer, inv, cc, ansibleLog, runDirectory)
+	}
+	hosts := []string{}
+	for _, n := range nodes {
+		hosts = append(hosts, n.Host)
+	}
+	return ae.runPlaybookOnNodeWithExplainer(playbook, eventExplainer, inv, cc, strings.Join(hosts, ","), ansibleLog, runDirectory)
+}
+
+// returns the hosts that are at the version of the installer. An error is
+// returned if a node is at a newer version, as downgrades are not supported.
+// Nodes with an unknown version are upgraded.
+func nodesAtInstallerVersion(versions []NodeVersion) (map[string]bool, error) {
+	target, err := parsePackageVersion(kismaticPackageVersion)
+	if err != nil {
+		return nil, err
+	}
+	upToDate := map[string]bool{}
+	for _, v := range versions {
+		if v.Version == "" {
+			continue
+		}
+		installed, err := parsePackageVersion(v.Version)
+		if err != nil {
+			return nil, fmt.Errorf("error reading installed version of node %q: %v", v.Node.Host, err)
+		}
+		switch installed.compare(target) {
+		case 1:
+			return nil, fmt.Errorf("node %q is at version %s, which is newer than the version of this installer %s. Downgrades are not supported", v.Node.Host, v.Version, kismaticPackageVersion)
+		case 0:
+			upToDate[v.Node.Host] = true
+		}
+	}
+	return upToDate, nil
+}
+
+func nodesToUpgrade(nodes []Node, upToDate map[string]bool) []Node {
+	upgrade := []Node{}
+	for _, n := range nodes {
+		if !upToDate[n.Host] {
+			upgrade = append(upgrade, n)
+		}
+	}
+	return upgrade
+}
+
+// packageVersion is the version of a Kismatic package, made of the
+// Kubernetes version and the Kismatic release. The rpm version 1.5.2_3-1 and
+// the deb version 1.5.2-3 are both Kubernetes 1.5.2, Kismatic release 3.
+type packageVersion [4]int
+
+func parsePackageVersion(s string) (packageVersion, error) {
+	v := packageVersion{}
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == '.' || r == '_' || r == '-' })
+	if len(fields) < len(v) {
+		return v, fmt.Errorf("invalid package version %q", s)
+	}
+	for i := range v {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return v, fmt.Errorf("invalid package version %q", s)
+		}
+		v[i] = n
+	}
+	return v, nil
+}
+
+// compare returns -1, 0 or 1 when v is older, the same or newer than o
+func (v packageVersion) compare(o packageVersion) int {
+	for i := range v {
+		if v[i] < o[i] {
+			return -1
+		}
+		if v[i] > o[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// returns the worker, ingress and storage nodes that are not masters.
+// Masters are upgraded with the rest of the control plane.
+func (p *Plan) getUpgradeableWorkers() []Node {
+	nodes := []Node{}
+	seen := map[string]bool{}
+	for _, n := range p.Master.Nodes {
+		seen[n.Host] = true
+	}
+	candidates := append([]Node{}, p.Worker.Nodes...)
+	candidates = append(candidates, p.Ingress.Nodes...)
+	candidates = append(candidates, p.Storage.Nodes...)
+	for _, n := range candidates {
+		if seen[n.Host] {
+			continue
+		}
+		seen[n.Host] = true
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
@@ -0,0 +1,227 @@
+package install
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"testing"
+
+	"github.com/apprenda/kismatic/pkg/ansible"
+	"github.com/apprenda/kismatic/pkg/install/explain"
+	"github.com/apprenda/kismatic/pkg/ssh"
+)
+
+var upgradePlan = Plan{
+	Cluster: Cluster{
+		Networking: NetworkConfig{
+			ServiceCIDRBlock: "10.0.0.0/16",
+		},
+	},
+	Etcd: NodeGroup{
+		Nodes: []Node{{Host: "etcd01"}},
+	},
+	Master: MasterNodeGroup{
+		Nodes: []Node{{Host: "master01", InternalIP: "10.10.2.20"}},
+	},
+	Worker: NodeGroup{
+		Nodes: []Node{{Host: "worker01"}, {Host: "worker02"}, {Host: "master01"}},
+	},
+	Ingress: OptionalNodeGroup{
+		Nodes: []Node{{Host: "worker01"}, {Host: "ingress01"}},
+	},
+}
+
+func TestUpgradeOrder(t *testing.T) {
+	fakeRunner := fakeRunner{}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		certsDir: mustGetTempDir(t),
+	}
+	p := upgradePlan
+	if err := e.Upgrade(&p, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedPlaybooks := []string{"upgrade-etcd.yaml", "upgrade-master.yaml"}
+	if !reflect.DeepEqual(fakeRunner.allNodesPlaybooks, expectedPlaybooks) {
+		t.Errorf("expected playbooks %v, but got %v", expectedPlaybooks, fakeRunner.allNodesPlaybooks)
+	}
+	// each node is upgraded once, and masters are not upgraded as workers
+	expectedNodePlaybooks := []string{"worker01:upgrade-worker.yaml", "worker02:upgrade-worker.yaml", "ingress01:upgrade-worker.yaml"}
+	if !reflect.DeepEqual(fakeRunner.nodePlaybooks, expectedNodePlaybooks) {
+		t.Errorf("expected node playbooks %v, but got %v", expectedNodePlaybooks, fakeRunner.nodePlaybooks)
+	}
+	if !fakeRunner.incomingCatalog.ForceKubeletRestart {
+		t.Errorf("missing restart flag for service kubelet")
+	}
+}
+
+func TestUpgradeStopsAfterFailure(t *testing.T) {
+	fakeRunner := fakeRunner{err: errors.New("exec error")}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		certsDir: mustGetTempDir(t),
+	}
+	p := upgradePlan
+	if err := e.Upgrade(&p, nil); err == nil {
+		t.Errorf("expected an error, but didn't get one")
+	}
+	if len(fakeRunner.allNodesPlaybooks) != 1 || len(fakeRunner.nodePlaybooks) != 0 {
+		t.Errorf("upgrade continued after etcd upgrade failed. Ran %v and %v", fakeRunner.allNodesPlaybooks, fakeRunner.nodePlaybooks)
+	}
+}
+
+func TestUpgradeSkipsNodesAtInstallerVersion(t *testing.T) {
+	fakeRunner := fakeRunner{}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		certsDir: mustGetTempDir(t),
+	}
+	p := upgradePlan
+	p.Etcd.Nodes = []Node{{Host: "etcd01"}, {Host: "etcd02"}}
+	versions := []NodeVersion{
+		{Node: Node{Host: "etcd01"}, Version: "1.5.2_3-1"},
+		{Node: Node{Host: "etcd02"}, Version: "1.5.1_1-1"},
+		{Node: Node{Host: "master01"}, Version: "1.5.2-3"},
+		{Node: Node{Host: "worker01"}, Version: "1.5.1-1"},
+		{Node: Node{Host: "worker02"}, Version: "1.5.2-3"},
+		{Node: Node{Host: "ingress01"}, Version: ""},
+	}
+	if err := e.Upgrade(&p, versions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fakeRunner.allNodesPlaybooks) != 0 {
+		t.Errorf("expected no playbooks to run on all nodes, but got %v", fakeRunner.allNodesPlaybooks)
+	}
+	// nodes with an unknown version are upgraded
+	expectedNodePlaybooks := []string{"etcd02:upgrade-etcd.yaml", "worker01:upgrade-worker.yaml", "ingress01:upgrade-worker.yaml"}
+	if !reflect.DeepEqual(fakeRunner.nodePlaybooks, expectedNodePlaybooks) {
+		t.Errorf("expected node playbooks %v, but got %v", expectedNodePlaybooks, fakeRunner.nodePlaybooks)
+	}
+}
+
+func TestUpgradeAllNodesAtInstallerVersion(t *testing.T) {
+	fakeRunner := fakeRunner{}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		certsDir: mustGetTempDir(t),
+	}
+	p := upgradePlan
+	versions := []NodeVersion{}
+	for _, n := range p.GetUniqueNodes() {
+		versions = append(versions, NodeVersion{Node: n, Version: kismaticPackageVersion})
+	}
+	if err := e.Upgrade(&p, versions); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fakeRunner.allNodesPlaybooks) != 0 || len(fakeRunner.nodePlaybooks) != 0 {
+		t.Errorf("expected no playbooks to run, but got %v and %v", fakeRunner.allNodesPlaybooks, fakeRunner.nodePlaybooks)
+	}
+}
+
+func TestUpgradeFailsOnDowngrade(t *testing.T) {
+	fakeRunner := fakeRunner{}
+	e := ansibleExecutor{
+		options:             ExecutorOptions{RunsDirectory: mustGetTempDir(t)},
+		stdout:              ioutil.Discard,
+		consoleOutputFormat: ansible.RawFormat,
+		runnerExplainerFactory: func(explain.AnsibleEventExplainer, io.Writer) (ansible.Runner, *explain.AnsibleEventStreamExplainer, error) {
+			return &fakeRunner, &explain.AnsibleEventStreamExplainer{}, nil
+		},
+		certsDir: mustGetTempDir(t),
+	}
+	p := upgradePlan
+	versions := []NodeVersion{
+		{Node: Node{Host: "etcd01"}, Version: "1.5.1-1"},
+		{Node: Node{Host: "worker02"}, Version: "1.5.3_1-1"},
+	}
+	if err := e.Upgrade(&p, versions); err == nil {
+		t.Errorf("expected an error upgrading nodes that are newer than the installer")
+	}
+	if len(fakeRunner.allNodesPlaybooks) != 0 || len(fakeRunner.nodePlaybooks) != 0 {
+		t.Errorf("expected no playbooks to run, but got %v and %v", fakeRunner.allNodesPlaybooks, fakeRunner.nodePlaybooks)
+	}
+}
+
+func TestParsePackageVersion(t *testing.T) {
+	tests := []struct {
+		version  string
+		expected packageVersion
+		valid    bool
+	}{
+		{"1.5.2-3", packageVersion{1, 5, 2, 3}, true},
+		{"1.5.2_3-1", packageVersion{1, 5, 2, 3}, true},
+		{"1.10.0-12", packageVersion{1, 10, 0, 12}, true},
+		{"1.5.2", packageVersion{}, false},
+		{"1.5.x-3", packageVersion{}, false},
+	}
+	for _, test := range tests {
+		v, err := parsePackageVersion(test.version)
+		if (err == nil) != test.valid {
+			t.Errorf("version %q: expected valid to be %v, got error %v", test.version, test.valid, err)
+			continue
+		}
+		if test.valid && v != test.expected {
+			t.Errorf("version %q: expected %v, got %v", test.version, test.expected, v)
+		}
+	}
+	if (packageVersion{1, 5, 2, 3}).compare(packageVersion{1, 10, 0, 1}) != -1 {
+		t.Errorf("expected 1.5.2-3 to be older than 1.10.0-1")
+	}
+}
+
+func TestListVersions(t *testing.T) {
+	clients := map[string]*fakeSSHClient{}
+	sshClient := func(host string) (ssh.Client, error) {
+		c := &fakeSSHClient{output: "1.5.2-3\n"}
+		clients[host] = c
+		return c, nil
+	}
+	p := upgradePlan
+	versions, err := listVersions(&p, sshClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(versions) != 5 {
+		t.Fatalf("expected 5 nodes, but got %d", len(versions))
+	}
+	for _, v := range versions {
+		if v.Version != "1.5.2-3" {
+			t.Errorf("expected version 1.5.2-3 for node %q, but got %q", v.Node.Host, v.Version)
+		}
+	}
+	if !reflect.DeepEqual(versions[1].Roles, []string{"master", "worker"}) {
+		t.Errorf("unexpected roles for master01: %v", versions[1].Roles)
+	}
+	if !reflect.DeepEqual(versions[2].Roles, []string{"worker", "ingress"}) {
+		t.Errorf("unexpected roles for worker01: %v", versions[2].Roles)
+	}
+}
+
+type fakeSSHClient struct {
+	output string
+	err    error
+}
+
+func (f *fakeSSHClient) Output(pty bool, args ...string) (string, error) { return f.output, f.err }
+func (f *fakeSSHClient) Shell(pty bool, args ...string) error            { return f.err }
@@ -9,7 +9,6 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/apprenda/kismatic/pkg/ssh"
@@ -39,7 +38,7 @@ func ValidateNode(node *Node) (bool, []error) {
 func ValidatePlanSSHConnections(p *Plan) (bool, []error) {
 	v := newValidator()
 
-	s := sshConnectionSet{p.Cluster.SSH, p.GetUniqueNodeIPs()}
+	s := sshConnectionSet{p.Cluster.SSH, p.GetUniqueNodes(), SSHValidationOptions{}}
 
 	v.validateWithErrPrefix("Node Connnection", s)
 
@@ -48,14 +47,15 @@ func ValidatePlanSSHConnections(p *Plan) (bool, []error) {
 
 type sshConnectionSet struct {
 	SSHConfig SSHConfig
-	IPs       []string
+	Nodes     []Node
+	Options   SSHValidationOptions
 }
 
 // ValidateSSHConnection tries to establish SSH connection with the details provieded for a single node
 func ValidateSSHConnection(con *SSHConnection, prefix string) (bool, []error) {
 	v := newValidator()
 
-	s := sshConnectionSet{*con.SSHConfig, []string{con.Node.IP}}
+	s := sshConnectionSet{*con.SSHConfig, []Node{*con.Node}, SSHValidationOptions{}}
 
 	v.validateWithErrPrefix(prefix, s)
 
@@ -66,7 +66,7 @@ func ValidateSSHConnection(con *SSHConnection, prefix string) (bool, []error) {
 func ValidateCertificates(p *Plan, pki *LocalPKI) (bool, []error) {
 	v := newValidator()
 
-	warn, err := pki.ValidateClusterCertificates(p, []string{"admin"})
+	warn, err := pki.ValidateClusterCertificates(p, clusterUsers)
 	if err != nil && len(err) > 0 {
 		v.addError(err...)
 	}
@@ -138,6 +138,8 @@ func (p *Plan) validate() (bool, []error) {
 	v.validateWithErrPrefix("Ingress nodes", &p.Ingress)
 	v.validate(&p.NFS)
 	v.validateWithErrPrefix("Storage nodes", &p.Storage)
+	validateAddOns(p, v)
+	v.validate(authorization{users: p.Users, groups: p.Groups, nodes: p.GetUniqueNodes()})
 
 	return v.valid()
 }
@@ -186,6 +188,37 @@ func (c *CertsConfig) validate() (bool, []error) {
 	if _, err := time.ParseDuration(c.Expiry); err != nil {
 		v.addError(fmt.Errorf("Invalid certificate expiry %q provided: %v", c.Expiry, err))
 	}
+	if c.CACert == "" && c.CAKey != "" {
+		v.addError(errors.New("Certificates ca_cert is required when ca_key is provided"))
+	}
+	if c.CACert != "" && c.CAKey == "" {
+		v.addError(errors.New("Certificates ca_key is required when ca_cert is provided"))
+	}
+	if c.CACert == "" && c.CAChain != "" {
+		v.addError(errors.New("Certificates ca_cert is required when ca_chain is provided"))
+	}
+	files := []struct {
+		field string
+		path  string
+	}{
+		{"ca_cert", c.CACert},
+		{"ca_key", c.CAKey},
+		{"ca_chain", c.CAChain},
+	}
+	for _, f := range files {
+		if f.path == "" {
+			continue
+		}
+		if !filepath.IsAbs(f.path) {
+			v.addError(fmt.Errorf("Certificates %s must be an absolute path", f.field))
+		}
+		if _, err := os.Stat(f.path); os.IsNotExist(err) {
+			v.addError(fmt.Errorf("Certificates %s file was not found at %q", f.field, f.path))
+		}
+	}
+	if c.Subject.Country != "" && len(c.Subject.Country) != 2 {
+		v.addError(fmt.Errorf("Invalid certificate subject country %q provided, must be a two-letter country code", c.Subject.Country))
+	}
 	return v.valid()
 }
 
@@ -216,35 +249,11 @@ func (s sshConnectionSet) validate() (bool, []error) {
 	err := ssh.ValidUnencryptedPrivateKey(s.SSHConfig.Key)
 	if err != nil {
 		v.addError(fmt.Errorf("error parsing SSH key: %v", err))
-	} else {
-		var wg sync.WaitGroup
-		errQueue := make(chan error, len(s.IPs))
-		// number of nodes
-		wg.Add(len(s.IPs))
-		for _, ipa := range s.IPs {
-			go func(ip string) {
-				defer wg.Done()
-				sshErr := ssh.TestConnection(ip, s.SSHConfig.Port, s.SSHConfig.User, s.SSHConfig.Key)
-				// Need to send something the buffered channel
-				if sshErr != nil {
-					errQueue <- fmt.Errorf("SSH connectivity validation failed for %q: %v", ip, sshErr)
-				} else {
-					errQueue <- nil
-				}
-			}(ipa)
-		}
-
-		// Wait for all nodes to complete, then close channel
-		go func() {
-			wg.Wait()
-			close(errQueue)
-		}()
-
-		// Read any error
-		for err := range errQueue {
-			if err != nil {
-				v.addError(err)
-			}
+		return v.valid()
+	}
+	for _, r := range CheckSSHConnections(s.SSHConfig, s.Nodes, s.Options) {
+		if r.Err != nil {
+			v.addError(fmt.Errorf("SSH connectivity validation failed for %q: %v", r.IP, r.Err))
 		}
 	}
 
@@ -401,6 +410,118 @@ func (nfsVol NFSVolume) validate() (bool, []error) {
 	return v.valid()
 }
 
+func (l *Logging) validate() (bool, []error) {
+	v := newValidator()
+	if l.RetentionDays < 1 {
+		v.addError(errors.New("Retention must be 1 day or longer"))
+	}
+	return v.valid()
+}
+
+// the storage of an add-on, which can only use glusterfs when the cluster has storage nodes
+type addOnStorage struct {
+	storage      AddOnStorage
+	storageNodes int
+}
+
+func (s addOnStorage) validate() (bool, []error) {
+	v := newValidator()
+	switch s.storage.Backend {
+	case "nfs":
+		if s.storage.NFSServer == "" {
+			v.addError(errors.New("NFS server cannot be empty when the backend is nfs"))
+		}
+		if s.storage.NFSPath == "" {
+			v.addError(errors.New("NFS path cannot be empty when the backend is nfs"))
+		}
+		if len(s.storage.NFSPath) > 0 && s.storage.NFSPath[0] != '/' {
+			v.addError(errors.New("NFS path must be absolute"))
+		}
+	case "glusterfs":
+		if s.storageNodes == 0 {
+			v.addError(errors.New("Backend glusterfs requires storage nodes"))
+		}
+	default:
+		v.addError(fmt.Errorf("Backend %q is not supported. Must be one of nfs or glusterfs", s.storage.Backend))
+	}
+	if s.storage.StorageSizeGB < 1 {
+		v.addError(errors.New("Storage size must be 1GB or larger"))
+	}
+	return v.valid()
+}
+
+// the names of the certificates that are generated for the cluster, which
+// are stored in the same directory as the certificates of the users
+var reservedCertificateNames = []string{"ca", caChainName, "admin", monitoringUser, "service-account", "docker"}
+
+var userNameRE = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._@-]*$`)
+
+// the users and groups that are granted access to the cluster
+type authorization struct {
+	users  []User
+	groups []Group
+	nodes  []Node
+}
+
+func (a authorization) validate() (bool, []error) {
+	v := newValidator()
+	groups := map[string]bool{}
+	for _, g := range a.groups {
+		if g.Name == "" {
+			v.addError(errors.New("Group name cannot be empty"))
+			continue
+		}
+		if strings.HasPrefix(g.Name, "system:") {
+			v.addError(fmt.Errorf("Group %q: the system: prefix is reserved by Kubernetes", g.Name))
+		}
+		if groups[g.Name] {
+			v.addError(fmt.Errorf("Group %q is declared more than once", g.Name))
+		}
+		groups[g.Name] = true
+		v.validateWithErrPrefix(fmt.Sprintf("Group %q", g.Name), accessList(g.Access))
+	}
+	users := map[string]bool{}
+	for _, u := range a.users {
+		if !userNameRE.MatchString(u.Name) {
+			v.addError(fmt.Errorf("User name %q is not valid. Must start with a letter or number and only contain letters, numbers and the characters . _ @ -", u.Name))
+			continue
+		}
+		if users[u.Name] {
+			v.addError(fmt.Errorf("User %q is declared more than once", u.Name))
+		}
+		users[u.Name] = true
+		for _, r := range reservedCertificateNames {
+			if u.Name == r {
+				v.addError(fmt.Errorf("User name %q is reserved", u.Name))
+			}
+		}
+		for _, n := range a.nodes {
+			if u.Name == n.Host {
+				v.addError(fmt.Errorf("User name %q cannot be the same as the hostname of a node", u.Name))
+			}
+		}
+		for _, g := range u.Groups {
+			if !groups[g] {
+				v.addError(fmt.Errorf("User %q: group %q is not declared in the groups section", u.Name, g))
+			}
+		}
+		v.validateWithErrPrefix(fmt.Sprintf("User %q", u.Name), accessList(u.Access))
+	}
+	return v.valid()
+}
+
+type accessList []Access
+
+func (l accessList) validate() (bool, []error) {
+	v := newValidator()
+	for _, a := range l {
+		if a.Namespace == "" {
+			v.addError(errors.New("Access namespace cannot be empty, use \"*\" for all namespaces"))
+		}
+	}
+	return v.valid()
+}
+
 func (sv StorageVolume) validate() (bool, []error) {
 	v := newValidator()
 	notAllowed := ": / \\ & < > |"
@@ -2,6 +2,7 @@ package install
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -140,6 +141,31 @@ func TestValidatePlanInvalidCertExpiry(t *testing.T) {
 	assertInvalidPlan(t, p)
 }
 
+func TestValidatePlanCACertWithoutKey(t *testing.T) {
+	p := validPlan
+	p.Cluster.Certificates.CACert = "/bin/sh"
+	assertInvalidPlan(t, p)
+}
+
+func TestValidatePlanNonExistentCACert(t *testing.T) {
+	p := validPlan
+	p.Cluster.Certificates.CACert = "/foo/ca.pem"
+	p.Cluster.Certificates.CAKey = "/bin/sh"
+	assertInvalidPlan(t, p)
+}
+
+func TestValidatePlanCAChainWithoutCACert(t *testing.T) {
+	p := validPlan
+	p.Cluster.Certificates.CAChain = "/bin/sh"
+	assertInvalidPlan(t, p)
+}
+
+func TestValidatePlanInvalidCertSubjectCountry(t *testing.T) {
+	p := validPlan
+	p.Cluster.Certificates.Subject.Country = "USA"
+	assertInvalidPlan(t, p)
+}
+
 func TestValidatePlanEmptySSHUser(t *testing.T) {
 	p := validPlan
 	p.Cluster.SSH.User = ""
@@ -699,3 +725,143 @@ func TestDisconnectedInstallationPrereq(t *testing.T) {
 		fmt.Println(errs)
 	}
 }
+
+func TestValidateAddOnStorage(t *testing.T) {
+	tests := []struct {
+		storage      AddOnStorage
+		storageNodes int
+		valid        bool
+	}{
+		{
+			storage: AddOnStorage{Backend: "nfs", NFSServer: "10.10.2.10", NFSPath: "/data", StorageSizeGB: 10},
+			valid:   true,
+		},
+		{
+			storage:      AddOnStorage{Backend: "glusterfs", StorageSizeGB: 10},
+			storageNodes: 1,
+			valid:        true,
+		},
+		{
+			storage: AddOnStorage{Backend: "glusterfs", StorageSizeGB: 10},
+			valid:   false,
+		},
+		{
+			storage: AddOnStorage{Backend: "nfs", NFSPath: "/data", StorageSizeGB: 10},
+			valid:   false,
+		},
+		{
+			storage: AddOnStorage{Backend: "nfs", NFSServer: "10.10.2.10", NFSPath: "data", StorageSizeGB: 10},
+			valid:   false,
+		},
+		{
+			storage: AddOnStorage{Backend: "s3", StorageSizeGB: 10},
+			valid:   false,
+		},
+		{
+			storage:      AddOnStorage{Backend: "glusterfs", StorageSizeGB: 0},
+			storageNodes: 1,
+			valid:        false,
+		},
+	}
+	for i, test := range tests {
+		if valid, errs := (addOnStorage{test.storage, test.storageNodes}).validate(); valid != test.valid {
+			t.Errorf("test %d: expected valid = %v, but got %v: %v", i, test.valid, valid, errs)
+		}
+	}
+}
+
+func TestValidatePlanLogging(t *testing.T) {
+	p := validPlan
+	// the add-on settings are not validated when it is disabled
+	p.Logging = Logging{AddOnStorage: AddOnStorage{Backend: "s3"}}
+	_, errs := ValidatePlan(&p)
+	for _, err := range errs {
+		if strings.HasPrefix(err.Error(), "Logging") {
+			t.Errorf("unexpected logging error when the add-on is disabled: %v", err)
+		}
+	}
+
+	p.Logging = Logging{
+		Enabled:       true,
+		AddOnStorage:  AddOnStorage{Backend: "nfs", NFSServer: "10.10.2.10", NFSPath: "/logs", StorageSizeGB: 10},
+		RetentionDays: 0,
+	}
+	assertInvalidPlan(t, p)
+}
+
+func TestValidatePlanMonitoring(t *testing.T) {
+	p := validPlan
+	p.Monitoring = Monitoring{Enabled: true, AddOnStorage: AddOnStorage{Backend: "glusterfs", StorageSizeGB: 10}}
+	assertInvalidPlan(t, p)
+
+	p.Storage = OptionalNodeGroup{ExpectedCount: 1, Nodes: []Node{{Host: "storage01", IP: "10.0.0.10"}}}
+	_, errs := ValidatePlan(&p)
+	for _, err := range errs {
+		if strings.HasPrefix(err.Error(), "Monitoring") {
+			t.Errorf("unexpected monitoring error with storage nodes: %v", err)
+		}
+	}
+}
+
+func TestValidateAuthorization(t *testing.T) {
+	nodes := []Node{{Host: "worker01", IP: "10.0.0.1"}}
+	groups := []Group{{Name: "developers", Access: []Access{{Namespace: "dev"}}}}
+	tests := []struct {
+		users  []User
+		groups []Group
+		valid  bool
+	}{
+		{
+			users:  []User{{Name: "jane", Groups: []string{"developers"}, Access: []Access{{Namespace: "*", ReadOnly: true}}}},
+			groups: groups,
+			valid:  true,
+		},
+		{
+			users: []User{{Name: "jane@example.com"}},
+			valid: true,
+		},
+		{
+			users: []User{{Name: ""}},
+			valid: false,
+		},
+		{
+			users: []User{{Name: "jane doe"}},
+			valid: false,
+		},
+		{
+			users: []User{{Name: "jane"}, {Name: "jane"}},
+			valid: false,
+		},
+		{
+			// the certificate of the user would replace the admin certificate
+			users: []User{{Name: "admin"}},
+			valid: false,
+		},
+		{
+			users: []User{{Name: "worker01"}},
+			valid: false,
+		},
+		{
+			users: []User{{Name: "jane", Groups: []string{"operators"}}},
+			valid: false,
+		},
+		{
+			users: []User{{Name: "jane", Access: []Access{{Namespace: ""}}}},
+			valid: false,
+		},
+		{
+			groups: []Group{{Name: "system:masters"}},
+			valid:  false,
+		},
+		{
+			groups: []Group{{Name: "developers"}, {Name: "developers"}},
+			valid:  false,
+		},
+	}
+	for i, test := range tests {
+		a := authorization{users: test.users, groups: test.groups, nodes: nodes}
+		if valid, errs := a.validate(); valid != test.valid {
+			t.Errorf("test %d: expected valid = %v, but got %v: %v", i, test.valid, valid, errs)
+		}
+	}
+}
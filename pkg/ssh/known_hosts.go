@@ -0,0 +1,213 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// the known hosts used by the clients returned by NewClient
+var knownHosts *KnownHosts
+
+// UseKnownHostsFile enables host key verification for the clients returned
+// by NewClient, using the known_hosts file
+func UseKnownHostsFile(file string) {
+	// The file is also given to ansible, which runs in a different directory
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	knownHosts = &KnownHosts{File: file}
+}
+
+// returns the callback that verifies the host keys against the known_hosts
+// file, or rejects them if a known_hosts file is not in use
+func knownHostsCallback() func(string, net.Addr, ssh.PublicKey) error {
+	if knownHosts == nil {
+		return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			return fmt.Errorf("the host key of %s cannot be verified, as a known_hosts file is not in use", hostname)
+		}
+	}
+	return knownHosts.HostKeyCallback
+}
+
+// InsecureIgnoreHostKey returns a host key callback that accepts the key of
+// any node. It should only be used when the nodes are trusted, such as in tests.
+func InsecureIgnoreHostKey() func(string, net.Addr, ssh.PublicKey) error {
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return nil
+	}
+}
+
+// KnownHostsFile returns the known_hosts file used by the clients returned
+// by NewClient, or an empty string if host keys are not verified
+func KnownHostsFile() string {
+	if knownHosts == nil {
+		return ""
+	}
+	return knownHosts.File
+}
+
+// HostKeyMismatchError is returned when a node presents a host key that is
+// different from the one recorded for it
+type HostKeyMismatchError struct {
+	Host        string
+	File        string
+	Fingerprint string
+}
+
+func (e *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("the host key of %s has changed and does not match the key recorded in %q (received %s). "+
+		"Someone could be intercepting the connection to the node. If the node was rebuilt, "+
+		"run \"kismatic host-keys rotate\" to record its new key", e.Host, e.File, e.Fingerprint)
+}
+
+// KnownHosts verifies the host keys of the nodes against a known_hosts file.
+// The key of a node is recorded the first time a connection is made to it,
+// and later connections fail if the node presents a different key.
+type KnownHosts struct {
+	File string
+	mu   sync.Mutex
+}
+
+// HostKeyCallback verifies the key of the node, recording it if the node is
+// not in the known_hosts file
+func (k *KnownHosts) HostKeyCallback(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	addr := knownHostsAddress(hostname)
+	recorded, err := k.read()
+	if err != nil {
+		return err
+	}
+	for _, r := range recorded[addr] {
+		// Nodes have a key for each algorithm, only keys of the same type are compared
+		if r.Type() != key.Type() {
+			continue
+		}
+		if bytes.Equal(r.Marshal(), key.Marshal()) {
+			return nil
+		}
+		return &HostKeyMismatchError{Host: addr, File: k.File, Fingerprint: Fingerprint(key)}
+	}
+	return k.record(addr, key)
+}
+
+// Remove removes the recorded keys of the hosts, given as "host:port"
+func (k *KnownHosts) Remove(hosts ...string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	remove := map[string]bool{}
+	for _, h := range hosts {
+		remove[knownHostsAddress(h)] = true
+	}
+	data, err := ioutil.ReadFile(k.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading known hosts file %q: %v", k.File, err)
+	}
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) > 0 && remove[fields[0]] {
+			continue
+		}
+		out.WriteString(line + "\n")
+	}
+	if err := ioutil.WriteFile(k.File, out.Bytes(), 0600); err != nil {
+		return fmt.Errorf("error writing known hosts file %q: %v", k.File, err)
+	}
+	return nil
+}
+
+// Fingerprints returns the fingerprints of the keys recorded for the host,
+// given as "host:port"
+func (k *KnownHosts) Fingerprints(hostname string) ([]string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	recorded, err := k.read()
+	if err != nil {
+		return nil, err
+	}
+	fingerprints := []string{}
+	for _, key := range recorded[knownHostsAddress(hostname)] {
+		fingerprints = append(fingerprints, Fingerprint(key))
+	}
+	return fingerprints, nil
+}
+
+// returns the recorded keys, keyed by address
+func (k *KnownHosts) read() (map[string][]ssh.PublicKey, error) {
+	keys := map[string][]ssh.PublicKey{}
+	data, err := ioutil.ReadFile(k.File)
+	if os.IsNotExist(err) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading known hosts file %q: %v", k.File, err)
+	}
+	// The lines are parsed one at a time, so that a line that cannot be
+	// parsed is skipped instead of dropping all the entries after it
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		_, hosts, key, _, _, err := ssh.ParseKnownHosts(scanner.Bytes())
+		if err != nil {
+			// Empty lines and comments are returned as EOF
+			continue
+		}
+		for _, h := range hosts {
+			keys[h] = append(keys[h], key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading known hosts file %q: %v", k.File, err)
+	}
+	return keys, nil
+}
+
+func (k *KnownHosts) record(addr string, key ssh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(k.File), 0700); err != nil {
+		return fmt.Errorf("error creating directory for known hosts file: %v", err)
+	}
+	f, err := os.OpenFile(k.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening known hosts file %q: %v", k.File, err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintf(f, "%s %s", addr, ssh.MarshalAuthorizedKey(key)); err != nil {
+		return fmt.Errorf("error recording host key of %s: %v", addr, err)
+	}
+	return nil
+}
+
+// returns the address in the format used by known_hosts files. The port is
+// only included when it is not the default SSH port.
+func knownHostsAddress(hostname string) string {
+	host, port, err := net.SplitHostPort(hostname)
+	if err != nil {
+		return hostname
+	}
+	if port == "22" {
+		return host
+	}
+	return fmt.Sprintf("[%s]:%s", host, port)
+}
+
+// Fingerprint returns the SHA256 fingerprint of the key
+func Fingerprint(key ssh.PublicKey) string {
+	sum := sha256.Sum256(key.Marshal())
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
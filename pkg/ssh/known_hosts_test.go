@@ -0,0 +1,176 @@
+package ssh
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func mustGetKnownHosts(t *testing.T) *KnownHosts {
+	dir, err := ioutil.TempDir("", "known-hosts-tests")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	// the directory of the file is created when the first key is recorded
+	return &KnownHosts{File: filepath.Join(dir, "generated", "known_hosts")}
+}
+
+func mustGetPublicKey(t *testing.T) ssh.PublicKey {
+	signer, err := ssh.NewSignerFromKey(mustGenerateKey(t))
+	if err != nil {
+		t.Fatalf("error creating signer: %v", err)
+	}
+	return signer.PublicKey()
+}
+
+func TestKnownHostsRecordsKeyOnFirstContact(t *testing.T) {
+	kh := mustGetKnownHosts(t)
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(kh.File)))
+	key := mustGetPublicKey(t)
+
+	if err := kh.HostKeyCallback("10.0.0.1:22", nil, key); err != nil {
+		t.Fatalf("unexpected error on first contact: %v", err)
+	}
+	if err := kh.HostKeyCallback("10.0.0.2:2222", nil, key); err != nil {
+		t.Fatalf("unexpected error on first contact: %v", err)
+	}
+	data, err := ioutil.ReadFile(kh.File)
+	if err != nil {
+		t.Fatalf("error reading known hosts file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 recorded keys, got %d: %q", len(lines), string(data))
+	}
+	if !strings.HasPrefix(lines[0], "10.0.0.1 ssh-rsa ") {
+		t.Errorf("unexpected entry for default port: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "[10.0.0.2]:2222 ssh-rsa ") {
+		t.Errorf("unexpected entry for non-default port: %q", lines[1])
+	}
+
+	// the same key is accepted and not recorded again
+	if err := kh.HostKeyCallback("10.0.0.1:22", nil, key); err != nil {
+		t.Errorf("unexpected error with recorded key: %v", err)
+	}
+	data, _ = ioutil.ReadFile(kh.File)
+	if strings.Count(string(data), "\n") != 2 {
+		t.Errorf("key was recorded more than once: %q", string(data))
+	}
+}
+
+func TestKnownHostsMismatch(t *testing.T) {
+	kh := mustGetKnownHosts(t)
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(kh.File)))
+
+	if err := kh.HostKeyCallback("10.0.0.1:22", nil, mustGetPublicKey(t)); err != nil {
+		t.Fatalf("unexpected error on first contact: %v", err)
+	}
+	err := kh.HostKeyCallback("10.0.0.1:22", nil, mustGetPublicKey(t))
+	if err == nil {
+		t.Fatalf("expected an error when the host key changed")
+	}
+	if _, ok := err.(*HostKeyMismatchError); !ok {
+		t.Errorf("expected a HostKeyMismatchError, got %T: %v", err, err)
+	}
+}
+
+func TestKnownHostsRemove(t *testing.T) {
+	kh := mustGetKnownHosts(t)
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(kh.File)))
+
+	for _, h := range []string{"10.0.0.1:22", "10.0.0.2:22"} {
+		if err := kh.HostKeyCallback(h, nil, mustGetPublicKey(t)); err != nil {
+			t.Fatalf("unexpected error on first contact: %v", err)
+		}
+	}
+	if err := kh.Remove("10.0.0.1:22"); err != nil {
+		t.Fatalf("unexpected error removing host: %v", err)
+	}
+	// the rebuilt node has a new key
+	if err := kh.HostKeyCallback("10.0.0.1:22", nil, mustGetPublicKey(t)); err != nil {
+		t.Errorf("unexpected error after the recorded key was removed: %v", err)
+	}
+	// other nodes are still verified
+	if err := kh.HostKeyCallback("10.0.0.2:22", nil, mustGetPublicKey(t)); err == nil {
+		t.Errorf("expected an error for the node whose key was not removed")
+	}
+}
+
+func TestNativeClientVerifiesHostKey(t *testing.T) {
+	server, client := newTestServerAndClient(t)
+	defer server.close()
+	defer client.Pool.Close()
+	kh := mustGetKnownHosts(t)
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(kh.File)))
+
+	client.HostKeyCallback = kh.HostKeyCallback
+	if _, err := client.Output(false, "echo", "hello"); err != nil {
+		t.Fatalf("unexpected error on first contact: %v", err)
+	}
+
+	// the node presents a different key when reconnecting
+	client.Pool.Close()
+	otherKey := mustGetPublicKey(t)
+	client.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		return kh.HostKeyCallback(hostname, remote, otherKey)
+	}
+	_, err := client.Output(false, "echo", "hello")
+	if err == nil || !strings.Contains(err.Error(), "host key") {
+		t.Errorf("expected a host key error, got %v", err)
+	}
+}
+
+func TestKnownHostsSkipsInvalidLines(t *testing.T) {
+	kh := mustGetKnownHosts(t)
+	defer os.RemoveAll(filepath.Dir(filepath.Dir(kh.File)))
+	key := mustGetPublicKey(t)
+	if err := kh.HostKeyCallback("10.0.0.1:22", nil, key); err != nil {
+		t.Fatalf("unexpected error on first contact: %v", err)
+	}
+	// Add an invalid line before the recorded key
+	data, err := ioutil.ReadFile(kh.File)
+	if err != nil {
+		t.Fatalf("error reading known hosts file: %v", err)
+	}
+	data = append([]byte("# comment\n10.0.0.9 ssh-rsa not-a-valid-key\n\n"), data...)
+	if err := ioutil.WriteFile(kh.File, data, 0600); err != nil {
+		t.Fatalf("error writing known hosts file: %v", err)
+	}
+
+	fingerprints, err := kh.Fingerprints("10.0.0.1:22")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fingerprints) != 1 || fingerprints[0] != Fingerprint(key) {
+		t.Errorf("expected the key recorded after the invalid line, got %v", fingerprints)
+	}
+	// The key must still be verified, instead of being recorded again
+	if err := kh.HostKeyCallback("10.0.0.1:22", nil, mustGetPublicKey(t)); err == nil {
+		t.Errorf("expected a mismatch error for a different key")
+	}
+}
+
+func TestNativeClientRejectsHostKeyWithoutKnownHosts(t *testing.T) {
+	server, client := newTestServerAndClient(t)
+	defer server.close()
+	defer client.Pool.Close()
+
+	// clients created without a known_hosts file reject all keys
+	client.HostKeyCallback = knownHostsCallback()
+	_, err := client.Output(false, "echo", "hello")
+	if err == nil || !strings.Contains(err.Error(), "cannot be verified") {
+		t.Errorf("expected a host key error, got %v", err)
+	}
+
+	client.HostKeyCallback = nil
+	_, err = client.Output(false, "echo", "hello")
+	if err == nil || !strings.Contains(err.Error(), "cannot be verified") {
+		t.Errorf("expected a host key error when the callback is nil, got %v", err)
+	}
+}
@@ -0,0 +1,451 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// DefaultConnectTimeout is the time allowed for establishing an SSH connection
+const DefaultConnectTimeout = 10 * time.Second
+
+// ExitError is returned when a remote command exits with a non-zero status
+type ExitError struct {
+	Host       string
+	Command    string
+	ExitStatus int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command %q on host %q exited with status %d", e.Command, e.Host, e.ExitStatus)
+}
+
+// NativeClient is a Client that uses the Go implementation of SSH instead of
+// the ssh binary. Connections are kept in a pool and reused between commands.
+type NativeClient struct {
+	Host string
+	Port int
+	User string
+	// Auth are the methods used to authenticate with the node
+	Auth []ssh.AuthMethod
+	// HostKeyCallback is used to verify the key of the node. The connection
+	// fails when it is nil; use InsecureIgnoreHostKey to accept all keys.
+	HostKeyCallback func(hostname string, remote net.Addr, key ssh.PublicKey) error
+	// ConnectTimeout is the time allowed for establishing the connection
+	ConnectTimeout time.Duration
+	// Pool holds the connections of the client. The default pool is used when nil.
+	Pool *ConnectionPool
+}
+
+// NewNativeClient returns a NativeClient that authenticates with the private
+// key file, and verifies the key of the node against the known_hosts file
+// given to UseKnownHostsFile. All keys are rejected if a known_hosts file is
+// not in use.
+func NewNativeClient(host string, port int, user string, key string) (*NativeClient, error) {
+	if err := ValidUnencryptedPrivateKey(key); err != nil {
+		return nil, err
+	}
+	buffer, err := ioutil.ReadFile(key)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := ssh.ParsePrivateKey(buffer)
+	if err != nil {
+		return nil, fmt.Errorf("Parse SSH key error: %v", err)
+	}
+	return &NativeClient{
+		Host:            host,
+		Port:            port,
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: knownHostsCallback(),
+		ConnectTimeout:  DefaultConnectTimeout,
+	}, nil
+}
+
+func (c *NativeClient) address() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+func (c *NativeClient) pool() *ConnectionPool {
+	if c.Pool == nil {
+		return defaultPool
+	}
+	return c.Pool
+}
+
+func (c *NativeClient) connect(ctx context.Context) (*ssh.Client, error) {
+	key := fmt.Sprintf("%s@%s", c.User, c.address())
+	return c.pool().get(key, func() (*ssh.Client, error) {
+		return c.dial(ctx)
+	})
+}
+
+func (c *NativeClient) dial(ctx context.Context) (*ssh.Client, error) {
+	conn, err := c.dialTCP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.handshake(ctx, conn)
+}
+
+func (c *NativeClient) connectTimeout() time.Duration {
+	if c.ConnectTimeout == 0 {
+		return DefaultConnectTimeout
+	}
+	return c.ConnectTimeout
+}
+
+func (c *NativeClient) dialTCP(ctx context.Context) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: c.connectTimeout()}
+	conn, err := dialer.DialContext(ctx, "tcp", c.address())
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %v", c.address(), err)
+	}
+	return conn, nil
+}
+
+// establishes the SSH connection over conn, which is closed on error
+func (c *NativeClient) handshake(ctx context.Context, conn net.Conn) (*ssh.Client, error) {
+	// The handshake is bound by the same deadline as the connection
+	deadline := time.Now().Add(c.connectTimeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	if c.HostKeyCallback == nil {
+		conn.Close()
+		return nil, fmt.Errorf("error establishing SSH connection to %s: the host key cannot be verified", c.address())
+	}
+	config := &ssh.ClientConfig{
+		User:            c.User,
+		Auth:            c.Auth,
+		HostKeyCallback: c.HostKeyCallback,
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, c.address(), config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error establishing SSH connection to %s: %v", c.address(), err)
+	}
+	conn.SetDeadline(time.Time{})
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// opens a session on a pooled connection. If the connection was closed by
+// the node, it is dropped from the pool and a new one is established.
+func (c *NativeClient) newSession(ctx context.Context) (*ssh.Session, error) {
+	client, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session, err := client.NewSession()
+	if err == nil {
+		return session, nil
+	}
+	c.pool().remove(client)
+	client, err = c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	session, err = client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("error creating SSH session on %s: %v", c.address(), err)
+	}
+	return session, nil
+}
+
+// Output runs the command on the node and returns the combined output
+func (c *NativeClient) Output(pty bool, args ...string) (string, error) {
+	return c.OutputContext(context.Background(), pty, args...)
+}
+
+// OutputContext runs the command on the node and returns the combined output.
+// The command is terminated when the context is done.
+func (c *NativeClient) OutputContext(ctx context.Context, pty bool, args ...string) (string, error) {
+	session, err := c.newSession(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+	if pty {
+		if err := requestPty(session, 80, 40); err != nil {
+			return "", err
+		}
+	}
+	var out syncBuffer
+	session.Stdout = &out
+	session.Stderr = &out
+	err = c.run(ctx, session, strings.Join(args, " "))
+	return out.String(), err
+}
+
+// Shell runs the command on the node, binding Stdin, Stdout and Stderr. An
+// interactive shell is started when no command is given.
+func (c *NativeClient) Shell(pty bool, args ...string) error {
+	ctx := context.Background()
+	session, err := c.newSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	if pty || len(args) == 0 {
+		fd := int(os.Stdin.Fd())
+		width, height := 80, 40
+		if terminal.IsTerminal(fd) {
+			state, err := terminal.MakeRaw(fd)
+			if err != nil {
+				return fmt.Errorf("error setting terminal to raw mode: %v", err)
+			}
+			defer terminal.Restore(fd, state)
+			if w, h, err := terminal.GetSize(fd); err == nil {
+				width, height = w, h
+			}
+		}
+		if err := requestPty(session, width, height); err != nil {
+			return err
+		}
+	}
+	session.Stdin = os.Stdin
+	session.Stdout = os.Stdout
+	session.Stderr = os.Stderr
+	if len(args) == 0 {
+		if err := session.Shell(); err != nil {
+			return fmt.Errorf("error starting shell on %s: %v", c.address(), err)
+		}
+		return c.wait(ctx, session, "")
+	}
+	return c.run(ctx, session, strings.Join(args, " "))
+}
+
+// Upload copies the contents of the reader to the file on the node
+func (c *NativeClient) Upload(ctx context.Context, r io.Reader, dest string, mode os.FileMode) error {
+	session, err := c.newSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	var out syncBuffer
+	session.Stdin = r
+	session.Stdout = &out
+	session.Stderr = &out
+	cmd := fmt.Sprintf("cat > %s && chmod %#o %s", shellQuote(dest), mode.Perm(), shellQuote(dest))
+	if err := c.run(ctx, session, cmd); err != nil {
+		return fmt.Errorf("error uploading %q: %v: %s", dest, err, out.String())
+	}
+	return nil
+}
+
+// UploadFile copies the local file to the node
+func (c *NativeClient) UploadFile(ctx context.Context, src string, dest string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return c.Upload(ctx, f, dest, info.Mode())
+}
+
+// ConnectionCheck is the result of checking the SSH access to a node
+type ConnectionCheck struct {
+	// Reachable is true if the SSH port of the node accepted the connection
+	Reachable bool
+	// Authenticated is true if the user authenticated with the node
+	Authenticated bool
+	// Sudo is true if the user can run commands with sudo without a password
+	Sudo bool
+	// Latency is the time it took to connect to the SSH port of the node
+	Latency time.Duration
+	// Err is the reason the check failed
+	Err error
+}
+
+// CheckConnection checks that the node is reachable, that the user can
+// authenticate and that the user can use sudo. A new connection is
+// established for the check, instead of using the pool. The check is
+// stopped when the context is done.
+func (c *NativeClient) CheckConnection(ctx context.Context) ConnectionCheck {
+	check := ConnectionCheck{}
+	start := time.Now()
+	conn, err := c.dialTCP(ctx)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	check.Reachable = true
+	check.Latency = time.Since(start)
+	client, err := c.handshake(ctx, conn)
+	if err != nil {
+		check.Err = err
+		return check
+	}
+	defer client.Close()
+	check.Authenticated = true
+	session, err := client.NewSession()
+	if err != nil {
+		check.Err = fmt.Errorf("error creating SSH session on %s: %v", c.address(), err)
+		return check
+	}
+	defer session.Close()
+	// sudo refuses to run without a terminal when requiretty is set
+	if err := requestPty(session, 80, 40); err != nil {
+		check.Err = err
+		return check
+	}
+	var out syncBuffer
+	session.Stdout = &out
+	session.Stderr = &out
+	if err := c.run(ctx, session, "sudo -n true"); err != nil {
+		check.Err = fmt.Errorf("user %q cannot use sudo without a password: %v", c.User, err)
+		if msg := strings.TrimSpace(out.String()); msg != "" {
+			check.Err = fmt.Errorf("%v: %s", check.Err, msg)
+		}
+		return check
+	}
+	check.Sudo = true
+	return check
+}
+
+func (c *NativeClient) run(ctx context.Context, session *ssh.Session, cmd string) error {
+	if err := session.Start(cmd); err != nil {
+		return fmt.Errorf("error running %q on %s: %v", cmd, c.address(), err)
+	}
+	return c.wait(ctx, session, cmd)
+}
+
+func (c *NativeClient) wait(ctx context.Context, session *ssh.Session, cmd string) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+	select {
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+		return ctx.Err()
+	case err := <-done:
+		if exitErr, ok := err.(*ssh.ExitError); ok {
+			return &ExitError{Host: c.Host, Command: cmd, ExitStatus: exitErr.ExitStatus()}
+		}
+		return err
+	}
+}
+
+func requestPty(session *ssh.Session, width, height int) error {
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty("xterm", height, width, modes); err != nil {
+		return fmt.Errorf("error requesting pseudo terminal: %v", err)
+	}
+	return nil
+}
+
+// a buffer that is shared by the stdout and stderr of a session
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'"'"'`, -1) + "'"
+}
+
+// ConnectionPool holds SSH connections so that they can be reused
+type ConnectionPool struct {
+	mu      sync.Mutex
+	entries map[string]*poolEntry
+}
+
+// connections to different nodes are established concurrently
+type poolEntry struct {
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+var defaultPool = &ConnectionPool{}
+
+// CloseConnections closes the connections in the default pool
+func CloseConnections() {
+	defaultPool.Close()
+}
+
+func (p *ConnectionPool) get(key string, dial func() (*ssh.Client, error)) (*ssh.Client, error) {
+	p.mu.Lock()
+	if p.entries == nil {
+		p.entries = map[string]*poolEntry{}
+	}
+	e, ok := p.entries[key]
+	if !ok {
+		e = &poolEntry{}
+		p.entries[key] = e
+	}
+	p.mu.Unlock()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.client != nil {
+		return e.client, nil
+	}
+	c, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	e.client = c
+	return c, nil
+}
+
+func (p *ConnectionPool) remove(client *ssh.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, e := range p.entries {
+		e.mu.Lock()
+		if e.client == client {
+			e.client = nil
+		}
+		e.mu.Unlock()
+	}
+	client.Close()
+}
+
+// Close closes all the connections in the pool
+func (p *ConnectionPool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, e := range p.entries {
+		e.mu.Lock()
+		if e.client != nil {
+			e.client.Close()
+		}
+		e.mu.Unlock()
+		delete(p.entries, k)
+	}
+}
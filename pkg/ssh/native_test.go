@@ -0,0 +1,422 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// an in-process SSH server that understands a handful of commands
+type testServer struct {
+	t        *testing.T
+	listener net.Listener
+	config   *ssh.ServerConfig
+
+	mu          sync.Mutex
+	connections int
+	conns       []ssh.Conn
+	files       map[string][]byte
+	fileModes   map[string]string
+	// noSudo makes sudo require a password
+	noSudo bool
+	// requireTTY makes sudo fail when the session does not have a pty
+	requireTTY bool
+}
+
+var uploadCommandRE = regexp.MustCompile(`^cat > '([^']+)' && chmod (\d+) '[^']+'$`)
+
+func mustGenerateKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	return key
+}
+
+// writes the private key to a file and returns the path
+func mustWriteKeyFile(t *testing.T, key *rsa.PrivateKey) string {
+	dir, err := ioutil.TempDir("", "ssh-tests")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	file := filepath.Join(dir, "id_rsa")
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := ioutil.WriteFile(file, keyPEM, 0600); err != nil {
+		t.Fatalf("error writing key: %v", err)
+	}
+	return file
+}
+
+func newTestServer(t *testing.T, authorizedKey ssh.PublicKey) *testServer {
+	hostKey, err := ssh.NewSignerFromKey(mustGenerateKey(t))
+	if err != nil {
+		t.Fatalf("error creating host key: %v", err)
+	}
+	s := &testServer{
+		t:         t,
+		files:     map[string][]byte{},
+		fileModes: map[string]string{},
+	}
+	s.config = &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if bytes.Equal(key.Marshal(), authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("unauthorized key for %q", conn.User())
+		},
+	}
+	s.config.AddHostKey(hostKey)
+	s.listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	go s.serve()
+	return s
+}
+
+func (s *testServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testServer) close() {
+	s.listener.Close()
+	s.closeConnections()
+}
+
+func (s *testServer) closeConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *testServer) connectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connections
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			sshConn, chans, reqs, err := ssh.NewServerConn(conn, s.config)
+			if err != nil {
+				conn.Close()
+				return
+			}
+			s.mu.Lock()
+			s.connections++
+			s.conns = append(s.conns, sshConn)
+			s.mu.Unlock()
+			go ssh.DiscardRequests(reqs)
+			for newChan := range chans {
+				if newChan.ChannelType() != "session" {
+					newChan.Reject(ssh.UnknownChannelType, "unknown channel type")
+					continue
+				}
+				ch, requests, err := newChan.Accept()
+				if err != nil {
+					continue
+				}
+				go s.handleSession(ch, requests)
+			}
+		}()
+	}
+}
+
+func (s *testServer) handleSession(ch ssh.Channel, requests <-chan *ssh.Request) {
+	var pty bool
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			pty = true
+			req.Reply(true, nil)
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			go s.exec(ch, payload.Command, pty)
+		default:
+			req.Reply(false, nil)
+		}
+	}
+}
+
+func (s *testServer) exec(ch ssh.Channel, cmd string, pty bool) {
+	status := 0
+	switch {
+	case strings.HasPrefix(cmd, "echo "):
+		ch.Write([]byte(strings.TrimPrefix(cmd, "echo ") + "\n"))
+	case strings.HasPrefix(cmd, "exit "):
+		status, _ = strconv.Atoi(strings.TrimPrefix(cmd, "exit "))
+	case cmd == "sudo -n true":
+		s.mu.Lock()
+		noSudo, requireTTY := s.noSudo, s.requireTTY
+		s.mu.Unlock()
+		if requireTTY && !pty {
+			ch.Stderr().Write([]byte("sudo: sorry, you must have a tty to run sudo\n"))
+			status = 1
+		} else if noSudo {
+			ch.Stderr().Write([]byte("sudo: a password is required\n"))
+			status = 1
+		}
+	case cmd == "sleep":
+		// never completes, the client must give up
+		return
+	case uploadCommandRE.MatchString(cmd):
+		m := uploadCommandRE.FindStringSubmatch(cmd)
+		data, _ := ioutil.ReadAll(ch)
+		s.mu.Lock()
+		s.files[m[1]] = data
+		s.fileModes[m[1]] = m[2]
+		s.mu.Unlock()
+	default:
+		ch.Stderr().Write([]byte("command not found\n"))
+		status = 127
+	}
+	ch.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(status)}))
+	ch.Close()
+}
+
+// returns a server and a client that is authorized to connect to it
+func newTestServerAndClient(t *testing.T) (*testServer, *NativeClient) {
+	key := mustGenerateKey(t)
+	keyFile := mustWriteKeyFile(t, key)
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatalf("error creating signer: %v", err)
+	}
+	server := newTestServer(t, signer.PublicKey())
+	client, err := NewNativeClient("127.0.0.1", server.port(), "kismaticuser", keyFile)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	client.HostKeyCallback = InsecureIgnoreHostKey()
+	client.Pool = &ConnectionPool{}
+	return server, client
+}
+
+func TestNativeClientOutput(t *testing.T) {
+	server, client := newTestServerAndClient(t)
+	defer server.close()
+	defer client.Pool.Close()
+
+	out, err := client.Output(false, "echo", "hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "hello\n" {
+		t.Errorf("expected output %q, got %q", "hello\n", out)
+	}
+	// the output of commands that use a pseudo-terminal is also returned
+	out, err = client.Output(true, "echo", "sudo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "sudo\n" {
+		t.Errorf("expected output %q, got %q", "sudo\n", out)
+	}
+}
+
+func TestNativeClientExitError(t *testing.T) {
+	server, client := newTestServerAndClient(t)
+	defer server.close()
+	defer client.Pool.Close()
+
+	out, err := client.Output(false, "foo")
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+	exitErr, ok := err.(*ExitError)
+	if !ok {
+		t.Fatalf("expected an ExitError, got %T: %v", err, err)
+	}
+	if exitErr.ExitStatus != 127 {
+		t.Errorf("expected exit status 127, got %d", exitErr.ExitStatus)
+	}
+	if exitErr.Host != "127.0.0.1" || exitErr.Command != "foo" {
+		t.Errorf("unexpected host or command in error: %v", exitErr)
+	}
+	if out != "command not found\n" {
+		t.Errorf("expected stderr in output, got %q", out)
+	}
+
+	if _, err := client.Output(false, "exit", "0"); err != nil {
+		t.Errorf("unexpected error when command exits with 0: %v", err)
+	}
+}
+
+func TestNativeClientReusesConnections(t *testing.T) {
+	server, client := newTestServerAndClient(t)
+	defer server.close()
+	defer client.Pool.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Output(false, "echo", "hello"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if server.connectionCount() != 1 {
+		t.Errorf("expected 1 connection, got %d", server.connectionCount())
+	}
+
+	// a connection closed by the node is replaced
+	server.closeConnections()
+	if _, err := client.Output(false, "echo", "hello"); err != nil {
+		t.Fatalf("unexpected error after the connection was closed: %v", err)
+	}
+	if server.connectionCount() != 2 {
+		t.Errorf("expected 2 connections, got %d", server.connectionCount())
+	}
+}
+
+func TestNativeClientUpload(t *testing.T) {
+	server, client := newTestServerAndClient(t)
+	defer server.close()
+	defer client.Pool.Close()
+
+	content := []byte("some file content")
+	if err := client.Upload(context.Background(), bytes.NewReader(content), "/tmp/some file", 0600); err != nil {
+		t.Fatalf("unexpected error uploading file: %v", err)
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if !bytes.Equal(server.files["/tmp/some file"], content) {
+		t.Errorf("expected uploaded content %q, got %q", content, server.files["/tmp/some file"])
+	}
+	if server.fileModes["/tmp/some file"] != "0600" {
+		t.Errorf("expected mode 0600, got %q", server.fileModes["/tmp/some file"])
+	}
+}
+
+func TestNativeClientContextTimeout(t *testing.T) {
+	server, client := newTestServerAndClient(t)
+	defer server.close()
+	defer client.Pool.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.OutputContext(ctx, false, "sleep")
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected deadline exceeded error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("command was not terminated when the context expired")
+	}
+}
+
+func TestNativeClientUnauthorizedKey(t *testing.T) {
+	server, _ := newTestServerAndClient(t)
+	defer server.close()
+
+	otherKeyFile := mustWriteKeyFile(t, mustGenerateKey(t))
+	defer os.RemoveAll(filepath.Dir(otherKeyFile))
+	client, err := NewNativeClient("127.0.0.1", server.port(), "kismaticuser", otherKeyFile)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	client.HostKeyCallback = InsecureIgnoreHostKey()
+	client.Pool = &ConnectionPool{}
+	defer client.Pool.Close()
+	if _, err := client.Output(false, "echo", "hello"); err == nil {
+		t.Errorf("expected an error when the key is not authorized")
+	}
+}
+
+func TestNativeClientCheckConnection(t *testing.T) {
+	server, client := newTestServerAndClient(t)
+	defer server.close()
+	defer client.Pool.Close()
+
+	check := client.CheckConnection(context.Background())
+	if check.Err != nil || !check.Reachable || !check.Authenticated || !check.Sudo {
+		t.Errorf("expected a successful check, got %+v", check)
+	}
+	if check.Latency <= 0 {
+		t.Errorf("expected the latency to be measured, got %v", check.Latency)
+	}
+	// the check does not leave connections in the pool
+	if len(client.Pool.entries) != 0 {
+		t.Errorf("expected the check not to use the pool")
+	}
+
+	server.mu.Lock()
+	server.noSudo = true
+	server.mu.Unlock()
+	check = client.CheckConnection(context.Background())
+	if check.Err == nil || !check.Authenticated || check.Sudo {
+		t.Errorf("expected the check to fail on sudo, got %+v", check)
+	}
+	if !strings.Contains(check.Err.Error(), "a password is required") {
+		t.Errorf("expected the output of sudo in the error, got %v", check.Err)
+	}
+}
+
+func TestNativeClientCheckConnectionRequireTTY(t *testing.T) {
+	server, client := newTestServerAndClient(t)
+	defer server.close()
+	defer client.Pool.Close()
+	server.mu.Lock()
+	server.requireTTY = true
+	server.mu.Unlock()
+
+	check := client.CheckConnection(context.Background())
+	if check.Err != nil || !check.Sudo {
+		t.Errorf("expected sudo to work when requiretty is set, got %+v", check)
+	}
+}
+
+func TestNativeClientCheckConnectionFailures(t *testing.T) {
+	server, _ := newTestServerAndClient(t)
+	defer server.close()
+
+	otherKeyFile := mustWriteKeyFile(t, mustGenerateKey(t))
+	defer os.RemoveAll(filepath.Dir(otherKeyFile))
+	client, err := NewNativeClient("127.0.0.1", server.port(), "kismaticuser", otherKeyFile)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	client.HostKeyCallback = InsecureIgnoreHostKey()
+	check := client.CheckConnection(context.Background())
+	if check.Err == nil || !check.Reachable || check.Authenticated {
+		t.Errorf("expected the check to fail on authentication, got %+v", check)
+	}
+
+	// nothing listens on the port once the listener is closed
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening: %v", err)
+	}
+	client.Port = l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	check = client.CheckConnection(context.Background())
+	if check.Err == nil || check.Reachable {
+		t.Errorf("expected the check to fail on reachability, got %+v", check)
+	}
+}
@@ -1,6 +1,7 @@
 package ssh
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
@@ -24,6 +25,24 @@ var baseSSHArgs = []string{
 	"-o", "ControlPath=none",
 }
 
+// returns the base args of the ssh binary. When a known_hosts file is in use,
+// the ssh binary only connects to nodes whose key was recorded in it.
+func externalSSHArgs() []string {
+	args := append([]string{}, baseSSHArgs...)
+	if knownHosts == nil {
+		return args
+	}
+	for i := range args {
+		switch args[i] {
+		case "StrictHostKeyChecking=no":
+			args[i] = "StrictHostKeyChecking=yes"
+		case "UserKnownHostsFile=/dev/null":
+			args[i] = "UserKnownHostsFile=" + knownHosts.File
+		}
+	}
+	return args
+}
+
 type Client interface {
 	Output(pty bool, args ...string) (string, error)
 	Shell(pty bool, args ...string) error
@@ -41,12 +60,29 @@ func TestConnection(ip string, port int, user, key string) error {
 	if err != nil {
 		return err
 	}
+	_, err = client.Output(false, "exit")
+	return err
+}
 
-	return client.Shell(false, "exit")
+// CheckConnection checks the SSH access to ip:port as user with key. The
+// host key of the node is verified against the known_hosts file.
+func CheckConnection(ctx context.Context, ip string, port int, user, key string) ConnectionCheck {
+	client, err := NewNativeClient(ip, port, user, key)
+	if err != nil {
+		return ConnectionCheck{Err: err}
+	}
+	return client.CheckConnection(ctx)
 }
 
-// NewClient verifies ssh is available in the PATH and returns an SSH client
+// NewClient returns an SSH client that uses the Go implementation of SSH.
+// The host key of the node is verified against the known_hosts file.
 func NewClient(host string, port int, user string, key string) (Client, error) {
+	return NewNativeClient(host, port, user, key)
+}
+
+// NewExternalClient verifies ssh is available in the PATH and returns an SSH
+// client that runs the ssh binary
+func NewExternalClient(host string, port int, user string, key string) (Client, error) {
 	if err := ValidUnencryptedPrivateKey(key); err != nil {
 		return nil, err
 	}
@@ -61,7 +97,7 @@ func NewClient(host string, port int, user string, key string) (Client, error) {
 
 func newExternalClient(sshBinaryPath string, user string, host string, port int, key string) (*ExternalClient, error) {
 	// Get defailt args with user and host
-	args := append(baseSSHArgs, fmt.Sprintf("%s@%s", user, host))
+	args := append(externalSSHArgs(), fmt.Sprintf("%s@%s", user, host))
 	// set port
 	args = append(args, "-p", fmt.Sprintf("%d", port))
 	// set key
@@ -1,13 +1,18 @@
 package tls
 
 import (
+	"bytes"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/cloudflare/cfssl/csr"
+	"github.com/cloudflare/cfssl/helpers"
 	"github.com/cloudflare/cfssl/initca"
 	"github.com/cloudflare/cfssl/log"
 )
@@ -75,3 +80,64 @@ func ReadCACert(name, dir string) (key, cert []byte, err error) {
 	}
 	return key, cert, nil
 }
+
+// ValidateCA verifies that the certificate is a Certificate Authority that
+// can be used to sign certificates with the key. When a chain is provided,
+// the certificate must chain up to a root Certificate Authority in the chain.
+func ValidateCA(key, cert, chain []byte) error {
+	caCert, err := helpers.ParseCertificatePEM(cert)
+	if err != nil {
+		return fmt.Errorf("error parsing CA certificate: %v", err)
+	}
+	if !caCert.IsCA {
+		return errors.New("the certificate is not a Certificate Authority")
+	}
+	if time.Now().After(caCert.NotAfter) {
+		return fmt.Errorf("the certificate expired on %s", caCert.NotAfter)
+	}
+	caKey, err := helpers.ParsePrivateKeyPEM(key)
+	if err != nil {
+		return fmt.Errorf("error parsing CA private key: %v", err)
+	}
+	certPub, err := x509.MarshalPKIXPublicKey(caCert.PublicKey)
+	if err != nil {
+		return fmt.Errorf("error reading public key of CA certificate: %v", err)
+	}
+	keyPub, err := x509.MarshalPKIXPublicKey(caKey.Public())
+	if err != nil {
+		return fmt.Errorf("error reading public key of CA private key: %v", err)
+	}
+	if !bytes.Equal(certPub, keyPub) {
+		return errors.New("the private key does not match the CA certificate")
+	}
+	if len(chain) == 0 {
+		return nil
+	}
+	chainCerts, err := helpers.ParseCertificatesPEM(chain)
+	if err != nil {
+		return fmt.Errorf("error parsing CA chain: %v", err)
+	}
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	foundRoot := false
+	for _, c := range chainCerts {
+		if bytes.Equal(c.RawIssuer, c.RawSubject) && c.CheckSignatureFrom(c) == nil {
+			roots.AddCert(c)
+			foundRoot = true
+			continue
+		}
+		intermediates.AddCert(c)
+	}
+	if !foundRoot {
+		return errors.New("the CA chain does not contain a root Certificate Authority")
+	}
+	opts := x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if _, err := caCert.Verify(opts); err != nil {
+		return fmt.Errorf("error verifying the CA certificate against the chain: %v", err)
+	}
+	return nil
+}
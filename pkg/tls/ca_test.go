@@ -1,6 +1,12 @@
 package tls
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
 	"reflect"
 	"testing"
 	"time"
@@ -61,3 +67,68 @@ func TestNewCACert(t *testing.T) {
 		t.Errorf("expected expiration date %q, got %q", expectedExpiration, parsedCert.NotAfter)
 	}
 }
+
+// returns a CA certificate and key signed by the parent. The certificate is
+// self-signed when the parent is nil.
+func mustCreateTestCA(t *testing.T, cn string, parent *x509.Certificate, parentKey *rsa.PrivateKey) (*x509.Certificate, *rsa.PrivateKey, []byte, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if parent == nil {
+		parent = tmpl
+		parentKey = key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("error creating certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("error parsing certificate: %v", err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return cert, key, certPEM, keyPEM
+}
+
+func TestValidateCASelfSigned(t *testing.T) {
+	_, _, certPEM, keyPEM := mustCreateTestCA(t, "root", nil, nil)
+	if err := ValidateCA(keyPEM, certPEM, nil); err != nil {
+		t.Errorf("unexpected error validating CA: %v", err)
+	}
+}
+
+func TestValidateCAKeyMismatch(t *testing.T) {
+	_, _, certPEM, _ := mustCreateTestCA(t, "root", nil, nil)
+	_, _, _, otherKeyPEM := mustCreateTestCA(t, "other", nil, nil)
+	if err := ValidateCA(otherKeyPEM, certPEM, nil); err == nil {
+		t.Errorf("expected an error when the key does not match the certificate")
+	}
+}
+
+func TestValidateCAIntermediateWithChain(t *testing.T) {
+	root, rootKey, rootPEM, _ := mustCreateTestCA(t, "root", nil, nil)
+	_, _, intermediatePEM, intermediateKeyPEM := mustCreateTestCA(t, "intermediate", root, rootKey)
+	if err := ValidateCA(intermediateKeyPEM, intermediatePEM, rootPEM); err != nil {
+		t.Errorf("unexpected error validating intermediate CA: %v", err)
+	}
+
+	// the chain must contain the root that signed the intermediate
+	_, _, otherRootPEM, _ := mustCreateTestCA(t, "other-root", nil, nil)
+	if err := ValidateCA(intermediateKeyPEM, intermediatePEM, otherRootPEM); err == nil {
+		t.Errorf("expected an error when the chain does not contain the issuer of the intermediate CA")
+	}
+	if err := ValidateCA(intermediateKeyPEM, intermediatePEM, intermediatePEM); err == nil {
+		t.Errorf("expected an error when the chain does not contain a root CA")
+	}
+}
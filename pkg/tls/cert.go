@@ -1,11 +1,15 @@
 package tls
 
 import (
+	"bytes"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
+	"time"
 
 	"github.com/apprenda/kismatic/pkg/util"
 	"github.com/cloudflare/cfssl/cli/genkey"
@@ -24,10 +28,15 @@ type CA struct {
 	Password string
 	// Cert is the CA's public certificate.
 	Cert []byte
+	// Chain contains the certificates of the chain of an intermediate CA, up
+	// to the root CA. Empty when the CA is a root CA.
+	Chain []byte
 	// ConfigFile contains a cfssl configuration file for the Certificate Authority
 	ConfigFile string
 	// Profile to be used when signing with this Certificate Authority
 	Profile string
+	// Expiry overrides the expiry of the signing profile when set
+	Expiry time.Duration
 }
 
 // NewCert creates a new certificate/key pair using the CertificateAuthority provided
@@ -53,6 +62,14 @@ func NewCert(ca *CA, req csr.CertificateRequest) (key, cert []byte, err error) {
 	if err != nil {
 		return nil, nil, fmt.Errorf("error loading CA Config: %v", err)
 	}
+	if ca.Expiry != 0 {
+		if caConfig.Signing.Default != nil {
+			caConfig.Signing.Default.Expiry = ca.Expiry
+		}
+		if profile, ok := caConfig.Signing.Profiles[ca.Profile]; ok {
+			profile.Expiry = ca.Expiry
+		}
+	}
 	// Create signer using CA
 	s, err := local.NewSigner(caPriv, caCert, sigAlgo, caConfig.Signing)
 	if err != nil {
@@ -132,7 +149,7 @@ func CertValid(CN string, SANs []string, name, dir string) (valid bool, warn []e
 	}
 
 	// verify certificate
-	cert, err := helpers.ParseCertificatePEM(certBytes)
+	cert, err := ParseLeafCertificatePEM(certBytes)
 	if err != nil {
 		return false, []error{fmt.Errorf("error parsing cert %s: %v", name, err)}, nil
 	}
@@ -161,6 +178,19 @@ func CertValid(CN string, SANs []string, name, dir string) (valid bool, warn []e
 	return len(warn) == 0, warn, nil
 }
 
+// ParseLeafCertificatePEM parses the first certificate in the PEM data. The
+// certificate may be followed by the chain of the CA that signed it.
+func ParseLeafCertificatePEM(certPEM []byte) (*x509.Certificate, error) {
+	certs, _, err := helpers.ParseOneCertificateFromPEM(bytes.TrimSpace(certPEM))
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+	return certs[0], nil
+}
+
 // CertExistsAndValid verifies that the cert exists and the CN and SANs match the expected values
 func CertExistsAndValid(CN string, SANs []string, name, dir string) (valid bool, warn []error, err error) {
 	exists, err := CertKeyPairExists(name, dir)
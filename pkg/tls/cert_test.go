@@ -135,6 +135,33 @@ func TestGenerateNewCertificate(t *testing.T) {
 
 }
 
+func TestGenerateNewCertificateWithExpiry(t *testing.T) {
+	key, caCert, err := NewCACert("test/ca-csr.json", "someCN", Subject{})
+	if err != nil {
+		t.Fatalf("error creating CA: %v", err)
+	}
+	ca := &CA{
+		Key:        key,
+		Cert:       caCert,
+		ConfigFile: "test/ca-config.json",
+		Profile:    "kubernetes",
+		Expiry:     2 * time.Hour,
+	}
+	_, cert, err := NewCert(ca, *buildReq("testKube", []string{"testHostname"}))
+	if err != nil {
+		t.Fatalf("error creating certificate: %v", err)
+	}
+	parsedCert, err := helpers.ParseCertificatePEM(cert)
+	if err != nil {
+		t.Fatalf("error parsing certificate: %v", err)
+	}
+	// the signer backdates the certificates by a few minutes
+	expectedExpiration := time.Now().Add(2 * time.Hour)
+	if parsedCert.NotAfter.Sub(expectedExpiration) > 10*time.Minute || expectedExpiration.Sub(parsedCert.NotAfter) > 10*time.Minute {
+		t.Errorf("expected expiration date %q, got %q", expectedExpiration, parsedCert.NotAfter)
+	}
+}
+
 func TestCertValid(t *testing.T) {
 	tests := []struct {
 		expectedCN   string